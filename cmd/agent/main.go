@@ -2,8 +2,7 @@ package main
 
 import (
 	"context"
-	"crypto/tls"
-	"crypto/x509"
+	"crypto/rsa"
 	"fmt"
 	"log"
 	"net/http"
@@ -14,11 +13,14 @@ import (
 
 	"github.com/Xacor/go-metrics/internal/agent/config"
 	"github.com/Xacor/go-metrics/internal/agent/metric"
+	"github.com/Xacor/go-metrics/internal/agent/receiver"
+	"github.com/Xacor/go-metrics/internal/envelope"
 	"github.com/Xacor/go-metrics/internal/logger"
 	"github.com/Xacor/go-metrics/proto"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/encoding/gzip"
 
 	poller "github.com/Xacor/go-metrics/internal/agent/http"
@@ -58,34 +60,63 @@ func main() {
 		l.Error("failed to get key", zap.Error(err))
 	}
 
-	monitor, err := metric.NewMonitor(time.Duration(cfg.GetPollInterval()) * time.Second)
+	collectors, err := cfg.GetCollectors()
 	if err != nil {
-		l.Error("failed to create monitor", zap.Error(err))
+		l.Fatal("failed to configure collectors", zap.Error(err))
 	}
+
+	acc := metric.NewAccumulator()
+	monitor := metric.NewMonitor(time.Duration(cfg.GetPollInterval())*time.Second, acc, collectors)
 	defer monitor.Close()
 
+	if cfg.StatsDAddress != "" {
+		statsd, err := receiver.NewStatsD(cfg.StatsDAddress, acc, cfg.GetReceiverOptions())
+		if err != nil {
+			l.Fatal("unable to start statsd receiver", zap.Error(err))
+		}
+		defer statsd.Close()
+	}
+
+	if cfg.PushAddress != "" {
+		push, err := receiver.NewPush(cfg.PushAddress, acc, cfg.GetReceiverOptions())
+		if err != nil {
+			l.Fatal("unable to start push receiver", zap.Error(err))
+		}
+		defer push.Close()
+	}
+
 	publicKey, err := cfg.GetPublicKey()
 	if err != nil {
 		l.Error("failed to get public key", zap.Error(err))
 	}
 
-	conn, err := dialGRPC(cfg)
+	conn, err := dialGRPC(cfg, publicKey)
 	if err != nil {
 		l.Fatal("unable to open client connection", zap.Error(err))
 	}
 	defer conn.Close()
 	metricClient := proto.NewMetricsClient(conn)
 
+	client, err := newHTTPClient(cfg)
+	if err != nil {
+		l.Fatal("unable to configure http client", zap.Error(err))
+	}
+
 	pcfg := poller.PollerConfig{
 		ReportInterval: cfg.GetReportInterval(),
 		RateLimit:      cfg.GetRateLimit(),
 		Address:        cfg.GetURL(),
 		Key:            key,
-		MetricCh:       monitor.C,
-		Client:         &http.Client{},
+		KeyID:          cfg.KeyID,
+		Source:         acc,
+		Client:         client,
 		GrpcClient:     metricClient,
 		Logger:         l,
 		PublicKey:      publicKey,
+		CryptoKeyID:    cfg.CryptoKeyID,
+		APIKey:         cfg.APIKey,
+		TenantID:       cfg.TenantID,
+		TenantToken:    cfg.TenantToken,
 	}
 
 	poller := poller.NewPoller(&pcfg)
@@ -104,34 +135,38 @@ func main() {
 	l.Info("gracefully shutting down")
 }
 
-func loadTLSCredentials(cafile string) (credentials.TransportCredentials, error) {
-	pemServerCA, err := os.ReadFile(cafile)
-	if err != nil {
-		return nil, err
+func dialGRPC(cfg config.Config, publicKey *rsa.PublicKey) (*grpc.ClientConn, error) {
+	creds := insecure.NewCredentials()
+	if cfg.TLSEnabled() {
+		tlsConfig, err := cfg.GetTLSConfig()
+		if err != nil {
+			return nil, err
+		}
+		creds = credentials.NewTLS(tlsConfig)
 	}
 
-	certPool := x509.NewCertPool()
-	if !certPool.AppendCertsFromPEM(pemServerCA) {
-		return nil, fmt.Errorf("failed to add server CA's certificate")
+	callOpts := []grpc.CallOption{grpc.UseCompressor(gzip.Name)}
+	if publicKey != nil {
+		callOpts = append(callOpts, grpc.ForceCodec(envelope.NewClientCodec(publicKey, cfg.CryptoKeyID)))
 	}
 
-	config := &tls.Config{
-		RootCAs: certPool,
+	conn, err := grpc.Dial(cfg.GRPCAddress, grpc.WithTransportCredentials(creds), grpc.WithDefaultCallOptions(callOpts...))
+	if err != nil {
+		return nil, err
 	}
 
-	return credentials.NewTLS(config), nil
+	return conn, nil
 }
 
-func dialGRPC(cfg config.Config) (*grpc.ClientConn, error) {
-	creds, err := loadTLSCredentials(cfg.CACertFile)
-	if err != nil {
-		return nil, err
+func newHTTPClient(cfg config.Config) (*http.Client, error) {
+	if !cfg.HTTPS {
+		return &http.Client{}, nil
 	}
 
-	conn, err := grpc.Dial(cfg.GRPCAddress, grpc.WithTransportCredentials(creds), grpc.WithDefaultCallOptions(grpc.UseCompressor(gzip.Name)))
+	tlsConfig, err := cfg.GetTLSConfig()
 	if err != nil {
 		return nil, err
 	}
 
-	return conn, nil
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}, nil
 }
@@ -8,18 +8,25 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/Xacor/go-metrics/internal/envelope"
 	"github.com/Xacor/go-metrics/internal/logger"
+	"github.com/Xacor/go-metrics/internal/server/audit"
+	"github.com/Xacor/go-metrics/internal/server/auth"
 	"github.com/Xacor/go-metrics/internal/server/config"
 	"github.com/Xacor/go-metrics/internal/server/core"
 	"github.com/Xacor/go-metrics/internal/server/core/db"
+	"github.com/Xacor/go-metrics/internal/server/handlers/admin"
 	"github.com/Xacor/go-metrics/internal/server/handlers/database"
 	"github.com/Xacor/go-metrics/internal/server/handlers/metrics"
 	"github.com/Xacor/go-metrics/internal/server/interceptors"
+	"github.com/Xacor/go-metrics/internal/server/limit"
 	"github.com/Xacor/go-metrics/internal/server/middleware"
 	"github.com/Xacor/go-metrics/internal/server/storage"
+	"github.com/Xacor/go-metrics/internal/signing"
 	"github.com/Xacor/go-metrics/proto"
 	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
@@ -43,6 +50,13 @@ func printInfo() {
 func main() {
 	printInfo()
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			log.Fatalf("migrate: %v", err)
+		}
+		return
+	}
+
 	gracefullShutdown := make(chan os.Signal, 2)
 	signal.Notify(gracefullShutdown, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
 
@@ -59,13 +73,52 @@ func main() {
 	l := logger.Get()
 	defer l.Sync()
 
+	authenticator, err := initAuth(&cfg)
+	if err != nil {
+		l.Fatal("failed to configure authentication", zap.Error(err))
+	}
+
+	auditLog, err := cfg.GetAuditLog()
+	if err != nil {
+		l.Fatal("failed to open audit log", zap.Error(err))
+	}
+	defer auditLog.Close()
+
+	keyring, err := cfg.GetKeyring()
+	if err != nil {
+		l.Fatal("failed to load signing keys", zap.Error(err))
+	}
+	verifier := cfg.GetVerifier(keyring)
+
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	if keyring != nil {
+		reloaded := func() {
+			err := auditLog.Record(ctx, audit.Event{Operation: audit.OpKeyringReload, Target: cfg.KeyringFile})
+			if err != nil {
+				l.Error("unable to write audit event", zap.Error(err))
+			}
+		}
+		go keyring.Watch(ctx, time.Duration(cfg.KeyringReload)*time.Second, reloaded)
+		go reloadOnHangup(ctx, keyring, reloaded, l)
+	}
+
+	// ограничения общие для HTTP и gRPC
+	limiter, shedder := cfg.GetLimiter(), cfg.GetShedder()
+
 	r := chi.NewRouter()
-	_, err = middleware.RegisterMiddlewares(r, &cfg)
+	_, err = middleware.RegisterMiddlewares(r, &cfg, authenticator, keyring, verifier, limiter, shedder)
 	if err != nil {
-		l.Error("failed to configure middleware", zap.Error(err))
+		l.Fatal("failed to configure middleware", zap.Error(err))
 	}
 
 	repo := db.InitDB(&cfg)
+	if cfg.TenantsFile != "" {
+		repo = storage.NewTenantStorage(repo)
+	}
+	if auditLog != nil {
+		repo = storage.NewAuditStorage(repo, auditLog)
+	}
 	defer repo.Close()
 
 	metricsAPI := metrics.NewAPI(repo, l)
@@ -74,20 +127,38 @@ func main() {
 	databaseAPI := database.NewHealthService(repo)
 	databaseAPI.RegisterRoutes(r)
 
+	if authenticator != nil {
+		adminAPI := admin.NewAPI(authenticator, auditLog, l)
+		adminAPI.RegisterRoutes(r)
+	}
+
 	srv := http.Server{
 		Addr:    cfg.Address,
 		Handler: r,
 	}
 
+	if cfg.HTTPTLS {
+		tlsConfig, err := cfg.GetTLSConfig()
+		if err != nil {
+			l.Fatal("failed to configure tls", zap.Error(err))
+		}
+		srv.TLSConfig = tlsConfig
+	}
+
 	l.Info(fmt.Sprintf("starting serving on %s", cfg.Address), zap.Any("server configuration", cfg))
 	go func() {
-		srv.ListenAndServe()
+		var err error
+		if srv.TLSConfig != nil {
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			err = srv.ListenAndServe()
+		}
 		if err != nil && err != http.ErrServerClosed {
 			l.Fatal(err.Error())
 		}
 	}()
 
-	grpc := startGRPC(cfg, l, repo)
+	grpc := startGRPC(cfg, l, repo, authenticator, verifier, limiter, shedder)
 
 	<-gracefullShutdown
 
@@ -102,22 +173,30 @@ func main() {
 	grpc.GracefulStop()
 }
 
-func startGRPC(cfg config.Config, log *zap.Logger, repo storage.MetricRepo) *grpc.Server {
+func startGRPC(cfg config.Config, log *zap.Logger, repo storage.MetricRepo, authenticator *auth.Authenticator, verifier *signing.Verifier, limiter *limit.Limiter, shedder *limit.Shedder) *grpc.Server {
 	listen, err := net.Listen("tcp", cfg.GAddress)
 	if err != nil {
 		log.Fatal("unable to listen tcp", zap.Error(err))
 	}
 
 	opts := make([]grpc.ServerOption, 0)
-	if cfg.GRPCConfig.TLSCertFile != "" && cfg.GRPCConfig.TLSKeyFile != "" {
-		creds, err := credentials.NewServerTLSFromFile(cfg.TLSCertFile, cfg.GRPCConfig.TLSKeyFile)
+	if cfg.TLSEnabled() {
+		tlsConfig, err := cfg.GetTLSConfig()
 		if err != nil {
-			log.Fatal("failed to create credentials: %v", zap.Error(err))
+			log.Fatal("failed to create credentials", zap.Error(err))
 		}
-		opts = append(opts, grpc.Creds(creds))
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	keys, err := cfg.GetPrivateKeys()
+	if err != nil {
+		log.Fatal("failed to load private keys", zap.Error(err))
+	}
+	if keys != nil {
+		opts = append(opts, grpc.ForceServerCodec(envelope.NewServerCodec(keys)), grpc.StatsHandler(envelope.StatsHandler{}))
 	}
 
-	opts = append(opts, interceptors.RegisterUnaryInterceptorChain(cfg))
+	opts = append(opts, interceptors.RegisterUnaryInterceptorChain(cfg, authenticator, verifier, limiter, shedder))
 
 	s := grpc.NewServer(opts...)
 	proto.RegisterMetricsServer(s, core.NewMetricsServer(repo, log))
@@ -130,3 +209,58 @@ func startGRPC(cfg config.Config, log *zap.Logger, repo storage.MetricRepo) *grp
 
 	return s
 }
+
+// initAuth настраивает аутентификацию клиентов. Возвращает nil, если она отключена.
+func initAuth(cfg *config.Config) (*auth.Authenticator, error) {
+	if !cfg.AuthEnabled() {
+		return nil, nil
+	}
+
+	var keys *auth.KeyStore
+	if cfg.AuthKeysFile != "" {
+		k, err := auth.LoadKeyStore(cfg.AuthKeysFile)
+		if err != nil {
+			return nil, err
+		}
+		keys = k
+	}
+
+	secret, err := cfg.GetJWTSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	var certScopes []auth.Scope
+	if cfg.ClientCAFile != "" && cfg.CertScopes != "" {
+		for _, s := range strings.Split(cfg.CertScopes, ",") {
+			scope, err := auth.ParseScope(strings.TrimSpace(s))
+			if err != nil {
+				return nil, err
+			}
+			certScopes = append(certScopes, scope)
+		}
+	}
+
+	return auth.NewAuthenticator(keys, secret, certScopes), nil
+}
+
+// reloadOnHangup перечитывает набор ключей подписи по сигналу SIGHUP.
+func reloadOnHangup(ctx context.Context, keyring *signing.Keyring, reloaded func(), l *zap.Logger) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+			if err := keyring.Reload(); err != nil {
+				l.Error("unable to reload keyring", zap.Error(err))
+				continue
+			}
+			l.Info("keyring reloaded")
+			reloaded()
+		}
+	}
+}
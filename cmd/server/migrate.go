@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Xacor/go-metrics/internal/logger"
+	"github.com/Xacor/go-metrics/internal/server/storage"
+	"github.com/Xacor/go-metrics/internal/server/storage/migrations"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"go.uber.org/zap"
+)
+
+const migrateUsage = `usage: server migrate [-d dsn] <command>
+
+commands:
+  up          apply all pending migrations
+  down [N]    roll back the last N migrations (default 1)
+  status      print migrations state
+`
+
+var errMigrateUsage = errors.New("invalid migrate command")
+
+// runMigrate реализует подкоманду migrate для управления схемой БД.
+func runMigrate(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), migrateUsage)
+		fs.PrintDefaults()
+	}
+	dsn := fs.String("d", os.Getenv("DATABASE_DSN"), "database dsn e.g. host=127.0.0.1 port=5432 user=user dbname=db password=pass or sqlite:///path/to/metrics.db")
+	timeout := fs.Duration("timeout", time.Minute, "migration timeout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *dsn == "" || fs.NArg() == 0 {
+		fs.Usage()
+		return errMigrateUsage
+	}
+
+	db, migrator, err := openMigrator(*dsn, logger.Get())
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	switch fs.Arg(0) {
+	case "up":
+		n, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("applied %d migration(s)\n", n)
+
+	case "down":
+		steps := 1
+		if fs.NArg() > 1 {
+			steps, err = strconv.Atoi(fs.Arg(1))
+			if err != nil || steps <= 0 {
+				return fmt.Errorf("%w: invalid number of steps %q", errMigrateUsage, fs.Arg(1))
+			}
+		}
+		n, err := migrator.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("reverted %d migration(s)\n", n)
+
+	case "status":
+		status, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, st := range status {
+			appliedAt := "pending"
+			if st.Applied {
+				appliedAt = st.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", st.Version, st.Name, appliedAt)
+		}
+		w.Flush()
+
+	default:
+		fs.Usage()
+		return fmt.Errorf("%w: %s", errMigrateUsage, fs.Arg(0))
+	}
+
+	return nil
+}
+
+// openMigrator открывает БД и выбирает набор миграций по DSN так же, как сервер:
+// DSN с префиксом sqlite:// относится к встроенной SQLite, остальные — к PostgreSQL.
+func openMigrator(dsn string, l *zap.Logger) (*sql.DB, *migrations.Migrator, error) {
+	var (
+		db  *sql.DB
+		err error
+		mk  = migrations.NewPostgres
+	)
+	if strings.HasPrefix(dsn, storage.SQLiteScheme) {
+		db, err = storage.OpenSQLite(dsn)
+		mk = migrations.NewSQLite
+	} else {
+		db, err = sql.Open("pgx", dsn)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	migrator, err := mk(db, l)
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+
+	return db, migrator, nil
+}
@@ -2,34 +2,74 @@ package config
 
 import (
 	"crypto/rsa"
+	"crypto/tls"
 	"crypto/x509"
+	"encoding/json"
 	"encoding/pem"
 	"errors"
 	"fmt"
 	"os"
+
+	"github.com/Xacor/go-metrics/internal/agent/metric"
+	"github.com/Xacor/go-metrics/internal/agent/receiver"
+	"github.com/Xacor/go-metrics/internal/tlsconfig"
 )
 
 type Config struct {
 	GRPCConfig
+	// Настройки коллекторов по их именам, задаются только в файле конфигурации.
+	CollectorOptions map[string]json.RawMessage `json:"collector_options"`
+	Collectors       []string                   `env:"COLLECTORS" envSeparator:"," json:"collectors"`
+	// Адреса приёма метрик от приложений; пустой адрес отключает приём.
+	StatsDAddress string `env:"STATSD_ADDRESS" json:"statsd_address"`
+	PushAddress   string `env:"PUSH_ADDRESS" json:"push_address"`
+	// Наибольшее число имён метрик приложений между отправками и разрешение
+	// принимать метрики не только на loopback-адресе.
+	ReceiverMaxNames    int  `env:"RECEIVER_MAX_NAMES" json:"receiver_max_names"`
+	ReceiverAllowRemote bool `env:"RECEIVER_ALLOW_REMOTE" json:"receiver_allow_remote"`
+
 	Address             string `env:"ADDRESS" json:"address"`
 	LogLevel            string `env:"LOG_LEVEL" json:"log_level"`
 	Key                 string `env:"KEY" json:"key"`
+	KeyID               string `env:"KEY_ID" json:"key_id"`
 	CryptoKeyPublicFile string `env:"CRYPTO_KEY" json:"crypto_key"`
+	CryptoKeyID         string `env:"CRYPTO_KEY_ID" json:"crypto_key_id"`
 	ConfigFile          string `env:"CONFIG" json:"-"`
+	APIKey              string `env:"API_KEY" json:"api_key"`
+	TenantID            string `env:"TENANT_ID" json:"tenant_id"`
+	TenantToken         string `env:"TENANT_TOKEN" json:"tenant_token"`
 	ReportInterval      int    `env:"REPORT_INTERVAL" json:"report_interval"`
 	PollInterval        int    `env:"POLL_INTERVAL" json:"poll_interval"`
 	RateLimit           int    `env:"RATE_LIMIT" json:"rate_limit"`
+	HTTPS               bool   `env:"HTTPS" json:"https"`
 }
 
 type GRPCConfig struct {
-	GRPCAddress string `env:"G_ADDRESS" json:"g_address"`
-	CACertFile  string `env:"CA_CERT" json:"ca_cert"`
+	GRPCAddress    string `env:"G_ADDRESS" json:"g_address"`
+	CACertFile     string `env:"CA_CERT" json:"ca_cert"`
+	ClientCertFile string `env:"CLIENT_CERT" json:"client_cert"`
+	ClientKeyFile  string `env:"CLIENT_KEY" json:"client_key"`
 }
 
 func (c *Config) GetURL() string {
+	if c.HTTPS {
+		return fmt.Sprintf("https://%s", c.Address)
+	}
 	return fmt.Sprintf("http://%s", c.Address)
 }
 
+// TLSEnabled() сообщает, нужно ли подключаться к серверу по gRPC через TLS:
+// при включённом HTTPS или заданных сертификатах. Без CA сертификат сервера
+// проверяется по системным корневым сертификатам.
+func (c *Config) TLSEnabled() bool {
+	return c.HTTPS || c.CACertFile != "" || c.ClientCertFile != ""
+}
+
+// GetTLSConfig() возвращает настройки TLS, общие для HTTP и gRPC.
+func (c *Config) GetTLSConfig() (*tls.Config, error) {
+	return tlsconfig.Client(c.CACertFile, c.ClientCertFile, c.ClientKeyFile)
+}
+
 func (c *Config) GetReportInterval() int {
 	return c.ReportInterval
 }
@@ -54,6 +94,21 @@ func (c *Config) GetRateLimit() int {
 	return c.RateLimit
 }
 
+// GetCollectors() создаёт включённые коллекторы метрик.
+func (c *Config) GetCollectors() (map[string]metric.Collector, error) {
+	names := c.Collectors
+	if len(names) == 0 {
+		names = metric.DefaultCollectors
+	}
+
+	return metric.NewCollectors(names, c.CollectorOptions)
+}
+
+// GetReceiverOptions() возвращает настройки приёмников метрик от приложений.
+func (c *Config) GetReceiverOptions() receiver.Options {
+	return receiver.Options{MaxNames: c.ReceiverMaxNames, AllowRemote: c.ReceiverAllowRemote}
+}
+
 func (c *Config) GetPublicKey() (*rsa.PublicKey, error) {
 	if c.CryptoKeyPublicFile == "" {
 		return nil, nil
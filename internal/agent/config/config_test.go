@@ -0,0 +1,37 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_TLSEnabled(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		want bool
+	}{
+		{name: "plaintext", cfg: Config{}, want: false},
+		{name: "https", cfg: Config{HTTPS: true}, want: true},
+		{name: "ca cert", cfg: Config{GRPCConfig: GRPCConfig{CACertFile: "ca.pem"}}, want: true},
+		{name: "client cert", cfg: Config{GRPCConfig: GRPCConfig{ClientCertFile: "client.pem"}}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.cfg.TLSEnabled())
+		})
+	}
+}
+
+func TestConfig_GetTLSConfig_SystemRoots(t *testing.T) {
+	cfg := Config{HTTPS: true}
+
+	tlsConfig, err := cfg.GetTLSConfig()
+	require.NoError(t, err)
+	// без CA сертификат сервера проверяется по системным корневым сертификатам
+	assert.Nil(t, tlsConfig.RootCAs)
+	assert.False(t, tlsConfig.InsecureSkipVerify)
+	assert.Equal(t, "https://localhost:8080", (&Config{HTTPS: true, Address: "localhost:8080"}).GetURL())
+}
@@ -6,7 +6,9 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 
+	"github.com/Xacor/go-metrics/internal/agent/receiver"
 	"github.com/caarlos0/env/v6"
 )
 
@@ -14,13 +16,29 @@ func (c *Config) ParseFlags() {
 	flag.StringVar(&c.Address, "a", "localhost:8080", "destination server address")
 	flag.StringVar(&c.GRPCAddress, "g", "localhost:8081", "destination server address")
 	flag.StringVar(&c.CACertFile, "ca", "", "ca cert file")
+	flag.StringVar(&c.ClientCertFile, "client-cert", "", "client certificate presented to the server")
+	flag.StringVar(&c.ClientKeyFile, "client-key", "", "client certificate key")
+	flag.BoolVar(&c.HTTPS, "https", false, "send metrics over HTTPS and gRPC over TLS")
 	flag.StringVar(&c.LogLevel, "log", "info", "log level")
 	flag.StringVar(&c.Key, "k", "", "signature key")
+	flag.StringVar(&c.KeyID, "key-id", "", "id of signature key in server keyring")
 	flag.StringVar(&c.CryptoKeyPublicFile, "crypto-key", "", "path to RSA public key file in PEM format")
+	flag.StringVar(&c.CryptoKeyID, "crypto-key-id", "", "id of server RSA key matching crypto-key")
 	flag.StringVar(&c.ConfigFile, "c", "", "path to configuration file")
+	flag.StringVar(&c.APIKey, "api-key", "", "api key for server authentication")
+	flag.StringVar(&c.TenantID, "tenant", "", "tenant id")
+	flag.StringVar(&c.TenantToken, "tenant-token", "", "tenant token")
 	flag.IntVar(&c.ReportInterval, "r", 5, "report interval in seconds")
 	flag.IntVar(&c.PollInterval, "p", 2, "poll interval in seconds")
 	flag.IntVar(&c.RateLimit, "l", 1, "rate limit")
+	flag.StringVar(&c.StatsDAddress, "statsd", "", "udp address to receive statsd metrics from applications, e.g. localhost:8125")
+	flag.StringVar(&c.PushAddress, "push", "", "http address to receive metrics pushed by applications, e.g. localhost:8126")
+	flag.IntVar(&c.ReceiverMaxNames, "receiver-max-names", receiver.DefaultMaxNames, "distinct metric names accepted from applications between reports")
+	flag.BoolVar(&c.ReceiverAllowRemote, "receiver-allow-remote", false, "accept application metrics on non-loopback addresses, receivers do not authenticate clients")
+	flag.Func("collectors", "comma separated collectors to enable (default runtime,process,additional)", func(names string) error {
+		c.Collectors = strings.Split(names, ",")
+		return nil
+	})
 	flag.Parse()
 }
 
@@ -57,7 +75,9 @@ func (c *Config) ParseAll() error {
 			return err
 		}
 
-		c.ParseFlags()
+		// флаги уже определены, поэтому повторно только разбираются,
+		// чтобы явно заданные значения имели приоритет над файлом
+		flag.Parse()
 
 		if err := c.ParseEnvs(); err != nil {
 			return err
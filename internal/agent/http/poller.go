@@ -3,54 +3,85 @@ package http
 import (
 	"bytes"
 	"context"
-	"crypto/rand"
 	"crypto/rsa"
-	"crypto/sha256"
+	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/Xacor/go-metrics/internal/agent/metric"
+	"github.com/Xacor/go-metrics/internal/envelope"
 	"github.com/Xacor/go-metrics/proto"
-	"github.com/pkg/errors"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	protobuf "google.golang.org/protobuf/proto"
 )
 
 type PollerConfig struct {
-	MetricCh       <-chan metric.UpdateResult
+	Source         *metric.Accumulator
 	Client         *http.Client
 	GrpcClient     proto.MetricsClient
 	Logger         *zap.Logger
 	PublicKey      *rsa.PublicKey
 	Address        string
+	CryptoKeyID    string
 	Key            string
+	KeyID          string
+	APIKey         string
+	TenantID       string
+	TenantToken    string
 	ReportInterval int
 	RateLimit      int
 }
 
 type Poller struct {
-	metricCh       <-chan metric.UpdateResult
+	source         *metric.Accumulator
 	client         *http.Client
 	grpcClient     proto.MetricsClient
 	logger         *zap.Logger
 	publicKey      *rsa.PublicKey
 	address        string
+	cryptoKeyID    string
 	key            string
+	keyID          string
+	apiKey         string
+	tenantID       string
+	tenantToken    string
 	reportInterval int
 	rateLimit      int
+	// приращения счётчиков, которые не удалось отправить по HTTP и gRPC
+	unsentHTTP *metric.Accumulator
+	unsentGRPC *metric.Accumulator
+	// способы отправки и пауза между попытками, подменяются в тестах
+	httpSend func(metric.Metrics) error
+	grpcSend func(metric.Metrics) error
+	sleep    func(time.Duration)
 }
 
 func NewPoller(cfg *PollerConfig) *Poller {
 	p := &Poller{
 		reportInterval: cfg.ReportInterval,
 		address:        cfg.Address,
-		metricCh:       cfg.MetricCh,
+		cryptoKeyID:    cfg.CryptoKeyID,
+		source:         cfg.Source,
 		client:         cfg.Client,
 		grpcClient:     cfg.GrpcClient,
 		logger:         cfg.Logger,
 		key:            cfg.Key,
+		keyID:          cfg.KeyID,
+		apiKey:         cfg.APIKey,
+		tenantID:       cfg.TenantID,
+		tenantToken:    cfg.TenantToken,
 		rateLimit:      cfg.RateLimit,
+		unsentHTTP:     metric.NewAccumulator(),
+		unsentGRPC:     metric.NewAccumulator(),
+		sleep:          time.Sleep,
 	}
+	p.httpSend = p.sendHTTP
+	p.grpcSend = p.sendGRPC
 
 	if cfg.PublicKey != nil {
 		p.publicKey = cfg.PublicKey
@@ -64,25 +95,22 @@ func (p *Poller) Run(ctx context.Context, exitCh chan struct{}) {
 	semaphore := NewSemaphore(p.rateLimit)
 
 	t := time.NewTicker(time.Second * time.Duration(p.reportInterval))
-	var snap metric.UpdateResult
 	for {
 		select {
-		case snap = <-p.metricCh:
-			if snap.Err != nil {
-				p.logger.Error("failed to read from UpdateResult", zap.Error(snap.Err))
+		case <-t.C:
+			m := p.source.Drain()
+			if len(m.Samples) == 0 {
 				continue
 			}
 
-		case <-t.C:
 			go func(m metric.Metrics) {
 				semaphore.Acquire()
 				defer semaphore.Release()
 
-				err := p.Send(m)
-				if err != nil {
-					p.retry(p.Send, m)
+				if err := p.Send(m); err != nil {
+					p.logger.Error("unable to send metrics", zap.Error(err))
 				}
-			}(snap.Metrics)
+			}(m)
 
 		case <-ctx.Done():
 			p.logger.Info("sending latest metrics batch")
@@ -90,9 +118,8 @@ func (p *Poller) Run(ctx context.Context, exitCh chan struct{}) {
 			semaphore.Acquire()
 			defer semaphore.Release()
 
-			err := p.Send(snap.Metrics)
-			if err != nil {
-				p.retry(p.Send, snap.Metrics)
+			if err := p.Send(p.source.Drain()); err != nil {
+				p.logger.Error("unable to send metrics", zap.Error(err))
 			}
 
 			exitCh <- struct{}{}
@@ -101,23 +128,63 @@ func (p *Poller) Run(ctx context.Context, exitCh chan struct{}) {
 	}
 }
 
+// Send() отправляет метрики по HTTP и gRPC, повторяя неудачные отправки.
+// Приращения счётчиков, которые не удалось отправить одним из способов,
+// добавляются к следующей отправке этим же способом.
 func (p *Poller) Send(m metric.Metrics) error {
-	p.sendHTTP(m)
-	p.sendGRPC(m)
-	return nil
+	return errors.Join(p.send(p.httpSend, p.unsentHTTP, m), p.send(p.grpcSend, p.unsentGRPC, m))
 }
 
-func (p *Poller) retry(fn func(metric.Metrics) error, arg metric.Metrics) {
-	attempts := 0
-	var err error
-	for i := 1; i < 5; i += 2 {
-		time.Sleep(time.Second * time.Duration(i))
-		if err = fn(arg); err == nil {
-			return
+// send отправляет m вместе с накопленными в unsent приращениями счётчиков,
+// а при неудаче возвращает приращения в unsent.
+func (p *Poller) send(fn func(metric.Metrics) error, unsent *metric.Accumulator, m metric.Metrics) error {
+	if pending := counters(unsent.Drain()); len(pending) > 0 {
+		batch := metric.NewAccumulator()
+		batch.Add(m.Samples)
+		batch.Add(pending)
+		m = batch.Drain()
+	}
+
+	err := p.retry(fn, m)
+	if err != nil {
+		unsent.Add(counters(m))
+	}
+
+	return err
+}
+
+// counters возвращает ненулевые приращения счётчиков из m.
+func counters(m metric.Metrics) []metric.Sample {
+	var samples []metric.Sample
+	for _, s := range m.Samples {
+		if s.Type == metric.TypeCounter && s.Delta != 0 {
+			samples = append(samples, s)
 		}
+	}
+
+	return samples
+}
+
+// retry вызывает fn, повторяя вызов при ошибке с нарастающей паузой. Если сервер
+// отклонил запрос и указал время до повтора, пауза не меньше этого времени.
+func (p *Poller) retry(fn func(metric.Metrics) error, arg metric.Metrics) error {
+	err := fn(arg)
+	attempts := 0
+	for i := 1; i < 5 && err != nil; i += 2 {
 		attempts++
 		p.logger.Error("attempt failed", zap.Error(err), zap.Int("attempt #", attempts))
+
+		delay := time.Second * time.Duration(i)
+		var throttled *ThrottledError
+		if errors.As(err, &throttled) && throttled.RetryAfter > delay {
+			delay = throttled.RetryAfter
+		}
+		p.sleep(delay)
+
+		err = fn(arg)
 	}
+
+	return err
 }
 
 func (p *Poller) sendGRPC(m metric.Metrics) error {
@@ -129,28 +196,40 @@ func (p *Poller) sendGRPC(m metric.Metrics) error {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
 
+	if p.apiKey != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "X-API-Key", p.apiKey)
+	}
+	if p.tenantToken != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "X-Tenant-ID", p.tenantID, "X-Tenant-Token", p.tenantToken)
+	}
+
+	req := &proto.UpdateListRequest{Metric: pb}
+
 	if p.key != "" {
-		json, err := m.MarshalJSON()
+		data, err := protobuf.MarshalOptions{Deterministic: true}.Marshal(req)
 		if err != nil {
 			return err
 		}
-		sign, err := Sign(json, p.key)
+		sign, err := Sign(data, p.key, p.keyID)
 		if err != nil {
 			return err
 		}
-		metadata.AppendToOutgoingContext(ctx, "HashSHA256", string(sign))
-
+		ctx = metadata.AppendToOutgoingContext(ctx, sign.Pairs()...)
 	}
 
 	ip, err := GetLocalIP()
 	if err != nil {
 		return err
 	}
-	metadata.AppendToOutgoingContext(ctx, "X-Real-IP", ip)
+	ctx = metadata.AppendToOutgoingContext(ctx, "X-Real-IP", ip)
 
-	_, err = p.grpcClient.UpdateList(ctx, &proto.UpdateListRequest{Metric: pb})
+	var trailer metadata.MD
+	_, err = p.grpcClient.UpdateList(ctx, req, grpc.Trailer(&trailer))
+	if status.Code(err) == codes.ResourceExhausted {
+		return &ThrottledError{RetryAfter: parseRetryAfter(first(trailer, "retry-after"))}
+	}
 	if err != nil {
-		return errors.Wrap(err, "unable to make grpc call")
+		return fmt.Errorf("unable to make grpc call: %w", err)
 	}
 
 	return nil
@@ -170,16 +249,11 @@ func (p *Poller) sendHTTP(m metric.Metrics) error {
 	reader := bytes.NewReader(compressed)
 
 	if p.publicKey != nil {
-		encryptedBytes, err := rsa.EncryptOAEP(
-			sha256.New(),
-			rand.Reader,
-			p.publicKey,
-			compressed,
-			nil)
+		encrypted, err := envelope.Seal(p.publicKey, p.cryptoKeyID, compressed)
 		if err != nil {
 			return err
 		}
-		reader = bytes.NewReader(encryptedBytes)
+		reader = bytes.NewReader(encrypted)
 	}
 
 	request, err := http.NewRequest(http.MethodPost, p.address+"/updates/", reader)
@@ -188,11 +262,19 @@ func (p *Poller) sendHTTP(m metric.Metrics) error {
 	}
 
 	if p.key != "" {
-		sign, err := Sign(json, p.key)
+		sign, err := Sign(json, p.key, p.keyID)
 		if err != nil {
 			return err
 		}
-		request.Header.Set("HashSHA256", string(sign))
+		sign.SetHeader(request.Header)
+	}
+
+	if p.apiKey != "" {
+		request.Header.Set("X-API-Key", p.apiKey)
+	}
+	if p.tenantToken != "" {
+		request.Header.Set("X-Tenant-ID", p.tenantID)
+		request.Header.Set("X-Tenant-Token", p.tenantToken)
 	}
 
 	request.Header.Set("Content-Encoding", "gzip")
@@ -210,5 +292,20 @@ func (p *Poller) sendHTTP(m metric.Metrics) error {
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return &ThrottledError{RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("unexpected response status %s", resp.Status)
+	}
+
 	return nil
 }
+
+func first(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
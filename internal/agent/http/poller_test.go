@@ -0,0 +1,102 @@
+package http
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Xacor/go-metrics/internal/agent/metric"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// fakeSender запоминает доставленные метрики и отклоняет первые fails вызовов.
+type fakeSender struct {
+	batches [][]metric.Sample
+	fails   int
+	mu      sync.Mutex
+}
+
+func (f *fakeSender) send(m metric.Metrics) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.fails > 0 {
+		f.fails--
+		return errors.New("unavailable")
+	}
+	f.batches = append(f.batches, m.Samples)
+
+	return nil
+}
+
+func (f *fakeSender) delivered(name string) int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var total int64
+	for _, batch := range f.batches {
+		for _, s := range batch {
+			if s.Name == name && s.Type == metric.TypeCounter {
+				total += s.Delta
+			}
+		}
+	}
+
+	return total
+}
+
+func newTestPoller(httpSender, grpcSender *fakeSender) *Poller {
+	p := NewPoller(&PollerConfig{Logger: zap.NewNop(), RateLimit: 1})
+	p.httpSend = httpSender.send
+	p.grpcSend = grpcSender.send
+	p.sleep = func(time.Duration) {}
+
+	return p
+}
+
+func TestPoller_Send(t *testing.T) {
+	// HTTP отклоняет все попытки первой отправки, gRPC доступен
+	httpSender, grpcSender := &fakeSender{fails: 3}, &fakeSender{}
+	p := newTestPoller(httpSender, grpcSender)
+
+	err := p.Send(metric.Metrics{Samples: []metric.Sample{metric.CounterSample("PollCount", 5), metric.GaugeSample("Load", 1)}})
+	assert.Error(t, err)
+
+	assert.NoError(t, p.Send(metric.Metrics{Samples: []metric.Sample{metric.CounterSample("PollCount", 2), metric.GaugeSample("Load", 2)}}))
+	assert.NoError(t, p.Send(metric.Metrics{Samples: []metric.Sample{metric.CounterSample("PollCount", 1)}}))
+
+	// неотправленное приращение добавлено к следующей отправке ровно один раз,
+	// прежнее значение gauge повторно не передаётся
+	assert.Equal(t, [][]metric.Sample{
+		{metric.GaugeSample("Load", 2), metric.CounterSample("PollCount", 7)},
+		{metric.CounterSample("PollCount", 1)},
+	}, httpSender.batches)
+	assert.Equal(t, [][]metric.Sample{
+		{metric.CounterSample("PollCount", 5), metric.GaugeSample("Load", 1)},
+		{metric.CounterSample("PollCount", 2), metric.GaugeSample("Load", 2)},
+		{metric.CounterSample("PollCount", 1)},
+	}, grpcSender.batches)
+}
+
+func TestPoller_SendConcurrent(t *testing.T) {
+	httpSender, grpcSender := &fakeSender{fails: 7}, &fakeSender{fails: 4}
+	p := newTestPoller(httpSender, grpcSender)
+
+	const sends = 20
+	var wg sync.WaitGroup
+	for i := 0; i < sends; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.Send(metric.Metrics{Samples: []metric.Sample{metric.CounterSample("PollCount", 1)}})
+		}()
+	}
+	wg.Wait()
+
+	// после очередной отправки доставлены все приращения без повторов
+	assert.NoError(t, p.Send(metric.Metrics{}))
+	assert.Equal(t, int64(sends), httpSender.delivered("PollCount"))
+	assert.Equal(t, int64(sends), grpcSender.delivered("PollCount"))
+}
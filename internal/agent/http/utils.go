@@ -3,19 +3,56 @@ package http
 import (
 	"bytes"
 	"compress/gzip"
-	"crypto/hmac"
-	"crypto/sha256"
 	"errors"
+	"fmt"
 	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Xacor/go-metrics/internal/signing"
 )
 
-func Sign(data []byte, key string) ([]byte, error) {
-	h := hmac.New(sha256.New, []byte(key))
-	if _, err := h.Write(data); err != nil {
-		return nil, err
+// Sign() подписывает данные запроса вместе со временем отправки и nonce,
+// чтобы сервер мог отклонить повторно отправленный запрос. Идентификатор keyID
+// указывает серверу, каким ключом из набора проверять подпись.
+func Sign(data []byte, key, keyID string) (signing.Signature, error) {
+	sign, err := signing.Sign([]byte(key), data)
+	if err != nil {
+		return sign, err
+	}
+	sign.KeyID = keyID
+
+	return sign, nil
+}
+
+// Ошибка отправки, отклонённой сервером из-за ограничения частоты запросов или перегрузки.
+type ThrottledError struct {
+	// Время, через которое сервер предлагает повторить запрос. Нулевое, если не указано.
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return fmt.Sprintf("request throttled by server, retry after %v", e.RetryAfter)
+}
+
+// parseRetryAfter разбирает значение заголовка Retry-After: количество секунд или дату.
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if d := time.Until(at); d > 0 {
+			return d
+		}
 	}
 
-	return h.Sum(nil), nil
+	return 0
 }
 
 func Compress(data []byte) ([]byte, error) {
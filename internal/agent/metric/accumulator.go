@@ -0,0 +1,144 @@
+package metric
+
+import (
+	"sort"
+	"sync"
+)
+
+// Число отправок, после которого метрика без новых значений удаляется из накопителя.
+const expireReports = 10
+
+// Накопитель значений метрик между отправками на сервер: для метрик типа gauge
+// хранится последнее значение, для метрик типа counter — сумма приращений
+// с предыдущей отправки.
+type Accumulator struct {
+	gauges   map[string]*accEntry
+	counters map[string]*accEntry
+	// число метрик, впервые добавленных через AddLimited
+	limited int
+	mu      sync.Mutex
+}
+
+type accEntry struct {
+	value float64
+	delta int64
+	// число отправок без новых значений
+	idle    int
+	limited bool
+}
+
+func NewAccumulator() *Accumulator {
+	return &Accumulator{
+		gauges:   make(map[string]*accEntry),
+		counters: make(map[string]*accEntry),
+	}
+}
+
+// Add() учитывает собранные значения.
+func (a *Accumulator) Add(samples []Sample) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, s := range samples {
+		a.add(s, false)
+	}
+}
+
+// AddLimited() учитывает значения из внешних источников, как Add(), но пропускает
+// значения метрик с новыми именами, если через AddLimited() уже добавлено limit
+// имён. Метрики, добавленные через Add(), в лимите не учитываются.
+// Возвращает число пропущенных значений.
+func (a *Accumulator) AddLimited(samples []Sample, limit int) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var dropped int
+	for _, s := range samples {
+		if a.entries(s.Type) == nil {
+			continue
+		}
+		if _, ok := a.entries(s.Type)[s.Name]; !ok && a.limited >= limit {
+			dropped++
+			continue
+		}
+		a.add(s, true)
+	}
+
+	return dropped
+}
+
+func (a *Accumulator) entries(t string) map[string]*accEntry {
+	switch t {
+	case TypeCounter:
+		return a.counters
+	case TypeGauge:
+		return a.gauges
+	default:
+		return nil
+	}
+}
+
+func (a *Accumulator) add(s Sample, limited bool) {
+	entries := a.entries(s.Type)
+	if entries == nil {
+		return
+	}
+
+	e, ok := entries[s.Name]
+	if !ok {
+		e = &accEntry{limited: limited}
+		entries[s.Name] = e
+		if limited {
+			a.limited++
+		}
+	}
+
+	e.idle = 0
+	switch s.Type {
+	case TypeCounter:
+		e.delta += s.Delta
+	case TypeGauge:
+		e.value = s.Value
+	}
+}
+
+// Drain() возвращает накопленные значения для отправки и обнуляет приращения счётчиков.
+// Gauge сохраняют значение между отправками. Метрики, не обновлявшиеся
+// expireReports отправок подряд, удаляются и больше не передаются.
+func (a *Accumulator) Drain() Metrics {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	samples := make([]Sample, 0, len(a.gauges)+len(a.counters))
+	for name, e := range a.gauges {
+		if a.expire(a.gauges, name, e) {
+			continue
+		}
+		samples = append(samples, GaugeSample(name, e.value))
+	}
+	for name, e := range a.counters {
+		if a.expire(a.counters, name, e) {
+			continue
+		}
+		samples = append(samples, CounterSample(name, e.delta))
+		e.delta = 0
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i].Name < samples[j].Name })
+
+	return Metrics{Samples: samples}
+}
+
+// expire удаляет запись, если она не обновлялась expireReports отправок,
+// иначе увеличивает счётчик отправок без обновлений.
+func (a *Accumulator) expire(entries map[string]*accEntry, name string, e *accEntry) bool {
+	if e.idle >= expireReports {
+		delete(entries, name)
+		if e.limited {
+			a.limited--
+		}
+		return true
+	}
+	e.idle++
+
+	return false
+}
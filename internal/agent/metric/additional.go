@@ -1,19 +1,28 @@
 package metric
 
-import "math/rand"
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+)
 
-type Additional struct {
-	PollCount   Counter
-	RandomValue Gauge
+func init() {
+	Register("additional", func(json.RawMessage) (Collector, error) {
+		return NewAdditional(), nil
+	})
 }
 
+// Количество опросов агента и случайное значение.
+type Additional struct{}
+
 func NewAdditional() *Additional {
 	return &Additional{}
 }
 
-func (a *Additional) Update() error {
-	a.PollCount++
-	a.RandomValue = Gauge(rand.Float64())
-
-	return nil
+// Collect() возвращает приращение счётчика опросов PollCount и новое значение RandomValue.
+func (a *Additional) Collect(context.Context) ([]Sample, error) {
+	return []Sample{
+		CounterSample("PollCount", 1),
+		GaugeSample("RandomValue", rand.Float64()),
+	}, nil
 }
@@ -0,0 +1,343 @@
+package metric
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	Register("cgroup", func(options json.RawMessage) (Collector, error) {
+		var opts CgroupOptions
+		if err := decodeOptions(options, &opts); err != nil {
+			return nil, err
+		}
+		return NewCgroup(opts)
+	})
+}
+
+var ErrNoCgroup = errors.New("cgroup of agent not found")
+
+// Значения лимитов cgroup v1 не меньше этого означают отсутствие ограничения.
+const cgroupV1Unlimited = 1 << 62
+
+// Настройки коллектора cgroup.
+type CgroupOptions struct {
+	// Каталог, в который смонтирована файловая система cgroup. По умолчанию /sys/fs/cgroup.
+	Root string `json:"root"`
+	// Путь группы относительно корня иерархии. По умолчанию группа агента из /proc/self/cgroup.
+	Path string `json:"path"`
+	// Сообщать также значения соседних групп с тем же родителем.
+	Siblings bool `json:"siblings"`
+}
+
+// Потребление ресурсов контейнером или другой группой процессов по данным cgroup
+// версии 1 или 2: память и её лимит, квота процессора и её исчерпание.
+// Метрики соседних групп дополняются их именами, например CgroupMemoryUsage_nginx_service.
+type Cgroup struct {
+	counters *deltas
+	// пути группы относительно иерархий контроллеров; для cgroup v2 иерархия одна
+	paths    map[string]string
+	root     string
+	v2       bool
+	siblings bool
+}
+
+func NewCgroup(opts CgroupOptions) (*Cgroup, error) {
+	c := &Cgroup{counters: newDeltas(), root: opts.Root, siblings: opts.Siblings}
+	if c.root == "" {
+		c.root = "/sys/fs/cgroup"
+	}
+
+	_, err := os.Stat(filepath.Join(c.root, "cgroup.controllers"))
+	c.v2 = err == nil
+
+	if opts.Path != "" {
+		c.paths = groupPaths(opts.Path)
+		return c, nil
+	}
+
+	f, err := os.Open(hostProc("self/cgroup"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if c.paths, err = parseProcCgroup(f); err != nil {
+		return nil, err
+	}
+	if _, ok := c.paths[c.controller("memory")]; !ok {
+		return nil, ErrNoCgroup
+	}
+
+	// Внутри контейнера путь группы из /proc/self/cgroup может быть не виден,
+	// тогда корнем иерархии смонтирована сама группа контейнера.
+	for controller, rel := range c.paths {
+		if _, err := os.Stat(c.dir(controller, rel)); err != nil {
+			c.paths[controller] = "/"
+		}
+	}
+
+	return c, nil
+}
+
+// groupPaths возвращает пути группы rel во всех используемых иерархиях.
+func groupPaths(rel string) map[string]string {
+	return map[string]string{"": rel, "memory": rel, "cpu": rel, "cpuacct": rel}
+}
+
+// parseProcCgroup разбирает файл /proc/<pid>/cgroup со строками вида
+// «идентификатор:контроллеры:путь». Путь иерархии cgroup v2 возвращается
+// с пустым именем контроллера.
+func parseProcCgroup(r io.Reader) (map[string]string, error) {
+	paths := make(map[string]string)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if parts[0] == "0" && parts[1] == "" {
+			paths[""] = parts[2]
+			continue
+		}
+		for _, controller := range strings.Split(parts[1], ",") {
+			paths[controller] = parts[2]
+		}
+	}
+
+	return paths, scanner.Err()
+}
+
+// controller возвращает имя контроллера в paths: для cgroup v2 все контроллеры
+// находятся в одной иерархии.
+func (c *Cgroup) controller(name string) string {
+	if c.v2 {
+		return ""
+	}
+
+	return name
+}
+
+// dir возвращает каталог группы rel в иерархии контроллера.
+func (c *Cgroup) dir(controller, rel string) string {
+	if c.v2 {
+		return filepath.Join(c.root, filepath.FromSlash(rel))
+	}
+
+	return filepath.Join(c.root, controller, filepath.FromSlash(rel))
+}
+
+// Collect() возвращает использование и лимит памяти, долю лимита и квоту процессора
+// в ядрах в gauge, приращения времени процессора, периодов планировщика, периодов
+// с исчерпанной квотой и времени ожидания квоты в микросекундах в counter.
+// Для групп без лимита памяти или квоты процессора эти значения не сообщаются.
+func (c *Cgroup) Collect(context.Context) ([]Sample, error) {
+	samples, err := c.collectGroup(c.paths, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if c.siblings {
+		siblings, err := c.collectSiblings()
+		samples = append(samples, siblings...)
+		if err != nil {
+			return samples, err
+		}
+	}
+	c.counters.prune()
+
+	return samples, nil
+}
+
+func (c *Cgroup) collectSiblings() ([]Sample, error) {
+	controller := c.controller("memory")
+	own := c.paths[controller]
+	// группа в корне иерархии или не видна из контейнера
+	if own == "/" || own == "" {
+		return nil, nil
+	}
+	parent := path.Dir(own)
+
+	entries, err := os.ReadDir(c.dir(controller, parent))
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		samples []Sample
+		errs    []error
+	)
+	for _, e := range entries {
+		rel := path.Join(parent, e.Name())
+		if !e.IsDir() || rel == own {
+			continue
+		}
+
+		s, err := c.collectGroup(groupPaths(rel), "_"+label(e.Name()))
+		// группа удалена после чтения каталога
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		samples = append(samples, s...)
+	}
+
+	return samples, errors.Join(errs...)
+}
+
+// collectGroup возвращает метрики группы с путями paths в иерархиях контроллеров.
+func (c *Cgroup) collectGroup(paths map[string]string, suffix string) ([]Sample, error) {
+	if c.v2 {
+		return c.collectV2(c.dir("", paths[""]), suffix)
+	}
+
+	return c.collectV1(paths, suffix)
+}
+
+func (c *Cgroup) collectV2(dir, suffix string) ([]Sample, error) {
+	usage, err := readCgroupUint(filepath.Join(dir, "memory.current"))
+	if err != nil {
+		return nil, err
+	}
+	samples := []Sample{GaugeSample("CgroupMemoryUsage"+suffix, float64(usage))}
+
+	if limit, err := readCgroupValue(filepath.Join(dir, "memory.max")); err == nil && limit != "max" {
+		if v, err := strconv.ParseUint(limit, 10, 64); err == nil {
+			samples = append(samples, memoryLimitSamples(usage, v, suffix)...)
+		}
+	}
+
+	if quota, err := readCgroupValue(filepath.Join(dir, "cpu.max")); err == nil {
+		if fields := strings.Fields(quota); len(fields) == 2 && fields[0] != "max" {
+			q, qErr := strconv.ParseFloat(fields[0], 64)
+			p, pErr := strconv.ParseFloat(fields[1], 64)
+			if qErr == nil && pErr == nil && p > 0 {
+				samples = append(samples, GaugeSample("CgroupCPUQuota"+suffix, q/p))
+			}
+		}
+	}
+
+	stat, err := readCgroupStat(filepath.Join(dir, "cpu.stat"))
+	if err != nil {
+		return samples, nil
+	}
+	samples = append(samples,
+		c.counters.sample("CgroupCPUUsageUsec"+suffix, stat["usage_usec"]),
+		c.counters.sample("CgroupCPUPeriods"+suffix, stat["nr_periods"]),
+		c.counters.sample("CgroupCPUThrottledPeriods"+suffix, stat["nr_throttled"]),
+		c.counters.sample("CgroupCPUThrottledUsec"+suffix, stat["throttled_usec"]),
+	)
+
+	return samples, nil
+}
+
+func (c *Cgroup) collectV1(paths map[string]string, suffix string) ([]Sample, error) {
+	memory := c.dir("memory", paths["memory"])
+	usage, err := readCgroupUint(filepath.Join(memory, "memory.usage_in_bytes"))
+	if err != nil {
+		return nil, err
+	}
+	samples := []Sample{GaugeSample("CgroupMemoryUsage"+suffix, float64(usage))}
+
+	if limit, err := readCgroupUint(filepath.Join(memory, "memory.limit_in_bytes")); err == nil && limit < cgroupV1Unlimited {
+		samples = append(samples, memoryLimitSamples(usage, limit, suffix)...)
+	}
+
+	// группа может не входить в иерархии контроллеров процессора
+	if rel, ok := paths["cpu"]; ok {
+		cpu := c.dir("cpu", rel)
+		quota, qErr := readCgroupValue(filepath.Join(cpu, "cpu.cfs_quota_us"))
+		period, pErr := readCgroupUint(filepath.Join(cpu, "cpu.cfs_period_us"))
+		if qErr == nil && pErr == nil && period > 0 {
+			if q, err := strconv.ParseInt(quota, 10, 64); err == nil && q > 0 {
+				samples = append(samples, GaugeSample("CgroupCPUQuota"+suffix, float64(q)/float64(period)))
+			}
+		}
+
+		if stat, err := readCgroupStat(filepath.Join(cpu, "cpu.stat")); err == nil {
+			samples = append(samples,
+				c.counters.sample("CgroupCPUPeriods"+suffix, stat["nr_periods"]),
+				c.counters.sample("CgroupCPUThrottledPeriods"+suffix, stat["nr_throttled"]),
+				// в cgroup v1 время указано в наносекундах
+				c.counters.sample("CgroupCPUThrottledUsec"+suffix, stat["throttled_time"]/1000),
+			)
+		}
+	}
+
+	if rel, ok := paths["cpuacct"]; ok {
+		if usage, err := readCgroupUint(filepath.Join(c.dir("cpuacct", rel), "cpuacct.usage")); err == nil {
+			samples = append(samples, c.counters.sample("CgroupCPUUsageUsec"+suffix, usage/1000))
+		}
+	}
+
+	return samples, nil
+}
+
+func memoryLimitSamples(usage, limit uint64, suffix string) []Sample {
+	samples := []Sample{GaugeSample("CgroupMemoryLimit"+suffix, float64(limit))}
+	if limit > 0 {
+		samples = append(samples, GaugeSample("CgroupMemoryUsedPercent"+suffix, 100*float64(usage)/float64(limit)))
+	}
+
+	return samples
+}
+
+func readCgroupValue(name string) (string, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
+
+func readCgroupUint(name string) (uint64, error) {
+	value, err := readCgroupValue(name)
+	if err != nil {
+		return 0, err
+	}
+
+	v, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value in %s: %w", name, err)
+	}
+
+	return v, nil
+}
+
+// readCgroupStat читает файл со строками «ключ значение», например cpu.stat.
+func readCgroupStat(name string) (map[string]uint64, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	stat := make(map[string]uint64)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		if v, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+			stat[fields[0]] = v
+		}
+	}
+
+	return stat, scanner.Err()
+}
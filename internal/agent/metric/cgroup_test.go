@@ -0,0 +1,154 @@
+package metric
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProcCgroup(t *testing.T) {
+	f, err := os.Open("testdata/cgroup/proc-v1/self/cgroup")
+	require.NoError(t, err)
+	defer f.Close()
+
+	paths, err := parseProcCgroup(f)
+	require.NoError(t, err)
+	assert.Equal(t, "/docker/abc", paths["memory"])
+	assert.Equal(t, "/docker/abc", paths["cpuacct"])
+	assert.NotContains(t, paths, "")
+}
+
+func TestCgroup_Collect(t *testing.T) {
+	tests := []struct {
+		name     string
+		procRoot string
+		opts     CgroupOptions
+		want     map[string]float64
+		absent   []string
+	}{
+		{
+			name:     "v2_with_siblings",
+			procRoot: "testdata/cgroup/proc-v2",
+			opts:     CgroupOptions{Root: "testdata/cgroup/v2", Siblings: true},
+			want: map[string]float64{
+				"CgroupMemoryUsage":               52428800,
+				"CgroupMemoryLimit":               104857600,
+				"CgroupMemoryUsedPercent":         50,
+				"CgroupCPUQuota":                  0.5,
+				"CgroupCPUThrottledUsec":          0,
+				"CgroupMemoryUsage_nginx_service": 8388608,
+			},
+			absent: []string{"CgroupMemoryLimit_nginx_service", "CgroupCPUQuota_nginx_service"},
+		},
+		{
+			name:     "v1_unlimited_memory",
+			procRoot: "testdata/cgroup/proc-v1",
+			opts:     CgroupOptions{Root: "testdata/cgroup/v1"},
+			want: map[string]float64{
+				"CgroupMemoryUsage":      209715200,
+				"CgroupCPUQuota":         1.5,
+				"CgroupCPUUsageUsec":     0,
+				"CgroupCPUThrottledUsec": 0,
+			},
+			absent: []string{"CgroupMemoryLimit"},
+		},
+		{
+			name:     "explicit_path",
+			procRoot: "testdata/cgroup/missing",
+			opts:     CgroupOptions{Root: "testdata/cgroup/v2", Path: "/system.slice/nginx.service"},
+			want:     map[string]float64{"CgroupMemoryUsage": 8388608},
+			absent:   []string{"CgroupMemoryLimit", "CgroupCPUQuota"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HOST_PROC", tt.procRoot)
+
+			c, err := NewCgroup(tt.opts)
+			require.NoError(t, err)
+
+			values := collectGauges(t, c)
+			for name, want := range tt.want {
+				assert.Contains(t, values, name)
+				assert.Equal(t, want, values[name], name)
+			}
+			for _, name := range tt.absent {
+				assert.NotContains(t, values, name)
+			}
+		})
+	}
+}
+
+func TestCgroup_Container(t *testing.T) {
+	// внутри контейнера путь группы из /proc/self/cgroup не виден,
+	// корнем иерархии смонтирована группа контейнера
+	proc := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(proc, "self"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(proc, "self", "cgroup"), []byte("0::/docker/abc\n"), 0o600))
+	t.Setenv("HOST_PROC", proc)
+
+	root := t.TempDir()
+	write := func(name, value string) {
+		require.NoError(t, os.WriteFile(filepath.Join(root, name), []byte(value+"\n"), 0o600))
+	}
+	write("cgroup.controllers", "cpu memory")
+	write("memory.current", "1024")
+	write("memory.max", "4096")
+	write("cpu.stat", "usage_usec 100\nnr_periods 10\nnr_throttled 1\nthrottled_usec 50")
+
+	c, err := NewCgroup(CgroupOptions{Root: root, Siblings: true})
+	require.NoError(t, err)
+
+	samples, err := c.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Contains(t, samples, GaugeSample("CgroupMemoryUsedPercent", 25))
+	assert.Contains(t, samples, CounterSample("CgroupCPUThrottledPeriods", 0))
+
+	write("cpu.stat", "usage_usec 400\nnr_periods 20\nnr_throttled 4\nthrottled_usec 250")
+	samples, err = c.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Contains(t, samples, CounterSample("CgroupCPUUsageUsec", 300))
+	assert.Contains(t, samples, CounterSample("CgroupCPUThrottledPeriods", 3))
+	assert.Contains(t, samples, CounterSample("CgroupCPUThrottledUsec", 200))
+}
+
+func TestCgroup_SiblingRemoved(t *testing.T) {
+	proc := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(proc, "self"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(proc, "self", "cgroup"), []byte("0::/system.slice/agent.service\n"), 0o600))
+	t.Setenv("HOST_PROC", proc)
+
+	root := t.TempDir()
+	for _, dir := range []string{"agent.service", "nginx.service", "removed.service"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, "system.slice", dir), 0o755))
+	}
+	write := func(name, value string) {
+		require.NoError(t, os.WriteFile(filepath.Join(root, filepath.FromSlash(name)), []byte(value+"\n"), 0o600))
+	}
+	write("cgroup.controllers", "cpu memory")
+	write("system.slice/agent.service/memory.current", "1024")
+	write("system.slice/nginx.service/memory.current", "2048")
+
+	c, err := NewCgroup(CgroupOptions{Root: root, Siblings: true})
+	require.NoError(t, err)
+
+	// каталог removed.service без файлов: группа удалена после чтения каталога
+	samples, err := c.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Contains(t, samples, GaugeSample("CgroupMemoryUsage", 1024))
+	assert.Contains(t, samples, GaugeSample("CgroupMemoryUsage_nginx_service", 2048))
+	for _, s := range samples {
+		assert.NotContains(t, s.Name, "removed")
+	}
+
+	// исчезновение собственной группы — ошибка, а не значения корня иерархии
+	write("memory.current", "4096")
+	require.NoError(t, os.RemoveAll(filepath.Join(root, "system.slice", "agent.service")))
+	_, err = c.Collect(context.Background())
+	assert.Error(t, err)
+}
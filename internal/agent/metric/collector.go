@@ -0,0 +1,117 @@
+package metric
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Типы метрик, которые принимает сервер.
+const (
+	TypeCounter = "counter"
+	TypeGauge   = "gauge"
+)
+
+var ErrUnknownCollector = errors.New("unknown collector")
+
+// Коллекторы, которые включены, если в конфигурации агента не указан их список.
+var DefaultCollectors = []string{"runtime", "process", "additional"}
+
+// Значение метрики, собранное коллектором.
+type Sample struct {
+	Name string
+	// TypeGauge или TypeCounter.
+	Type string
+	// Значение метрики типа gauge.
+	Value float64
+	// Приращение метрики типа counter с предыдущего сбора.
+	Delta int64
+}
+
+// GaugeSample() возвращает значение метрики типа gauge.
+func GaugeSample(name string, value float64) Sample {
+	return Sample{Name: name, Type: TypeGauge, Value: value}
+}
+
+// CounterSample() возвращает приращение метрики типа counter.
+func CounterSample(name string, delta int64) Sample {
+	return Sample{Name: name, Type: TypeCounter, Delta: delta}
+}
+
+// Коллектор собирает значения группы метрик. Метод Collect() вызывается
+// монитором раз в интервал опроса и не вызывается параллельно.
+type Collector interface {
+	Collect(ctx context.Context) ([]Sample, error)
+}
+
+// Функция, создающая коллектор по его настройкам из конфигурации агента.
+// Если настройки не заданы, options пустой.
+type Factory func(options json.RawMessage) (Collector, error)
+
+var registry = struct {
+	factories map[string]Factory
+	mu        sync.RWMutex
+}{factories: make(map[string]Factory)}
+
+// Register() регистрирует коллектор под именем name, по которому он включается
+// в конфигурации агента. Обычно вызывается из init() пакета с коллектором.
+func Register(name string, factory Factory) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	if factory == nil {
+		panic("metric: Register factory is nil")
+	}
+	if _, ok := registry.factories[name]; ok {
+		panic("metric: Register called twice for collector " + name)
+	}
+	registry.factories[name] = factory
+}
+
+// Registered() возвращает имена зарегистрированных коллекторов.
+func Registered() []string {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	names := make([]string, 0, len(registry.factories))
+	for name := range registry.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// NewCollectors() создаёт коллекторы с именами names и настройками options.
+func NewCollectors(names []string, options map[string]json.RawMessage) (map[string]Collector, error) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	collectors := make(map[string]Collector, len(names))
+	for _, name := range names {
+		factory, ok := registry.factories[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownCollector, name)
+		}
+
+		c, err := factory(options[name])
+		if err != nil {
+			return nil, fmt.Errorf("unable to create collector %s: %w", name, err)
+		}
+		collectors[name] = c
+	}
+
+	return collectors, nil
+}
+
+// decodeOptions разбирает настройки коллектора, если они заданы.
+func decodeOptions(options json.RawMessage, v any) error {
+	if len(options) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(options, v)
+}
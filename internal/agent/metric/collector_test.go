@@ -0,0 +1,121 @@
+package metric
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type staticCollector struct {
+	samples []Sample
+}
+
+func (c *staticCollector) Collect(context.Context) ([]Sample, error) {
+	return c.samples, nil
+}
+
+func init() {
+	Register("test_static", func(options json.RawMessage) (Collector, error) {
+		var opts struct {
+			Value float64 `json:"value"`
+		}
+		if err := decodeOptions(options, &opts); err != nil {
+			return nil, err
+		}
+		return &staticCollector{samples: []Sample{GaugeSample("Static", opts.Value)}}, nil
+	})
+}
+
+func TestNewCollectors(t *testing.T) {
+	assert.Subset(t, Registered(), append([]string{"test_static"}, DefaultCollectors...))
+
+	collectors, err := NewCollectors([]string{"test_static", "additional"}, map[string]json.RawMessage{
+		"test_static": json.RawMessage(`{"value": 4.5}`),
+	})
+	require.NoError(t, err)
+	require.Len(t, collectors, 2)
+
+	samples, err := collectors["test_static"].Collect(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []Sample{GaugeSample("Static", 4.5)}, samples)
+
+	_, err = NewCollectors([]string{"missing"}, nil)
+	assert.True(t, errors.Is(err, ErrUnknownCollector))
+
+	_, err = NewCollectors([]string{"test_static"}, map[string]json.RawMessage{"test_static": json.RawMessage(`[`)})
+	assert.Error(t, err)
+}
+
+func TestAccumulator(t *testing.T) {
+	acc := NewAccumulator()
+	acc.Add([]Sample{CounterSample("PollCount", 1), GaugeSample("RandomValue", 0.1)})
+	acc.Add([]Sample{CounterSample("PollCount", 1), GaugeSample("RandomValue", 0.2)})
+
+	m := acc.Drain()
+	assert.Equal(t, []Sample{CounterSample("PollCount", 2), GaugeSample("RandomValue", 0.2)}, m.Samples)
+
+	data, err := m.MarshalJSON()
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"id":"PollCount","type":"counter","delta":2},{"id":"RandomValue","type":"gauge","value":0.2}]`, string(data))
+
+	// после отправки счётчики считаются заново, gauge сохраняют значение
+	acc.Add([]Sample{CounterSample("PollCount", 1)})
+	assert.Equal(t, []Sample{CounterSample("PollCount", 1), GaugeSample("RandomValue", 0.2)}, acc.Drain().Samples)
+}
+
+func TestAccumulator_Expire(t *testing.T) {
+	acc := NewAccumulator()
+	acc.Add([]Sample{CounterSample("PollCount", 1), GaugeSample("RandomValue", 0.1)})
+	acc.Drain()
+
+	// метрики, не обновлявшиеся expireReports отправок, перестают передаваться
+	for i := 1; i < expireReports; i++ {
+		acc.Add([]Sample{GaugeSample("RandomValue", 0.2)})
+		acc.Drain()
+	}
+	acc.Add([]Sample{GaugeSample("RandomValue", 0.3)})
+	assert.Equal(t, []Sample{GaugeSample("RandomValue", 0.3)}, acc.Drain().Samples)
+
+	// освободившееся имя снова доступно внешним источникам
+	assert.Equal(t, 0, acc.AddLimited([]Sample{GaugeSample("Load", 1)}, 1))
+	for i := 0; i < expireReports; i++ {
+		acc.Drain()
+	}
+	assert.Empty(t, acc.Drain().Samples)
+	assert.Equal(t, 0, acc.AddLimited([]Sample{GaugeSample("Queue", 1)}, 1))
+}
+
+func TestAccumulator_AddLimited(t *testing.T) {
+	acc := NewAccumulator()
+
+	// метрики коллекторов не занимают лимит внешних источников
+	collected := make([]Sample, 0, 10)
+	for i := 0; i < 10; i++ {
+		collected = append(collected, GaugeSample(fmt.Sprintf("Series%d", i), float64(i)))
+	}
+	acc.Add(collected)
+
+	dropped := acc.AddLimited([]Sample{GaugeSample("Series0", 10), CounterSample("Requests", 1), GaugeSample("Load", 1), GaugeSample("Queue", 1)}, 2)
+	assert.Equal(t, 1, dropped)
+
+	samples := acc.Drain().Samples
+	assert.Len(t, samples, 12)
+	assert.Contains(t, samples, GaugeSample("Series0", 10))
+	assert.Contains(t, samples, CounterSample("Requests", 1))
+	assert.Contains(t, samples, GaugeSample("Load", 1))
+	assert.NotContains(t, samples, GaugeSample("Queue", 1))
+}
+
+func TestRuntime_Collect(t *testing.T) {
+	samples, err := NewRuntime().Collect(context.Background())
+	require.NoError(t, err)
+	require.NotEmpty(t, samples)
+	for _, s := range samples {
+		assert.Equal(t, TypeGauge, s.Type, s.Name)
+	}
+}
@@ -0,0 +1,44 @@
+package metric
+
+// Преобразует накопительные значения счётчиков, которые сообщает система,
+// в приращения с предыдущего сбора.
+type deltas struct {
+	prev map[string]uint64
+	seen map[string]struct{}
+}
+
+func newDeltas() *deltas {
+	return &deltas{
+		prev: make(map[string]uint64),
+		seen: make(map[string]struct{}),
+	}
+}
+
+// sample возвращает приращение счётчика name. При первом сборе приращение нулевое.
+// Если значение уменьшилось, например после перезапуска счётчика, приращением
+// считается само значение.
+func (d *deltas) sample(name string, value uint64) Sample {
+	prev, ok := d.prev[name]
+	d.prev[name] = value
+	d.seen[name] = struct{}{}
+
+	switch {
+	case !ok:
+		return CounterSample(name, 0)
+	case value < prev:
+		return CounterSample(name, int64(value))
+	default:
+		return CounterSample(name, int64(value-prev))
+	}
+}
+
+// prune забывает счётчики, которые не обновлялись с предыдущего вызова,
+// например счётчики отключённых устройств. Вызывается в конце сбора.
+func (d *deltas) prune() {
+	for name := range d.prev {
+		if _, ok := d.seen[name]; !ok {
+			delete(d.prev, name)
+		}
+	}
+	d.seen = make(map[string]struct{}, len(d.prev))
+}
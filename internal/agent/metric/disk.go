@@ -0,0 +1,149 @@
+package metric
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+func init() {
+	Register("disk", func(options json.RawMessage) (Collector, error) {
+		var opts DiskOptions
+		if err := decodeOptions(options, &opts); err != nil {
+			return nil, err
+		}
+		return NewDisk(opts)
+	})
+}
+
+// Настройки коллектора disk.
+type DiskOptions struct {
+	// Точки монтирования, для которых сообщается заполнение файловой системы.
+	Mounts Filter `json:"mounts"`
+	// Устройства, для которых сообщаются операции ввода-вывода, например sda или nvme0n1.
+	Devices Filter `json:"devices"`
+	// Учитывать виртуальные файловые системы, например tmpfs и proc.
+	AllFilesystems bool `json:"all_filesystems"`
+}
+
+// Заполнение файловых систем по точкам монтирования и операции ввода-вывода
+// по устройствам. Имена метрик дополняются точкой монтирования или устройством,
+// например DiskUsedPercent_var_lib или DiskReadBytes_sda. Точки монтирования
+// и устройства, имена которых совпадают после замены символов, пропускаются
+// с ошибкой, кроме первого.
+type Disk struct {
+	io   *deltas
+	opts DiskOptions
+	// заполнение файловой системы, в тестах подменяется
+	usageOf func(ctx context.Context, path string) (*disk.UsageStat, error)
+}
+
+func NewDisk(opts DiskOptions) (*Disk, error) {
+	if err := opts.Mounts.Validate(); err != nil {
+		return nil, err
+	}
+	if err := opts.Devices.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &Disk{io: newDeltas(), opts: opts, usageOf: disk.UsageWithContext}, nil
+}
+
+// Collect() возвращает заполнение файловых систем и inode в gauge
+// и приращения байтов и операций чтения и записи в counter.
+func (d *Disk) Collect(ctx context.Context) ([]Sample, error) {
+	usage, usageErr := d.usage(ctx)
+	io, ioErr := d.ioCounters(ctx)
+
+	return append(usage, io...), errors.Join(usageErr, ioErr)
+}
+
+func (d *Disk) usage(ctx context.Context) ([]Sample, error) {
+	partitions, err := disk.PartitionsWithContext(ctx, d.opts.AllFilesystems)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list partitions: %w", err)
+	}
+
+	var (
+		samples []Sample
+		errs    []error
+	)
+	seen := make(map[string]struct{}, len(partitions))
+	labels := make(labelSet, len(partitions))
+	for _, p := range partitions {
+		if _, ok := seen[p.Mountpoint]; ok || !d.opts.Mounts.Match(p.Mountpoint) {
+			continue
+		}
+		seen[p.Mountpoint] = struct{}{}
+
+		l, err := labels.add(p.Mountpoint)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		u, err := d.usageOf(ctx, p.Mountpoint)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("unable to get usage of %s: %w", p.Mountpoint, err))
+			continue
+		}
+
+		samples = append(samples,
+			GaugeSample("DiskTotal_"+l, float64(u.Total)),
+			GaugeSample("DiskFree_"+l, float64(u.Free)),
+			GaugeSample("DiskUsed_"+l, float64(u.Used)),
+			GaugeSample("DiskUsedPercent_"+l, u.UsedPercent),
+		)
+		// у некоторых файловых систем нет inode
+		if u.InodesTotal > 0 {
+			samples = append(samples,
+				GaugeSample("InodesTotal_"+l, float64(u.InodesTotal)),
+				GaugeSample("InodesUsed_"+l, float64(u.InodesUsed)),
+				GaugeSample("InodesUsedPercent_"+l, u.InodesUsedPercent),
+			)
+		}
+	}
+
+	return samples, errors.Join(errs...)
+}
+
+func (d *Disk) ioCounters(ctx context.Context) ([]Sample, error) {
+	counters, err := disk.IOCountersWithContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get io counters: %w", err)
+	}
+
+	names := make([]string, 0, len(counters))
+	for name := range counters {
+		if d.opts.Devices.Match(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var errs []error
+	samples := make([]Sample, 0, 4*len(names))
+	labels := make(labelSet, len(names))
+	for _, name := range names {
+		l, err := labels.add(name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		c := counters[name]
+		samples = append(samples,
+			d.io.sample("DiskReadBytes_"+l, c.ReadBytes),
+			d.io.sample("DiskWriteBytes_"+l, c.WriteBytes),
+			d.io.sample("DiskReadCount_"+l, c.ReadCount),
+			d.io.sample("DiskWriteCount_"+l, c.WriteCount),
+		)
+	}
+	d.io.prune()
+
+	return samples, errors.Join(errs...)
+}
@@ -0,0 +1,152 @@
+package metric
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDisk_Collect(t *testing.T) {
+	d, err := NewDisk(DiskOptions{Mounts: Filter{Include: []string{"/"}}})
+	require.NoError(t, err)
+
+	samples, err := d.Collect(context.Background())
+	if err != nil {
+		t.Skipf("disk statistics are not available: %v", err)
+	}
+
+	for _, s := range samples {
+		if strings.HasPrefix(s.Name, "Disk") && strings.Contains(s.Name, "Percent_") {
+			assert.Equal(t, "DiskUsedPercent_root", s.Name)
+		}
+	}
+}
+
+// fixtureUsage возвращает заполнение файловых систем из testdata/disk/usage.json.
+func fixtureUsage(t *testing.T) func(ctx context.Context, path string) (*disk.UsageStat, error) {
+	t.Helper()
+
+	data, err := os.ReadFile("testdata/disk/usage.json")
+	require.NoError(t, err)
+	var usage map[string]*disk.UsageStat
+	require.NoError(t, json.Unmarshal(data, &usage))
+
+	return func(_ context.Context, path string) (*disk.UsageStat, error) {
+		u, ok := usage[path]
+		if !ok {
+			return nil, os.ErrNotExist
+		}
+		return u, nil
+	}
+}
+
+func TestDisk_Usage(t *testing.T) {
+	t.Setenv("HOST_PROC", "testdata/disk/proc")
+
+	tests := []struct {
+		name        string
+		opts        DiskOptions
+		want        []string
+		wantMissing []string
+	}{
+		{
+			name:        "physical",
+			want:        []string{"DiskTotal_root", "DiskUsedPercent_var_lib", "InodesUsedPercent_srv", "DiskUsed_boot_efi"},
+			wantMissing: []string{"DiskTotal_run", "InodesTotal_boot_efi"},
+		},
+		{
+			name: "all_filesystems",
+			opts: DiskOptions{AllFilesystems: true},
+			want: []string{"DiskTotal_root", "DiskTotal_run", "InodesUsed_run"},
+		},
+		{
+			name:        "filter",
+			opts:        DiskOptions{Mounts: Filter{Include: []string{"/", "/var/lib", "/var_lib"}}},
+			want:        []string{"DiskTotal_root", "DiskTotal_var_lib"},
+			wantMissing: []string{"DiskTotal_srv", "DiskTotal_boot_efi"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := NewDisk(tt.opts)
+			require.NoError(t, err)
+			d.usageOf = fixtureUsage(t)
+
+			samples, err := d.usage(context.Background())
+			// /var_lib сообщался бы под тем же именем, что и /var/lib
+			assert.True(t, errors.Is(err, ErrLabelCollision), err)
+
+			values := make(map[string]Sample, len(samples))
+			for _, s := range samples {
+				assert.Equal(t, TypeGauge, s.Type, s.Name)
+				values[s.Name] = s
+			}
+			for _, name := range tt.want {
+				assert.Contains(t, values, name)
+			}
+			for _, name := range tt.wantMissing {
+				assert.NotContains(t, values, name)
+			}
+		})
+	}
+
+	d, err := NewDisk(DiskOptions{})
+	require.NoError(t, err)
+	d.usageOf = fixtureUsage(t)
+
+	samples, _ := d.usage(context.Background())
+	assert.Contains(t, samples, GaugeSample("DiskTotal_root", 1000))
+	assert.Contains(t, samples, GaugeSample("DiskFree_root", 250))
+	assert.Contains(t, samples, GaugeSample("DiskUsedPercent_root", 75))
+	assert.Contains(t, samples, GaugeSample("InodesTotal_root", 100))
+	assert.Contains(t, samples, GaugeSample("InodesUsedPercent_root", 40))
+	assert.Contains(t, samples, GaugeSample("DiskTotal_var_lib", 2000), "first mount keeps the name")
+}
+
+func TestDisk_IOCounters(t *testing.T) {
+	proc := t.TempDir()
+	write := func(stats string) {
+		require.NoError(t, os.WriteFile(filepath.Join(proc, "diskstats"), []byte(stats), 0o600))
+	}
+	data, err := os.ReadFile("testdata/disk/proc/diskstats")
+	require.NoError(t, err)
+	write(string(data))
+	t.Setenv("HOST_PROC", proc)
+
+	d, err := NewDisk(DiskOptions{Devices: Filter{Include: []string{"sd*", "nvme*"}, Exclude: []string{"sd?[0-9]"}}})
+	require.NoError(t, err)
+
+	samples, err := d.ioCounters(context.Background())
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []Sample{
+		CounterSample("DiskReadBytes_nvme0n1", 0),
+		CounterSample("DiskWriteBytes_nvme0n1", 0),
+		CounterSample("DiskReadCount_nvme0n1", 0),
+		CounterSample("DiskWriteCount_nvme0n1", 0),
+		CounterSample("DiskReadBytes_sda", 0),
+		CounterSample("DiskWriteBytes_sda", 0),
+		CounterSample("DiskReadCount_sda", 0),
+		CounterSample("DiskWriteCount_sda", 0),
+	}, samples, "first collect has no delta")
+
+	// приращения считаются от предыдущего сбора, сектор — 512 байт
+	write(`   8       0 sda 1010 10 20100 500 420 5 8040 300 0 700 800 0 0 0 0 0 0
+ 259       0 nvme0n1 200 0 4000 100 100 0 2000 50 0 150 150 0 0 0 0 0 0
+`)
+	samples, err = d.ioCounters(context.Background())
+	require.NoError(t, err)
+	assert.Contains(t, samples, CounterSample("DiskReadBytes_sda", 100*512))
+	assert.Contains(t, samples, CounterSample("DiskWriteBytes_sda", 40*512))
+	assert.Contains(t, samples, CounterSample("DiskReadCount_sda", 10))
+	assert.Contains(t, samples, CounterSample("DiskWriteCount_sda", 20))
+	assert.Contains(t, samples, CounterSample("DiskReadBytes_nvme0n1", 0))
+}
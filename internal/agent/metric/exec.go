@@ -0,0 +1,218 @@
+package metric
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+func init() {
+	Register("exec", func(options json.RawMessage) (Collector, error) {
+		var opts ExecOptions
+		if err := decodeOptions(options, &opts); err != nil {
+			return nil, err
+		}
+		return NewExec(opts)
+	})
+}
+
+// Форматы вывода команд коллектора exec.
+const (
+	FormatSimple     = "simple"
+	FormatPrometheus = "prometheus"
+)
+
+// Время выполнения команды по умолчанию в секундах.
+const defaultExecTimeout = 10
+
+var (
+	ErrInvalidCommand = errors.New("invalid command")
+	ErrInvalidOutput  = errors.New("invalid command output")
+)
+
+// Команда, вывод которой содержит значения метрик.
+type Command struct {
+	// Имя команды для сообщений об ошибках.
+	Name string `json:"name"`
+	// Исполняемый файл и аргументы. Команда запускается без оболочки.
+	Command []string `json:"command"`
+	// FormatSimple (по умолчанию) или FormatPrometheus.
+	Format string `json:"format"`
+	// Интервал запуска в секундах. По умолчанию команда запускается при каждом опросе.
+	Interval int `json:"interval"`
+	// Время выполнения в секундах, после которого команда завершается, но не больше
+	// интервала опроса агента. По умолчанию 10 секунд.
+	Timeout int `json:"timeout"`
+	// Префикс имён метрик.
+	Prefix string `json:"prefix"`
+}
+
+// Настройки коллектора exec.
+type ExecOptions struct {
+	Commands []Command `json:"commands"`
+}
+
+// Значения метрик, которые выводят пользовательские команды и скрипты.
+// В формате FormatSimple каждая строка вывода имеет вид «имя тип значение»,
+// где тип — gauge или counter, а значение counter — приращение с предыдущего
+// запуска. Пустые строки и строки, начинающиеся с #, пропускаются.
+// В формате FormatPrometheus значения счётчиков накопительные и преобразуются в приращения.
+type Exec struct {
+	commands []*execCommand
+}
+
+type execCommand struct {
+	Command
+	counters *deltas
+	lastRun  time.Time
+}
+
+func NewExec(opts ExecOptions) (*Exec, error) {
+	e := &Exec{commands: make([]*execCommand, 0, len(opts.Commands))}
+	for i, c := range opts.Commands {
+		if len(c.Command) == 0 || c.Command[0] == "" {
+			return nil, fmt.Errorf("%w: command %d is empty", ErrInvalidCommand, i)
+		}
+		if c.Name == "" {
+			c.Name = c.Command[0]
+		}
+		switch c.Format {
+		case "":
+			c.Format = FormatSimple
+		case FormatSimple, FormatPrometheus:
+		default:
+			return nil, fmt.Errorf("%w: %s: unknown format %q", ErrInvalidCommand, c.Name, c.Format)
+		}
+		if c.Interval < 0 || c.Timeout < 0 {
+			return nil, fmt.Errorf("%w: %s: negative interval or timeout", ErrInvalidCommand, c.Name)
+		}
+		if c.Timeout == 0 {
+			c.Timeout = defaultExecTimeout
+		}
+		e.commands = append(e.commands, &execCommand{Command: c, counters: newDeltas()})
+	}
+
+	return e, nil
+}
+
+// Collect() запускает команды, интервал которых истёк, параллельно и возвращает
+// значения из их вывода. Ошибка одной команды не мешает остальным.
+func (e *Exec) Collect(ctx context.Context) ([]Sample, error) {
+	now := time.Now()
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		samples []Sample
+		errs    []error
+	)
+	for _, c := range e.commands {
+		if !c.lastRun.IsZero() && now.Sub(c.lastRun) < time.Duration(c.Interval)*time.Second {
+			continue
+		}
+		c.lastRun = now
+
+		wg.Add(1)
+		go func(c *execCommand) {
+			defer wg.Done()
+
+			s, err := c.run(ctx)
+			mu.Lock()
+			defer mu.Unlock()
+			samples = append(samples, s...)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("command %s: %w", c.Name, err))
+			}
+		}(c)
+	}
+	wg.Wait()
+
+	return samples, errors.Join(errs...)
+}
+
+func (c *execCommand) run(ctx context.Context) ([]Sample, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(c.Timeout)*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, c.Command.Command[0], c.Command.Command[1:]...)
+	// не ждать процессы, которые унаследовали вывод завершённой команды
+	cmd.WaitDelay = time.Second
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("terminated: %w", ctx.Err())
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+
+	if c.Format == FormatPrometheus {
+		parsed, err := parsePromText(bytes.NewReader(out))
+		return promSamples(parsed, c.counters, c.Prefix), err
+	}
+
+	return parseSimple(bytes.NewReader(out), c.Prefix)
+}
+
+// parseSimple разбирает строки вида «имя тип значение». Возвращает разобранные
+// значения вместе с ошибками некорректных строк.
+func parseSimple(r io.Reader, prefix string) ([]Sample, error) {
+	var (
+		samples []Sample
+		errs    []error
+	)
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			errs = append(errs, fmt.Errorf("%w: line %d: expected name, type and value", ErrInvalidOutput, n))
+			continue
+		}
+
+		name := prefix + fields[0]
+		switch fields[1] {
+		case TypeGauge:
+			v, err := strconv.ParseFloat(fields[2], 64)
+			if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+				errs = append(errs, fmt.Errorf("%w: line %d: invalid gauge value %q", ErrInvalidOutput, n, fields[2]))
+				continue
+			}
+			samples = append(samples, GaugeSample(name, v))
+		case TypeCounter:
+			v, err := strconv.ParseInt(fields[2], 10, 64)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%w: line %d: invalid counter value %q", ErrInvalidOutput, n, fields[2]))
+				continue
+			}
+			samples = append(samples, CounterSample(name, v))
+		default:
+			errs = append(errs, fmt.Errorf("%w: line %d: unknown type %q", ErrInvalidOutput, n, fields[1]))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return samples, errors.Join(errs...)
+}
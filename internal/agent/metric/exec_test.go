@@ -0,0 +1,134 @@
+package metric
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePromText(t *testing.T) {
+	input := `# HELP http_requests_total Requests.
+# TYPE http_requests_total counter
+http_requests_total{method="get",code="200"} 1027 1395066363000
+http_requests_total{code="400",method="post"} 3
+# TYPE temperature gauge
+temperature{room="a \"big\" one"} 21.5
+# TYPE latency histogram
+latency_bucket{le="0.5"} 10
+latency_bucket{le="+Inf"} 12
+latency_sum 4.7
+latency_count 12
+untyped_value NaN
+broken{method="get"
+`
+	samples, err := parsePromText(strings.NewReader(input))
+	assert.True(t, errors.Is(err, ErrInvalidPromText))
+	assert.Equal(t, []promSample{
+		{name: "http_requests_total_code_200_method_get", kind: TypeCounter, value: 1027},
+		{name: "http_requests_total_code_400_method_post", kind: TypeCounter, value: 3},
+		{name: "temperature_room_a__big__one", kind: TypeGauge, value: 21.5},
+		{name: "latency_bucket_le_0_5", kind: TypeCounter, value: 10},
+		{name: "latency_bucket_le__Inf", kind: TypeCounter, value: 12},
+		{name: "latency_sum", kind: TypeGauge, value: 4.7},
+		{name: "latency_count", kind: TypeCounter, value: 12},
+	}, samples)
+}
+
+func TestParsePromText_Labels(t *testing.T) {
+	input := `mount{path=""} 1
+mount{path="/"} 2
+mount{path="root"} 3
+mount{path="/var/lib"} 4
+mount{path="/var_lib"} 5
+mount{path="/var/lib"} 6
+`
+	samples, err := parsePromText(strings.NewReader(input))
+	assert.True(t, errors.Is(err, ErrInvalidPromText))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 5: duplicate series mount_path__var_lib")
+	assert.Contains(t, err.Error(), "line 6: duplicate series mount_path__var_lib")
+	// пустое значение и значения, отличающиеся символами по краям, не совпадают
+	assert.Equal(t, []promSample{
+		{name: "mount_path_", kind: TypeGauge, value: 1},
+		{name: "mount_path__", kind: TypeGauge, value: 2},
+		{name: "mount_path_root", kind: TypeGauge, value: 3},
+		{name: "mount_path__var_lib", kind: TypeGauge, value: 4},
+	}, samples)
+}
+
+func TestExec_Collect(t *testing.T) {
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+	prom := filepath.Join(t.TempDir(), "metrics.prom")
+	require.NoError(t, os.WriteFile(prom, []byte("# TYPE jobs_total counter\njobs_total 5\nqueue 3\n"), 0o600))
+
+	e, err := NewExec(ExecOptions{Commands: []Command{
+		{Name: "orders", Command: []string{"sh", "-c", `printf 'Orders counter 2\n# comment\n\nRevenue gauge 10.5\nbad line\n'`}},
+		{Command: []string{"cat", prom}, Format: FormatPrometheus, Prefix: "app_"},
+		{Name: "hourly", Command: []string{"sh", "-c", "echo Hourly gauge 1"}, Interval: 3600},
+	}})
+	require.NoError(t, err)
+
+	samples, err := e.Collect(context.Background())
+	assert.True(t, errors.Is(err, ErrInvalidOutput), err)
+	assert.ElementsMatch(t, []Sample{
+		CounterSample("Orders", 2),
+		GaugeSample("Revenue", 10.5),
+		CounterSample("app_jobs_total", 0),
+		GaugeSample("app_queue", 3),
+		GaugeSample("Hourly", 1),
+	}, samples)
+
+	// накопительные счётчики Prometheus сообщаются приращениями,
+	// команда с неистёкшим интервалом не запускается
+	require.NoError(t, os.WriteFile(prom, []byte("# TYPE jobs_total counter\njobs_total 9\nqueue 1\n"), 0o600))
+	samples, _ = e.Collect(context.Background())
+	assert.Contains(t, samples, CounterSample("app_jobs_total", 4))
+	assert.Contains(t, samples, GaugeSample("app_queue", 1))
+	assert.NotContains(t, samples, GaugeSample("Hourly", 1))
+}
+
+func TestExec_Timeout(t *testing.T) {
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+	e, err := NewExec(ExecOptions{Commands: []Command{
+		{Name: "slow", Command: []string{"sh", "-c", "echo Slow gauge 1; sleep 30"}, Timeout: 1},
+		{Name: "failing", Command: []string{"sh", "-c", "echo oops >&2; exit 3"}},
+	}})
+	require.NoError(t, err)
+
+	start := time.Now()
+	samples, err := e.Collect(context.Background())
+	assert.Less(t, time.Since(start), 10*time.Second)
+	assert.Empty(t, samples)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), err)
+	assert.Contains(t, err.Error(), "oops")
+}
+
+func TestNewExec_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		command Command
+	}{
+		{name: "empty", command: Command{Name: "empty"}},
+		{name: "unknown_format", command: Command{Command: []string{"true"}, Format: "xml"}},
+		{name: "negative_timeout", command: Command{Command: []string{"true"}, Timeout: -1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewExec(ExecOptions{Commands: []Command{tt.command}})
+			assert.True(t, errors.Is(err, ErrInvalidCommand), err)
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package metric
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Фильтр имён по шаблонам path.Match. Имя проходит фильтр, если подходит
+// под один из шаблонов Include или Include пуст, и не подходит ни под один
+// из шаблонов Exclude.
+type Filter struct {
+	Include []string `json:"include"`
+	Exclude []string `json:"exclude"`
+}
+
+// Validate() проверяет синтаксис шаблонов.
+func (f Filter) Validate() error {
+	for _, patterns := range [][]string{f.Include, f.Exclude} {
+		for _, p := range patterns {
+			if _, err := path.Match(p, ""); err != nil {
+				return fmt.Errorf("invalid pattern %q: %w", p, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Match() сообщает, проходит ли имя name фильтр.
+func (f Filter) Match(name string) bool {
+	if len(f.Include) > 0 && !matchAny(f.Include, name) {
+		return false
+	}
+
+	return !matchAny(f.Exclude, name)
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+var ErrLabelCollision = errors.New("metric label collision")
+
+// labelSet запоминает, из каких значений получены метки, чтобы разные точки
+// монтирования или устройства не сообщались под одним именем метрики.
+type labelSet map[string]string
+
+// add возвращает метку значения или ErrLabelCollision, если такая же метка
+// уже получена из другого значения.
+func (ls labelSet) add(value string) (string, error) {
+	l := label(value)
+	if prev, ok := ls[l]; ok && prev != value {
+		return "", fmt.Errorf("%w: %s and %s are both reported as %s", ErrLabelCollision, prev, value, l)
+	}
+	ls[l] = value
+
+	return l, nil
+}
+
+// label преобразует имя устройства, точки монтирования и т. п. в часть имени метрики:
+// символы, кроме латинских букв, цифр и подчёркивания, заменяются подчёркиванием.
+// Корень «/» обозначается как root.
+func label(name string) string {
+	name = strings.Trim(name, "/")
+	if name == "" {
+		return "root"
+	}
+
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, name)
+}
@@ -0,0 +1,49 @@
+package metric
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilter_Match(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter
+		value  string
+		want   bool
+	}{
+		{name: "empty", filter: Filter{}, value: "/", want: true},
+		{name: "included", filter: Filter{Include: []string{"/var/*"}}, value: "/var/lib", want: true},
+		{name: "not_included", filter: Filter{Include: []string{"/var/*"}}, value: "/home", want: false},
+		{name: "excluded", filter: Filter{Exclude: []string{"loop*"}}, value: "loop0", want: false},
+		{name: "exclude_wins", filter: Filter{Include: []string{"sd*"}, Exclude: []string{"sdb"}}, value: "sdb", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.filter.Match(tt.value))
+		})
+	}
+
+	assert.Error(t, Filter{Include: []string{"["}}.Validate())
+}
+
+func TestLabel(t *testing.T) {
+	assert.Equal(t, "root", label("/"))
+	assert.Equal(t, "var_lib_docker", label("/var/lib/docker"))
+	assert.Equal(t, "nvme0n1", label("nvme0n1"))
+	assert.Equal(t, "dm_0", label("dm-0"))
+}
+
+func TestDeltas(t *testing.T) {
+	d := newDeltas()
+	assert.Equal(t, CounterSample("Bytes", 0), d.sample("Bytes", 100))
+	assert.Equal(t, CounterSample("Bytes", 50), d.sample("Bytes", 150))
+	assert.Equal(t, CounterSample("Bytes", 20), d.sample("Bytes", 20), "counter reset")
+
+	// счётчик, пропавший на одном сборе, начинается заново
+	d.prune()
+	d.prune()
+	assert.Equal(t, CounterSample("Bytes", 0), d.sample("Bytes", 500))
+}
@@ -0,0 +1,134 @@
+package metric
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/shirou/gopsutil/v3/host"
+	"github.com/shirou/gopsutil/v3/load"
+	"github.com/shirou/gopsutil/v3/mem"
+)
+
+func init() {
+	Register("host", func(json.RawMessage) (Collector, error) {
+		return NewHost(), nil
+	})
+}
+
+// Общие показатели системы: средняя загрузка, время работы, количество процессов,
+// переключения контекста, прерывания и использование подкачки.
+type Host struct {
+	counters *deltas
+}
+
+func NewHost() *Host {
+	return &Host{counters: newDeltas()}
+}
+
+// Collect() возвращает среднюю загрузку за 1, 5 и 15 минут, время работы в секундах,
+// количество процессов и заполнение подкачки в gauge, приращения переключений
+// контекста, прерываний, созданных процессов и обмена с подкачкой в counter.
+func (h *Host) Collect(ctx context.Context) ([]Sample, error) {
+	var (
+		samples []Sample
+		errs    []error
+	)
+
+	if avg, err := load.AvgWithContext(ctx); err == nil {
+		samples = append(samples,
+			GaugeSample("LoadAverage1", avg.Load1),
+			GaugeSample("LoadAverage5", avg.Load5),
+			GaugeSample("LoadAverage15", avg.Load15),
+		)
+	} else {
+		errs = append(errs, fmt.Errorf("unable to get load average: %w", err))
+	}
+
+	if uptime, err := host.UptimeWithContext(ctx); err == nil {
+		samples = append(samples, GaugeSample("Uptime", float64(uptime)))
+	} else {
+		errs = append(errs, fmt.Errorf("unable to get uptime: %w", err))
+	}
+
+	if misc, err := load.MiscWithContext(ctx); err == nil {
+		samples = append(samples,
+			GaugeSample("ProcsTotal", float64(misc.ProcsTotal)),
+			GaugeSample("ProcsRunning", float64(misc.ProcsRunning)),
+			GaugeSample("ProcsBlocked", float64(misc.ProcsBlocked)),
+			h.counters.sample("ProcsCreated", uint64(misc.ProcsCreated)),
+			h.counters.sample("ContextSwitches", uint64(misc.Ctxt)),
+		)
+	} else {
+		errs = append(errs, fmt.Errorf("unable to get process statistics: %w", err))
+	}
+
+	if intr, err := readInterrupts(); err == nil {
+		samples = append(samples, h.counters.sample("Interrupts", intr))
+	} else {
+		errs = append(errs, fmt.Errorf("unable to get interrupts: %w", err))
+	}
+
+	if swap, err := mem.SwapMemoryWithContext(ctx); err == nil {
+		samples = append(samples,
+			GaugeSample("SwapTotal", float64(swap.Total)),
+			GaugeSample("SwapUsed", float64(swap.Used)),
+			GaugeSample("SwapFree", float64(swap.Free)),
+			GaugeSample("SwapUsedPercent", swap.UsedPercent),
+			h.counters.sample("SwapIn", swap.Sin),
+			h.counters.sample("SwapOut", swap.Sout),
+		)
+	} else {
+		errs = append(errs, fmt.Errorf("unable to get swap usage: %w", err))
+	}
+
+	return samples, errors.Join(errs...)
+}
+
+// hostProc возвращает путь к файлу procfs. Как и в gopsutil, каталог procfs
+// можно переопределить переменной окружения HOST_PROC, например при запуске
+// агента в контейнере с примонтированным /proc хоста.
+func hostProc(name string) string {
+	root := os.Getenv("HOST_PROC")
+	if root == "" {
+		root = "/proc"
+	}
+
+	return filepath.Join(root, name)
+}
+
+func readInterrupts() (uint64, error) {
+	f, err := os.Open(hostProc("stat"))
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	return parseInterrupts(f)
+}
+
+// parseInterrupts возвращает общее количество прерываний из строки intr файла /proc/stat.
+func parseInterrupts(r io.Reader) (uint64, error) {
+	scanner := bufio.NewScanner(r)
+	// строка intr содержит счётчики всех прерываний и бывает длинной
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "intr" {
+			continue
+		}
+		return strconv.ParseUint(fields[1], 10, 64)
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+
+	return 0, errors.New("intr line not found")
+}
@@ -0,0 +1,49 @@
+package metric
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseInterrupts(t *testing.T) {
+	f, err := os.Open("testdata/host/stat")
+	require.NoError(t, err)
+	defer f.Close()
+
+	intr, err := parseInterrupts(f)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1462898), intr)
+
+	_, err = parseInterrupts(strings.NewReader("cpu 1 2 3\n"))
+	assert.Error(t, err)
+}
+
+func TestHost_Collect(t *testing.T) {
+	h := NewHost()
+
+	samples, err := h.Collect(context.Background())
+	if err != nil {
+		t.Skipf("host statistics are not available: %v", err)
+	}
+
+	values := make(map[string]Sample, len(samples))
+	for _, s := range samples {
+		values[s.Name] = s
+	}
+	for _, name := range []string{"LoadAverage1", "LoadAverage15", "Uptime", "ProcsTotal", "SwapTotal"} {
+		assert.Equal(t, TypeGauge, values[name].Type, name)
+	}
+	assert.Positive(t, values["Uptime"].Value)
+	assert.Equal(t, CounterSample("ContextSwitches", 0), values["ContextSwitches"], "first collect has no delta")
+
+	samples, err = h.Collect(context.Background())
+	require.NoError(t, err)
+	for _, s := range samples {
+		assert.GreaterOrEqual(t, s.Delta, int64(0), s.Name)
+	}
+}
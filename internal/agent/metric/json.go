@@ -2,16 +2,14 @@ package metric
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
 
 	"github.com/Xacor/go-metrics/proto"
 )
 
-const (
-	typeCounter = "counter"
-	typeGauge   = "gauge"
-)
+var ErrInvalidSample = errors.New("invalid metric")
 
 type jsonMetric struct {
 	Delta *int64   `json:"delta,omitempty"`
@@ -20,64 +18,31 @@ type jsonMetric struct {
 	MType string   `json:"type"`
 }
 
-func readStruct(st interface{}) ([]jsonMetric, error) {
+// readStruct возвращает значения полей структуры st: поля типа Gauge и срезы Gauge
+// становятся метриками gauge, поля типа Counter — метриками counter.
+func readStruct(st interface{}) ([]Sample, error) {
 	val := reflect.ValueOf(st)
 	if val.Kind() == reflect.Pointer {
 		val = val.Elem()
 	}
-	result := make([]jsonMetric, 0)
+	result := make([]Sample, 0, val.NumField())
 	for i := 0; i < val.NumField(); i++ {
 		f := val.Field(i)
+		name := val.Type().Field(i).Name
 
 		switch f.Kind() {
-		case reflect.Struct:
-			r, err := readStruct(f)
-			if err != nil {
-				return nil, err
-			}
-			result = append(result, r...)
-		case reflect.Pointer:
-			if f.Elem().CanInterface() {
-				r, err := readStruct(f.Elem().Interface())
-				if err != nil {
-					return nil, err
-				}
-				result = append(result, r...)
-			}
 		case reflect.Slice:
 			for j := 0; j < f.Len(); j++ {
-				v := f.Index(j).Float()
-				result = append(result, jsonMetric{
-					ID:    fmt.Sprintf("%v%v", val.Type().Field(i).Name, j+1),
-					MType: typeGauge,
-					Value: &v,
-				})
+				result = append(result, GaugeSample(fmt.Sprintf("%v%v", name, j+1), f.Index(j).Float()))
 			}
 		case reflect.Uint64:
-			v := int64(f.Uint())
-			result = append(result, jsonMetric{
-				ID:    val.Type().Field(i).Name,
-				MType: typeCounter,
-				Delta: &v,
-			})
+			result = append(result, CounterSample(name, int64(f.Uint())))
 		case reflect.Int64:
-			v := f.Int()
-			result = append(result, jsonMetric{
-				ID:    val.Type().Field(i).Name,
-				MType: typeCounter,
-				Delta: &v,
-			})
+			result = append(result, CounterSample(name, f.Int()))
 		case reflect.Float64:
-			v := f.Float()
-			result = append(result, jsonMetric{
-				ID:    val.Type().Field(i).Name,
-				MType: typeGauge,
-				Value: &v,
-			})
-		case reflect.Chan:
-			continue
+			result = append(result, GaugeSample(name, f.Float()))
 		default:
-			return nil, fmt.Errorf("ivalid metric Kind: %v %v", f.Kind(), val.Type().Field(i).Name)
+			return nil, fmt.Errorf("ivalid metric Kind: %v %v", f.Kind(), name)
 		}
 	}
 
@@ -85,9 +50,17 @@ func readStruct(st interface{}) ([]jsonMetric, error) {
 }
 
 func (m *Metrics) MarshalJSON() ([]byte, error) {
-	metrics, err := readStruct(m)
-	if err != nil {
-		return nil, err
+	metrics := make([]jsonMetric, 0, len(m.Samples))
+	for i := range m.Samples {
+		s := m.Samples[i]
+		jm := jsonMetric{ID: s.Name, MType: s.Type}
+		switch s.Type {
+		case TypeCounter:
+			jm.Delta = &s.Delta
+		case TypeGauge:
+			jm.Value = &s.Value
+		}
+		metrics = append(metrics, jm)
 	}
 
 	json, err := json.Marshal(metrics)
@@ -97,22 +70,49 @@ func (m *Metrics) MarshalJSON() ([]byte, error) {
 	return json, nil
 }
 
-func (m *Metrics) ToProto() ([]*proto.Metric, error) {
-	metrics, err := readStruct(m)
-	if err != nil {
-		return nil, err
+// UnmarshalJSON() разбирает список метрик в формате, который принимает сервер.
+func (m *Metrics) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &m.Samples)
+}
+
+// UnmarshalJSON() разбирает метрику в формате, который принимает сервер:
+// у метрики типа gauge должно быть значение value, у counter — приращение delta.
+func (s *Sample) UnmarshalJSON(data []byte) error {
+	var jm jsonMetric
+	if err := json.Unmarshal(data, &jm); err != nil {
+		return err
+	}
+	if jm.ID == "" {
+		return fmt.Errorf("%w: empty id", ErrInvalidSample)
 	}
 
-	res := make([]*proto.Metric, 0, len(metrics))
-	for i := range metrics {
-		res = append(res, &proto.Metric{
-			Id:    metrics[i].ID,
-			Type:  metrics[i].MType,
-			Delta: *metrics[i].Delta,
-			Value: *metrics[i].Value,
-		})
+	switch {
+	case jm.MType == TypeCounter && jm.Delta != nil:
+		*s = CounterSample(jm.ID, *jm.Delta)
+	case jm.MType == TypeGauge && jm.Value != nil:
+		*s = GaugeSample(jm.ID, *jm.Value)
+	default:
+		return fmt.Errorf("%w: %s: type %q without its value", ErrInvalidSample, jm.ID, jm.MType)
 	}
 
-	return res, nil
+	return nil
+}
+
+func (m *Metrics) ToProto() ([]*proto.Metric, error) {
+	res := make([]*proto.Metric, 0, len(m.Samples))
+	for _, s := range m.Samples {
+		pm := &proto.Metric{
+			Id:   s.Name,
+			Type: s.Type,
+		}
+		switch s.Type {
+		case TypeCounter:
+			pm.Delta = s.Delta
+		case TypeGauge:
+			pm.Value = s.Value
+		}
+		res = append(res, pm)
+	}
 
+	return res, nil
 }
@@ -1,64 +1,74 @@
 package metric
 
 import (
+	"context"
+	"sync"
 	"time"
 
 	"github.com/Xacor/go-metrics/internal/logger"
 	"go.uber.org/zap"
 )
 
-// Монитор содержит канал, по которому отрпавляет значения метрик раз в d сек
+// Монитор раз в d сек опрашивает коллекторы и накапливает собранные значения
 type Monitor struct {
-	C       chan UpdateResult
-	t       *time.Ticker
-	metrics *Metrics
+	t          *time.Ticker
+	acc        *Accumulator
+	collectors map[string]Collector
+	done       chan struct{}
+	interval   time.Duration
 }
 
-type UpdateResult struct {
-	Metrics Metrics
-	Err     error
-}
-
-func NewMonitor(d time.Duration) (*Monitor, error) {
-	m, err := NewMetrics()
-	if err != nil {
-		return nil, err
-	}
-	c := make(chan UpdateResult, 1)
-	t := time.NewTicker(d)
+func NewMonitor(d time.Duration, acc *Accumulator, collectors map[string]Collector) *Monitor {
 	monitor := &Monitor{
-		C:       c,
-		t:       t,
-		metrics: m,
+		t:          time.NewTicker(d),
+		acc:        acc,
+		collectors: collectors,
+		done:       make(chan struct{}),
+		interval:   d,
 	}
 	monitor.run()
 
-	return monitor, nil
+	return monitor
 }
 
 func (m *Monitor) Close() {
-	close(m.C)
-}
-
-// вспомогательная функция, чтобы слать метрки в канал без блокировки
-func sendResult(c chan UpdateResult, val UpdateResult) {
-	select {
-	case c <- val:
-	default:
-	}
+	m.t.Stop()
+	close(m.done)
 }
 
 func (m *Monitor) run() {
 	logger.Get().Debug("[monitor] started")
 	go func() {
-		for t := range m.t.C {
-			logger.Get().Debug("[monitor]", zap.Time("tick", t))
-			err := update(m.metrics)
-			result := UpdateResult{
-				Metrics: *m.metrics,
-				Err:     err,
+		for {
+			select {
+			case <-m.done:
+				return
+			case t := <-m.t.C:
+				logger.Get().Debug("[monitor]", zap.Time("tick", t))
+				m.collect()
 			}
-			sendResult(m.C, result)
 		}
 	}()
 }
+
+// collect опрашивает коллекторы параллельно. Ошибка одного коллектора
+// не мешает учесть значения остальных.
+func (m *Monitor) collect() {
+	ctx, cancel := context.WithTimeout(context.Background(), m.interval)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for name, c := range m.collectors {
+		wg.Add(1)
+		go func(name string, c Collector) {
+			defer wg.Done()
+
+			samples, err := c.Collect(ctx)
+			if err != nil {
+				logger.Get().Error("[monitor] collector failed", zap.String("collector", name), zap.Error(err))
+			}
+			m.acc.Add(samples)
+		}(name, c)
+	}
+	wg.Wait()
+}
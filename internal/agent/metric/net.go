@@ -0,0 +1,133 @@
+package metric
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/shirou/gopsutil/v3/net"
+)
+
+func init() {
+	Register("net", func(options json.RawMessage) (Collector, error) {
+		var opts NetOptions
+		if err := decodeOptions(options, &opts); err != nil {
+			return nil, err
+		}
+		return NewNet(opts)
+	})
+	Register("connections", func(options json.RawMessage) (Collector, error) {
+		var opts ConnectionsOptions
+		if err := decodeOptions(options, &opts); err != nil {
+			return nil, err
+		}
+		return NewConnections(opts)
+	})
+}
+
+// Настройки коллектора net.
+type NetOptions struct {
+	// Сетевые интерфейсы, для которых сообщаются счётчики, например eth0.
+	Interfaces Filter `json:"interfaces"`
+}
+
+// Счётчики сетевых интерфейсов. Имена метрик дополняются именем интерфейса,
+// например NetBytesRecv_eth0.
+type Net struct {
+	counters *deltas
+	opts     NetOptions
+}
+
+func NewNet(opts NetOptions) (*Net, error) {
+	if err := opts.Interfaces.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &Net{counters: newDeltas(), opts: opts}, nil
+}
+
+// Collect() возвращает приращения переданных и принятых байтов и пакетов,
+// ошибок и отброшенных пакетов в counter.
+func (n *Net) Collect(ctx context.Context) ([]Sample, error) {
+	stats, err := net.IOCountersWithContext(ctx, true)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get interface counters: %w", err)
+	}
+
+	samples := make([]Sample, 0, 8*len(stats))
+	for _, s := range stats {
+		if !n.opts.Interfaces.Match(s.Name) {
+			continue
+		}
+
+		l := label(s.Name)
+		samples = append(samples,
+			n.counters.sample("NetBytesSent_"+l, s.BytesSent),
+			n.counters.sample("NetBytesRecv_"+l, s.BytesRecv),
+			n.counters.sample("NetPacketsSent_"+l, s.PacketsSent),
+			n.counters.sample("NetPacketsRecv_"+l, s.PacketsRecv),
+			n.counters.sample("NetErrIn_"+l, s.Errin),
+			n.counters.sample("NetErrOut_"+l, s.Errout),
+			n.counters.sample("NetDropIn_"+l, s.Dropin),
+			n.counters.sample("NetDropOut_"+l, s.Dropout),
+		)
+	}
+	n.counters.prune()
+
+	return samples, nil
+}
+
+// Состояния TCP-соединений, количество которых сообщается всегда,
+// чтобы значение опускалось до нуля, когда соединений в состоянии не осталось.
+var tcpStates = []string{
+	"ESTABLISHED", "SYN_SENT", "SYN_RECV", "FIN_WAIT1", "FIN_WAIT2", "TIME_WAIT",
+	"CLOSE", "CLOSE_WAIT", "LAST_ACK", "LISTEN", "CLOSING",
+}
+
+// Настройки коллектора connections.
+type ConnectionsOptions struct {
+	// Учитываемые соединения: tcp, tcp4 или tcp6. По умолчанию tcp.
+	Kind string `json:"kind"`
+}
+
+// Количество TCP-соединений по состояниям, например TCPConnections_ESTABLISHED.
+type Connections struct {
+	kind string
+}
+
+func NewConnections(opts ConnectionsOptions) (*Connections, error) {
+	switch opts.Kind {
+	case "":
+		opts.Kind = "tcp"
+	case "tcp", "tcp4", "tcp6":
+	default:
+		return nil, fmt.Errorf("unsupported connection kind %q", opts.Kind)
+	}
+
+	return &Connections{kind: opts.Kind}, nil
+}
+
+// Collect() возвращает количество соединений в каждом состоянии в gauge.
+func (c *Connections) Collect(ctx context.Context) ([]Sample, error) {
+	conns, err := net.ConnectionsWithoutUidsWithContext(ctx, c.kind)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list connections: %w", err)
+	}
+
+	counts := make(map[string]int, len(tcpStates))
+	for _, state := range tcpStates {
+		counts[state] = 0
+	}
+	for _, conn := range conns {
+		if conn.Status != "" && conn.Status != "NONE" {
+			counts[conn.Status]++
+		}
+	}
+
+	samples := make([]Sample, 0, len(counts))
+	for state, count := range counts {
+		samples = append(samples, GaugeSample("TCPConnections_"+label(state), float64(count)))
+	}
+
+	return samples, nil
+}
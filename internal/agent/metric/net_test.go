@@ -0,0 +1,62 @@
+package metric
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNet_Collect(t *testing.T) {
+	n, err := NewNet(NetOptions{Interfaces: Filter{Include: []string{"lo"}}})
+	require.NoError(t, err)
+
+	first, err := n.Collect(context.Background())
+	require.NoError(t, err)
+	if len(first) == 0 {
+		t.Skip("loopback interface is not available")
+	}
+	for _, s := range first {
+		assert.Equal(t, int64(0), s.Delta, "first collect has no delta: %s", s.Name)
+	}
+
+	conn, err := net.Dial("udp", "127.0.0.1:9")
+	require.NoError(t, err)
+	_, _ = conn.Write([]byte("ping"))
+	conn.Close()
+
+	second, err := n.Collect(context.Background())
+	require.NoError(t, err)
+
+	var sent int64
+	for _, s := range second {
+		if s.Name == "NetPacketsSent_lo" {
+			sent = s.Delta
+		}
+	}
+	assert.Positive(t, sent)
+}
+
+func TestConnections_Collect(t *testing.T) {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	c, err := NewConnections(ConnectionsOptions{})
+	require.NoError(t, err)
+
+	samples, err := c.Collect(context.Background())
+	require.NoError(t, err)
+
+	states := make(map[string]float64, len(samples))
+	for _, s := range samples {
+		states[s.Name] = s.Value
+	}
+	assert.GreaterOrEqual(t, states["TCPConnections_LISTEN"], 1.0)
+	assert.Contains(t, states, "TCPConnections_TIME_WAIT")
+
+	_, err = NewConnections(ConnectionsOptions{Kind: "udp"})
+	assert.Error(t, err)
+}
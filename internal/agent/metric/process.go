@@ -1,6 +1,9 @@
 package metric
 
 import (
+	"context"
+	"encoding/json"
+
 	"github.com/shirou/gopsutil/v3/cpu"
 	"github.com/shirou/gopsutil/v3/mem"
 )
@@ -11,6 +14,12 @@ type Process struct {
 	FreeMemory     Gauge
 }
 
+func init() {
+	Register("process", func(json.RawMessage) (Collector, error) {
+		return NewProcess()
+	})
+}
+
 func NewProcess() (*Process, error) {
 	cores, err := cpu.Counts(true)
 	if err != nil {
@@ -41,3 +50,12 @@ func (p *Process) Update() error {
 
 	return nil
 }
+
+// Collect() возвращает загрузку процессоров и объём памяти системы.
+func (p *Process) Collect(context.Context) ([]Sample, error) {
+	if err := p.Update(); err != nil {
+		return nil, err
+	}
+
+	return readStruct(p)
+}
@@ -0,0 +1,334 @@
+package metric
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+func init() {
+	Register("procwatch", func(options json.RawMessage) (Collector, error) {
+		var opts ProcWatchOptions
+		if err := decodeOptions(options, &opts); err != nil {
+			return nil, err
+		}
+		return NewProcWatch(opts)
+	})
+}
+
+var ErrInvalidWatch = errors.New("invalid process watch")
+
+// Отслеживаемая группа процессов. Процесс входит в группу, если подходит
+// под все заданные условия.
+type Watch struct {
+	// Имя группы в именах метрик.
+	Name string `json:"name"`
+	// Имя исполняемого файла процесса.
+	Process string `json:"process"`
+	// Регулярное выражение для командной строки процесса.
+	Cmdline string `json:"cmdline"`
+	// Файл с PID процесса.
+	Pidfile string `json:"pidfile"`
+}
+
+// Настройки коллектора procwatch.
+type ProcWatchOptions struct {
+	Watches []Watch `json:"watches"`
+}
+
+type watch struct {
+	cmdline *regexp.Regexp
+	Watch
+	label string
+}
+
+// Процесс, найденный при предыдущих сборах.
+type trackedProcess struct {
+	prevAt time.Time
+	proc   *process.Process
+	// подходит ли процесс под имя и командную строку каждой группы
+	matched []bool
+	created int64
+	prevCPU float64
+}
+
+// Использование ресурсов отслеживаемыми процессами. Значения процессов группы
+// суммируются, время работы берётся у самого старого процесса. Процессы ищутся
+// заново при каждом сборе, поэтому перезапущенный процесс учитывается под новым PID.
+// Имена метрик дополняются именем группы, например ProcRSS_nginx.
+type ProcWatch struct {
+	tracked map[int32]*trackedProcess
+	watches []watch
+	// нужно ли просматривать все процессы или достаточно PID из файлов
+	scanAll bool
+}
+
+func NewProcWatch(opts ProcWatchOptions) (*ProcWatch, error) {
+	pw := &ProcWatch{tracked: make(map[int32]*trackedProcess)}
+
+	seen := make(map[string]struct{}, len(opts.Watches))
+	for _, w := range opts.Watches {
+		if w.Name == "" {
+			return nil, fmt.Errorf("%w: name is required", ErrInvalidWatch)
+		}
+		if w.Process == "" && w.Cmdline == "" && w.Pidfile == "" {
+			return nil, fmt.Errorf("%w: %s: process, cmdline or pidfile is required", ErrInvalidWatch, w.Name)
+		}
+
+		ww := watch{Watch: w, label: label(w.Name)}
+		if _, ok := seen[ww.label]; ok {
+			return nil, fmt.Errorf("%w: duplicate name %q", ErrInvalidWatch, w.Name)
+		}
+		seen[ww.label] = struct{}{}
+
+		if w.Cmdline != "" {
+			re, err := regexp.Compile(w.Cmdline)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s: %v", ErrInvalidWatch, w.Name, err)
+			}
+			ww.cmdline = re
+		}
+		if w.Process != "" || w.Cmdline != "" {
+			pw.scanAll = true
+		}
+
+		pw.watches = append(pw.watches, ww)
+	}
+
+	return pw, nil
+}
+
+// Сводные значения группы процессов.
+type procGroup struct {
+	count   int
+	cpu     float64
+	rss     uint64
+	fds     int64
+	threads int64
+	oldest  int64
+}
+
+// Collect() возвращает количество процессов каждой группы, загрузку процессора в процентах,
+// резидентную память, открытые файловые дескрипторы, потоки и время работы в секундах в gauge.
+func (pw *ProcWatch) Collect(ctx context.Context) ([]Sample, error) {
+	pidfiles, errs := pw.readPidfiles()
+
+	pids, err := pw.candidates(ctx, pidfiles)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	groups := make([]procGroup, len(pw.watches))
+	alive := make(map[int32]struct{}, len(pids))
+	for _, pid := range pids {
+		t := pw.track(ctx, pid)
+		if t == nil {
+			continue
+		}
+		alive[pid] = struct{}{}
+
+		var (
+			stats   procStats
+			sampled bool
+		)
+		for i := range pw.watches {
+			if !t.matched[i] || !pw.matchesPidfile(i, pid, pidfiles) {
+				continue
+			}
+			if !sampled {
+				stats, sampled = t.sample(ctx, now), true
+			}
+			groups[i].add(stats, t.created)
+		}
+	}
+
+	for pid := range pw.tracked {
+		if _, ok := alive[pid]; !ok {
+			delete(pw.tracked, pid)
+		}
+	}
+
+	samples := make([]Sample, 0, 6*len(pw.watches))
+	for i, w := range pw.watches {
+		g := groups[i]
+		var uptime float64
+		if g.count > 0 {
+			uptime = now.Sub(time.UnixMilli(g.oldest)).Seconds()
+		}
+
+		samples = append(samples,
+			GaugeSample("ProcCount_"+w.label, float64(g.count)),
+			GaugeSample("ProcCPUPercent_"+w.label, g.cpu),
+			GaugeSample("ProcRSS_"+w.label, float64(g.rss)),
+			GaugeSample("ProcOpenFDs_"+w.label, float64(g.fds)),
+			GaugeSample("ProcThreads_"+w.label, float64(g.threads)),
+			GaugeSample("ProcUptime_"+w.label, uptime),
+		)
+	}
+
+	return samples, errors.Join(errs...)
+}
+
+// readPidfiles читает PID из файлов групп. Отсутствующий файл означает,
+// что процесс не запущен, и не считается ошибкой.
+func (pw *ProcWatch) readPidfiles() (map[int]int32, []error) {
+	pids := make(map[int]int32)
+	var errs []error
+	for i, w := range pw.watches {
+		if w.Pidfile == "" {
+			continue
+		}
+
+		data, err := os.ReadFile(w.Pidfile)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		pid, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 32)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid pidfile %s: %w", w.Pidfile, err))
+			continue
+		}
+		pids[i] = int32(pid)
+	}
+
+	return pids, errs
+}
+
+// candidates возвращает PID процессов, которые могут входить в группы.
+func (pw *ProcWatch) candidates(ctx context.Context, pidfiles map[int]int32) ([]int32, error) {
+	if pw.scanAll {
+		pids, err := process.PidsWithContext(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list processes: %w", err)
+		}
+		return pids, nil
+	}
+
+	pids := make([]int32, 0, len(pidfiles))
+	seen := make(map[int32]struct{}, len(pidfiles))
+	for _, pid := range pidfiles {
+		if _, ok := seen[pid]; !ok {
+			seen[pid] = struct{}{}
+			pids = append(pids, pid)
+		}
+	}
+
+	return pids, nil
+}
+
+// track возвращает отслеживаемый процесс с PID pid или nil, если процесс завершился.
+// Процесс, найденный ранее, переиспользуется, если не сменилось время его запуска.
+func (pw *ProcWatch) track(ctx context.Context, pid int32) *trackedProcess {
+	p, err := process.NewProcessWithContext(ctx, pid)
+	if err != nil {
+		return nil
+	}
+	created, err := p.CreateTimeWithContext(ctx)
+	if err != nil {
+		return nil
+	}
+
+	if t, ok := pw.tracked[pid]; ok && t.created == created {
+		return t
+	}
+
+	t := &trackedProcess{proc: p, created: created, matched: make([]bool, len(pw.watches))}
+	for i := range pw.watches {
+		t.matched[i] = pw.matches(ctx, i, p)
+	}
+	pw.tracked[pid] = t
+
+	return t
+}
+
+// matches проверяет имя и командную строку процесса. Они не меняются
+// за время работы процесса, поэтому проверяются один раз.
+func (pw *ProcWatch) matches(ctx context.Context, i int, p *process.Process) bool {
+	w := pw.watches[i]
+
+	if w.Process != "" {
+		name, err := p.NameWithContext(ctx)
+		if err != nil || name != w.Process {
+			return false
+		}
+	}
+	if w.cmdline != nil {
+		cmdline, err := p.CmdlineWithContext(ctx)
+		if err != nil || !w.cmdline.MatchString(cmdline) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchesPidfile проверяет PID процесса по файлу группы, который читается при каждом сборе.
+func (pw *ProcWatch) matchesPidfile(i int, pid int32, pidfiles map[int]int32) bool {
+	if pw.watches[i].Pidfile == "" {
+		return true
+	}
+
+	filePid, ok := pidfiles[i]
+	return ok && filePid == pid
+}
+
+// Использование ресурсов процессом.
+type procStats struct {
+	cpu     float64
+	rss     uint64
+	fds     int64
+	threads int64
+}
+
+// sample возвращает использование ресурсов процессом. Загрузка процессора считается
+// с предыдущего сбора, поэтому при первом сборе процесса она нулевая. Значения,
+// недоступные из-за прав доступа, пропускаются.
+func (t *trackedProcess) sample(ctx context.Context, now time.Time) procStats {
+	var stats procStats
+
+	if times, err := t.proc.TimesWithContext(ctx); err == nil {
+		total := times.User + times.System
+		if !t.prevAt.IsZero() {
+			if elapsed := now.Sub(t.prevAt).Seconds(); elapsed > 0 {
+				stats.cpu = 100 * (total - t.prevCPU) / elapsed
+			}
+		}
+		t.prevCPU, t.prevAt = total, now
+	}
+	if mem, err := t.proc.MemoryInfoWithContext(ctx); err == nil {
+		stats.rss = mem.RSS
+	}
+	if fds, err := t.proc.NumFDsWithContext(ctx); err == nil {
+		stats.fds = int64(fds)
+	}
+	if threads, err := t.proc.NumThreadsWithContext(ctx); err == nil {
+		stats.threads = int64(threads)
+	}
+
+	return stats
+}
+
+func (g *procGroup) add(stats procStats, created int64) {
+	g.count++
+	g.cpu += stats.cpu
+	g.rss += stats.rss
+	g.fds += stats.fds
+	g.threads += stats.threads
+	if g.oldest == 0 || created < g.oldest {
+		g.oldest = created
+	}
+}
@@ -0,0 +1,96 @@
+package metric
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func collectGauges(t *testing.T, c Collector) map[string]float64 {
+	t.Helper()
+
+	samples, err := c.Collect(context.Background())
+	require.NoError(t, err)
+
+	values := make(map[string]float64, len(samples))
+	for _, s := range samples {
+		values[s.Name] = s.Value
+	}
+	return values
+}
+
+func startSleep(t *testing.T) *exec.Cmd {
+	t.Helper()
+
+	cmd := exec.Command("sleep", "317")
+	if err := cmd.Start(); err != nil {
+		t.Skipf("unable to start sleep: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+	return cmd
+}
+
+func TestProcWatch_Collect(t *testing.T) {
+	pidfile := filepath.Join(t.TempDir(), "self.pid")
+	require.NoError(t, os.WriteFile(pidfile, []byte(strconv.Itoa(os.Getpid())+"\n"), 0o600))
+
+	pw, err := NewProcWatch(ProcWatchOptions{Watches: []Watch{
+		{Name: "self", Pidfile: pidfile},
+		{Name: "sleeper", Process: "sleep", Cmdline: `^sleep 317$`},
+	}})
+	require.NoError(t, err)
+
+	first := startSleep(t)
+	values := collectGauges(t, pw)
+	assert.Equal(t, 1.0, values["ProcCount_self"])
+	assert.Positive(t, values["ProcRSS_self"])
+	assert.Positive(t, values["ProcThreads_self"])
+	assert.Positive(t, values["ProcOpenFDs_self"])
+	assert.Equal(t, 1.0, values["ProcCount_sleeper"])
+
+	// перезапущенный процесс находится под новым PID
+	require.NoError(t, first.Process.Kill())
+	_ = first.Wait()
+	second := startSleep(t)
+	require.NotEqual(t, first.Process.Pid, second.Process.Pid)
+
+	values = collectGauges(t, pw)
+	assert.Equal(t, 1.0, values["ProcCount_sleeper"])
+	_, tracked := pw.tracked[int32(first.Process.Pid)]
+	assert.False(t, tracked, "exited process is forgotten")
+
+	require.NoError(t, second.Process.Kill())
+	_ = second.Wait()
+	values = collectGauges(t, pw)
+	assert.Equal(t, 0.0, values["ProcCount_sleeper"])
+	assert.Equal(t, 0.0, values["ProcUptime_sleeper"])
+}
+
+func TestNewProcWatch_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		watches []Watch
+	}{
+		{name: "no_name", watches: []Watch{{Process: "nginx"}}},
+		{name: "no_criteria", watches: []Watch{{Name: "nginx"}}},
+		{name: "bad_regexp", watches: []Watch{{Name: "nginx", Cmdline: "("}}},
+		{name: "duplicate", watches: []Watch{{Name: "a", Process: "a"}, {Name: "a", Process: "b"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewProcWatch(ProcWatchOptions{Watches: tt.watches})
+			assert.True(t, errors.Is(err, ErrInvalidWatch), err)
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package metric
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+func init() {
+	Register("prometheus", func(options json.RawMessage) (Collector, error) {
+		var opts PrometheusOptions
+		if err := decodeOptions(options, &opts); err != nil {
+			return nil, err
+		}
+		return NewPrometheus(opts)
+	})
+}
+
+// Время ожидания ответа по умолчанию в секундах.
+const defaultScrapeTimeout = 5
+
+// Наибольший размер ответа, который разбирает коллектор.
+const maxScrapeSize = 16 << 20
+
+var ErrInvalidTarget = errors.New("invalid scrape target")
+
+// Адрес, по которому приложение отдаёт метрики в текстовом формате Prometheus.
+type Target struct {
+	// Адрес, например http://localhost:9100/metrics.
+	URL string `json:"url"`
+	// Префикс имён метрик. Если опрашиваются несколько приложений,
+	// префикс исключает совпадение имён их метрик.
+	Prefix string `json:"prefix"`
+	// Метрики, которые передаются на сервер. Шаблоны сравниваются с именем
+	// метрики, дополненным метками, но без префикса.
+	Metrics Filter `json:"metrics"`
+	// Время ожидания ответа в секундах, но не больше интервала опроса агента.
+	// По умолчанию 5 секунд.
+	Timeout int `json:"timeout"`
+}
+
+// Настройки коллектора prometheus.
+type PrometheusOptions struct {
+	Targets []Target `json:"targets"`
+}
+
+// Метрики приложений, которые отдают их в текстовом формате Prometheus.
+// Значения counter, а также ряды _count и _bucket гистограмм и summary накопительные
+// и передаются приращениями, остальные значения, в том числе дробные суммы _sum, —
+// как gauge. Метки добавляются к имени метрики, например
+// http_requests_total_code_200_method_get.
+type Prometheus struct {
+	client  *http.Client
+	targets []*scrapeTarget
+}
+
+type scrapeTarget struct {
+	Target
+	counters *deltas
+}
+
+func NewPrometheus(opts PrometheusOptions) (*Prometheus, error) {
+	p := &Prometheus{
+		client:  &http.Client{},
+		targets: make([]*scrapeTarget, 0, len(opts.Targets)),
+	}
+	for _, t := range opts.Targets {
+		u, err := url.Parse(t.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("%w: url %q", ErrInvalidTarget, t.URL)
+		}
+		if t.Timeout < 0 {
+			return nil, fmt.Errorf("%w: %s: negative timeout", ErrInvalidTarget, t.URL)
+		}
+		if t.Timeout == 0 {
+			t.Timeout = defaultScrapeTimeout
+		}
+		if err := t.Metrics.Validate(); err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidTarget, t.URL, err)
+		}
+		p.targets = append(p.targets, &scrapeTarget{Target: t, counters: newDeltas()})
+	}
+
+	return p, nil
+}
+
+// Collect() опрашивает адреса параллельно. Ошибка одного адреса не мешает
+// учесть значения остальных.
+func (p *Prometheus) Collect(ctx context.Context) ([]Sample, error) {
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		samples []Sample
+		errs    []error
+	)
+	for _, t := range p.targets {
+		wg.Add(1)
+		go func(t *scrapeTarget) {
+			defer wg.Done()
+
+			s, err := p.scrape(ctx, t)
+			mu.Lock()
+			defer mu.Unlock()
+			samples = append(samples, s...)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("scrape %s: %w", t.URL, err))
+			}
+		}(t)
+	}
+	wg.Wait()
+
+	return samples, errors.Join(errs...)
+}
+
+func (p *Prometheus) scrape(ctx context.Context, t *scrapeTarget) ([]Sample, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(t.Timeout)*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/plain;version=0.0.4")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	parsed, err := parsePromText(io.LimitReader(resp.Body, maxScrapeSize))
+	selected := parsed[:0]
+	for _, s := range parsed {
+		if t.Metrics.Match(s.name) {
+			selected = append(selected, s)
+		}
+	}
+
+	return promSamples(selected, t.counters, t.Prefix), err
+}
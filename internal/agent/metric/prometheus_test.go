@@ -0,0 +1,80 @@
+package metric
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrometheus_Collect(t *testing.T) {
+	var requests atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := requests.Add(1)
+		fmt.Fprintf(w, `# TYPE app_requests_total counter
+app_requests_total{code="200"} %d
+# TYPE app_in_flight gauge
+app_in_flight 4
+# TYPE app_latency_seconds histogram
+app_latency_seconds_bucket{le="1"} %d
+app_latency_seconds_sum %g
+app_latency_seconds_count %d
+# TYPE go_goroutines gauge
+go_goroutines 12
+`, 10*n, n, 12+0.25*float64(n), n)
+	}))
+	defer srv.Close()
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	p, err := NewPrometheus(PrometheusOptions{Targets: []Target{
+		{URL: srv.URL + "/metrics", Prefix: "web_", Metrics: Filter{Include: []string{"app_*"}}},
+		{URL: failing.URL},
+	}})
+	require.NoError(t, err)
+
+	samples, err := p.Collect(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "503")
+	assert.ElementsMatch(t, []Sample{
+		CounterSample("web_app_requests_total_code_200", 0),
+		GaugeSample("web_app_in_flight", 4),
+		CounterSample("web_app_latency_seconds_bucket_le_1", 0),
+		GaugeSample("web_app_latency_seconds_sum", 12.25),
+		CounterSample("web_app_latency_seconds_count", 0),
+	}, samples)
+
+	samples, _ = p.Collect(context.Background())
+	assert.Contains(t, samples, CounterSample("web_app_requests_total_code_200", 10))
+	assert.Contains(t, samples, CounterSample("web_app_latency_seconds_count", 1))
+	// сумма растёт меньше чем на единицу и передаётся без округления
+	assert.Contains(t, samples, GaugeSample("web_app_latency_seconds_sum", 12.5))
+}
+
+func TestNewPrometheus_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		target Target
+	}{
+		{name: "no_url", target: Target{}},
+		{name: "bad_scheme", target: Target{URL: "ftp://localhost/metrics"}},
+		{name: "negative_timeout", target: Target{URL: "http://localhost:9100/metrics", Timeout: -1}},
+		{name: "bad_pattern", target: Target{URL: "http://localhost:9100/metrics", Metrics: Filter{Include: []string{"["}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewPrometheus(PrometheusOptions{Targets: []Target{tt.target}})
+			assert.True(t, errors.Is(err, ErrInvalidTarget), err)
+		})
+	}
+}
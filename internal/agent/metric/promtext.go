@@ -0,0 +1,206 @@
+package metric
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidPromText = errors.New("invalid prometheus text format")
+
+// Значение из текстового формата Prometheus.
+type promSample struct {
+	// Имя метрики, дополненное метками, например http_requests_total_code_200_method_get.
+	name string
+	// TypeGauge или TypeCounter.
+	kind  string
+	value float64
+}
+
+// parsePromText разбирает текстовый формат Prometheus. Метрики типа counter,
+// а также ряды _count и _bucket гистограмм и summary считаются накопительными
+// счётчиками, остальные, в том числе untyped и дробные суммы _sum, — gauge.
+// Метки добавляются к имени парами «имя_значение» в порядке имён меток.
+// Неконечные значения (NaN, ±Inf) и временные метки пропускаются. Ряды, имена
+// которых совпали после замены недопустимых символов, пропускаются, кроме первого.
+// Возвращает разобранные значения вместе с ошибками некорректных строк.
+func parsePromText(r io.Reader) ([]promSample, error) {
+	var (
+		samples []promSample
+		errs    []error
+	)
+	types := make(map[string]string)
+	seen := make(map[string]struct{})
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			if fields := strings.Fields(line); len(fields) == 4 && fields[1] == "TYPE" {
+				types[fields[2]] = fields[3]
+			}
+			continue
+		}
+
+		s, err := parsePromLine(line, types)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%w: line %d: %v", ErrInvalidPromText, n, err))
+			continue
+		}
+		if _, ok := seen[s.name]; ok {
+			errs = append(errs, fmt.Errorf("%w: line %d: duplicate series %s", ErrInvalidPromText, n, s.name))
+			continue
+		}
+		seen[s.name] = struct{}{}
+		if math.IsNaN(s.value) || math.IsInf(s.value, 0) {
+			continue
+		}
+		samples = append(samples, s)
+	}
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return samples, errors.Join(errs...)
+}
+
+func parsePromLine(line string, types map[string]string) (promSample, error) {
+	end := strings.IndexAny(line, "{ \t")
+	if end <= 0 {
+		return promSample{}, errors.New("missing value")
+	}
+	name, rest := line[:end], line[end:]
+
+	var labels [][2]string
+	if strings.HasPrefix(rest, "{") {
+		var err error
+		if labels, rest, err = parsePromLabels(rest[1:]); err != nil {
+			return promSample{}, err
+		}
+	}
+
+	fields := strings.Fields(rest)
+	if len(fields) == 0 || len(fields) > 2 {
+		return promSample{}, errors.New("missing value")
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return promSample{}, err
+	}
+
+	sort.Slice(labels, func(i, j int) bool { return labels[i][0] < labels[j][0] })
+	full := name
+	for _, l := range labels {
+		full += "_" + promLabel(l[0]) + "_" + promLabel(l[1])
+	}
+
+	return promSample{name: full, kind: promKind(name, types), value: value}, nil
+}
+
+// promLabel заменяет в имени или значении метки символы, недопустимые в имени
+// метрики, на подчёркивание. В отличие от label() пустое значение и символы
+// по краям сохраняются, поэтому "" и "/" дают разные имена.
+func promLabel(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// parsePromLabels разбирает метки после открывающей фигурной скобки
+// и возвращает остаток строки после закрывающей.
+func parsePromLabels(s string) ([][2]string, string, error) {
+	var labels [][2]string
+	for {
+		s = strings.TrimLeft(s, " \t")
+		if strings.HasPrefix(s, "}") {
+			return labels, s[1:], nil
+		}
+
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 || len(s) < eq+2 || s[eq+1] != '"' {
+			return nil, "", errors.New("invalid label")
+		}
+		key := strings.TrimSpace(s[:eq])
+		s = s[eq+2:]
+
+		var value strings.Builder
+		closed := false
+		for i := 0; i < len(s); i++ {
+			switch c := s[i]; {
+			case c == '\\' && i+1 < len(s):
+				i++
+				if s[i] == 'n' {
+					value.WriteByte('\n')
+				} else {
+					value.WriteByte(s[i])
+				}
+			case c == '"':
+				s, closed = s[i+1:], true
+			default:
+				value.WriteByte(c)
+			}
+			if closed {
+				break
+			}
+		}
+		if !closed {
+			return nil, "", errors.New("unterminated label value")
+		}
+		labels = append(labels, [2]string{key, value.String()})
+
+		s = strings.TrimLeft(s, " \t")
+		if strings.HasPrefix(s, ",") {
+			s = s[1:]
+		}
+	}
+}
+
+// promKind определяет тип значения по объявлению # TYPE метрики или её семейства.
+func promKind(name string, types map[string]string) string {
+	if types[name] == "counter" {
+		return TypeCounter
+	}
+	for _, suffix := range []string{"_count", "_bucket"} {
+		family, ok := strings.CutSuffix(name, suffix)
+		if !ok {
+			continue
+		}
+		if t := types[family]; t == "histogram" || t == "summary" {
+			return TypeCounter
+		}
+	}
+
+	return TypeGauge
+}
+
+// promSamples преобразует значения Prometheus в значения метрик с префиксом prefix.
+// Накопительные счётчики преобразуются в приращения, дробная часть отбрасывается,
+// отрицательные значения счётчиков пропускаются.
+func promSamples(parsed []promSample, counters *deltas, prefix string) []Sample {
+	samples := make([]Sample, 0, len(parsed))
+	for _, s := range parsed {
+		if s.kind == TypeCounter {
+			if s.value < 0 {
+				continue
+			}
+			samples = append(samples, counters.sample(prefix+s.name, uint64(s.value)))
+			continue
+		}
+		samples = append(samples, GaugeSample(prefix+s.name, s.value))
+	}
+	counters.prune()
+
+	return samples
+}
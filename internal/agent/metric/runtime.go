@@ -1,6 +1,8 @@
 package metric
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"reflect"
 	runt "runtime"
@@ -36,10 +38,25 @@ type Runtime struct {
 	TotalAlloc    Gauge
 }
 
+func init() {
+	Register("runtime", func(json.RawMessage) (Collector, error) {
+		return NewRuntime(), nil
+	})
+}
+
 func NewRuntime() *Runtime {
 	return &Runtime{}
 }
 
+// Collect() возвращает статистику распределения памяти среды выполнения Go.
+func (r *Runtime) Collect(context.Context) ([]Sample, error) {
+	if err := r.Update(); err != nil {
+		return nil, err
+	}
+
+	return readStruct(r)
+}
+
 func (r *Runtime) Update() error {
 	var runTime runt.MemStats
 	runt.ReadMemStats(&runTime)
@@ -0,0 +1,161 @@
+package receiver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Xacor/go-metrics/internal/agent/metric"
+	"github.com/Xacor/go-metrics/internal/logger"
+	"go.uber.org/zap"
+)
+
+// Наибольший размер тела запроса.
+const maxPushSize = 1 << 20
+
+// Время на завершение обработки запросов при остановке приёмника.
+const shutdownTimeout = 5 * time.Second
+
+// Приёмник метрик по HTTP. Принимает запросы в том же формате, что и сервер:
+// POST /update/ с метрикой в JSON, POST /updates/ со списком метрик в JSON
+// и POST /update/{type}/{id}/{value}. Приёмник не проверяет подпись
+// и авторизацию, поэтому по умолчанию слушает только loopback-адрес.
+type Push struct {
+	srv  *http.Server
+	ln   net.Listener
+	acc  *metric.Accumulator
+	opts Options
+	err  chan error
+}
+
+// NewPush() начинает принимать метрики на TCP-адресе addr и добавлять их в acc.
+func NewPush(addr string, acc *metric.Accumulator, opts Options) (*Push, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if err := opts.checkAddr(ln.Addr()); err != nil {
+		ln.Close()
+		return nil, err
+	}
+
+	p := &Push{ln: ln, acc: acc, opts: opts, err: make(chan error, 1)}
+	p.srv = &http.Server{Handler: p.Handler(), ReadHeaderTimeout: shutdownTimeout}
+	go func() {
+		logger.Get().Info("[push] started", zap.String("address", ln.Addr().String()))
+		p.err <- p.srv.Serve(ln)
+	}()
+
+	return p, nil
+}
+
+// Addr() возвращает адрес, на котором принимаются метрики.
+func (p *Push) Addr() net.Addr {
+	return p.ln.Addr()
+}
+
+// Close() прекращает приём метрик, дожидаясь обработки начатых запросов.
+func (p *Push) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := p.srv.Shutdown(ctx); err != nil {
+		return err
+	}
+	if err := <-p.err; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// Handler() возвращает обработчик запросов приёмника.
+func (p *Push) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/update/", p.update)
+	mux.HandleFunc("/updates/", p.updates)
+
+	return mux
+}
+
+func (p *Push) update(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if r.URL.Path != "/update/" {
+		sample, err := sampleFromPath(strings.TrimPrefix(r.URL.Path, "/update/"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		p.add(w, []metric.Sample{sample})
+		return
+	}
+
+	var sample metric.Sample
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPushSize)).Decode(&sample); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	p.add(w, []metric.Sample{sample})
+}
+
+func (p *Push) updates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if r.URL.Path != "/updates/" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	var m metric.Metrics
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPushSize)).Decode(&m); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	p.add(w, m.Samples)
+}
+
+// add учитывает значения и отвечает 429, если часть из них отброшена из-за лимита имён.
+func (p *Push) add(w http.ResponseWriter, samples []metric.Sample) {
+	if dropped := p.acc.AddLimited(samples, p.opts.maxNames()); dropped > 0 {
+		http.Error(w, fmt.Sprintf("%v: %d values dropped", ErrTooManyNames, dropped), http.StatusTooManyRequests)
+	}
+}
+
+// sampleFromPath разбирает путь вида {type}/{id}/{value}.
+func sampleFromPath(path string) (metric.Sample, error) {
+	parts := strings.Split(path, "/")
+	if len(parts) != 3 || parts[1] == "" {
+		return metric.Sample{}, metric.ErrInvalidSample
+	}
+
+	switch parts[0] {
+	case metric.TypeCounter:
+		delta, err := strconv.ParseInt(parts[2], 10, 64)
+		if err != nil {
+			return metric.Sample{}, metric.ErrInvalidSample
+		}
+		return metric.CounterSample(parts[1], delta), nil
+	case metric.TypeGauge:
+		value, err := strconv.ParseFloat(parts[2], 64)
+		// NaN и бесконечность нельзя передать на сервер в JSON
+		if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+			return metric.Sample{}, metric.ErrInvalidSample
+		}
+		return metric.GaugeSample(parts[1], value), nil
+	default:
+		return metric.Sample{}, metric.ErrInvalidSample
+	}
+}
@@ -0,0 +1,79 @@
+package receiver
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Xacor/go-metrics/internal/agent/metric"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPush_Handler(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{name: "batch", method: http.MethodPost, path: "/updates/", body: `[{"id":"Jobs","type":"counter","delta":2},{"id":"Load","type":"gauge","value":0.5}]`, status: http.StatusOK},
+		{name: "single", method: http.MethodPost, path: "/update/", body: `{"id":"Jobs","type":"counter","delta":3}`, status: http.StatusOK},
+		{name: "path", method: http.MethodPost, path: "/update/gauge/Temp/21.5", status: http.StatusOK},
+		{name: "missing_value", method: http.MethodPost, path: "/updates/", body: `[{"id":"Load","type":"gauge"}]`, status: http.StatusBadRequest},
+		{name: "unknown_type", method: http.MethodPost, path: "/update/", body: `{"id":"X","type":"histogram","value":1}`, status: http.StatusBadRequest},
+		{name: "bad_path_value", method: http.MethodPost, path: "/update/gauge/Temp/NaN", status: http.StatusBadRequest},
+		{name: "get", method: http.MethodGet, path: "/updates/", status: http.StatusMethodNotAllowed},
+	}
+
+	acc := metric.NewAccumulator()
+	handler := (&Push{acc: acc}).Handler()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+			assert.Equal(t, tt.status, w.Code, w.Body.String())
+		})
+	}
+
+	assert.Equal(t, []metric.Sample{
+		metric.CounterSample("Jobs", 5),
+		metric.GaugeSample("Load", 0.5),
+		metric.GaugeSample("Temp", 21.5),
+	}, acc.Drain().Samples)
+}
+
+func TestNewPush(t *testing.T) {
+	acc := metric.NewAccumulator()
+	p, err := NewPush("127.0.0.1:0", acc, Options{})
+	require.NoError(t, err)
+
+	resp, err := http.Post("http://"+p.Addr().String()+"/update/counter/Hits/1", "text/plain", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []metric.Sample{metric.CounterSample("Hits", 1)}, acc.Drain().Samples)
+
+	require.NoError(t, p.Close())
+
+	_, err = NewPush("0.0.0.0:0", acc, Options{})
+	assert.True(t, errors.Is(err, ErrRemoteAddress), err)
+
+	p, err = NewPush("0.0.0.0:0", acc, Options{AllowRemote: true})
+	require.NoError(t, err)
+	require.NoError(t, p.Close())
+}
+
+func TestPush_MaxNames(t *testing.T) {
+	acc := metric.NewAccumulator()
+	handler := (&Push{acc: acc, opts: Options{MaxNames: 2}}).Handler()
+
+	w := httptest.NewRecorder()
+	body := `[{"id":"Jobs","type":"counter","delta":2},{"id":"Load","type":"gauge","value":0.5},{"id":"Temp","type":"gauge","value":21.5}]`
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/updates/", strings.NewReader(body)))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, []metric.Sample{metric.CounterSample("Jobs", 2), metric.GaugeSample("Load", 0.5)}, acc.Drain().Samples)
+}
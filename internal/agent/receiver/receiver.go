@@ -0,0 +1,54 @@
+package receiver
+
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+// Число имён метрик приложений, принимаемых между отправками на сервер, по умолчанию.
+const DefaultMaxNames = 10000
+
+var (
+	ErrRemoteAddress = errors.New("receiver address is not loopback")
+	ErrTooManyNames  = errors.New("too many metric names")
+)
+
+// Настройки приёмников метрик.
+type Options struct {
+	// Наибольшее число имён метрик приложений, накопленных между отправками на сервер.
+	// Метрики коллекторов агента в нём не учитываются. Значения метрик с новыми
+	// именами сверх него отбрасываются. По умолчанию DefaultMaxNames.
+	MaxNames int
+	// Разрешить приём не только на loopback-адресе. Приёмники не проверяют подпись
+	// и авторизацию, поэтому принимать метрики извне можно только в доверенной сети.
+	AllowRemote bool
+}
+
+func (o Options) maxNames() int {
+	if o.MaxNames > 0 {
+		return o.MaxNames
+	}
+
+	return DefaultMaxNames
+}
+
+// checkAddr проверяет, что приёмник слушает loopback-адрес, если приём извне не разрешён.
+func (o Options) checkAddr(addr net.Addr) error {
+	if o.AllowRemote {
+		return nil
+	}
+
+	var ip net.IP
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		ip = a.IP
+	case *net.UDPAddr:
+		ip = a.IP
+	}
+	if ip == nil || !ip.IsLoopback() {
+		return fmt.Errorf("%w: %s", ErrRemoteAddress, addr)
+	}
+
+	return nil
+}
@@ -0,0 +1,195 @@
+package receiver
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Xacor/go-metrics/internal/agent/metric"
+	"github.com/Xacor/go-metrics/internal/logger"
+	"go.uber.org/zap"
+)
+
+// Наибольший размер датаграммы UDP.
+const maxDatagramSize = 65535
+
+// Время, после которого текущее значение gauge без обновлений может быть
+// забыто, чтобы освободить место для новых имён.
+const gaugeTTL = 10 * time.Minute
+
+var (
+	ErrInvalidLine     = errors.New("invalid statsd line")
+	ErrUnsupportedType = errors.New("unsupported statsd metric type")
+)
+
+// Приёмник метрик по протоколу StatsD через UDP. Поддерживаются счётчики (c),
+// в том числе с частотой выборки @rate, и gauge (g), в том числе относительные
+// изменения со знаком + или -. Датаграмма может содержать несколько строк.
+// По умолчанию приёмник слушает только loopback-адрес.
+type StatsD struct {
+	conn net.PacketConn
+	acc  *metric.Accumulator
+	opts Options
+	// текущие значения gauge для относительных изменений
+	gauges map[string]gauge
+	// время, до которого устаревшие значения gauge не ищутся повторно
+	nextSweep time.Time
+	wg        sync.WaitGroup
+}
+
+type gauge struct {
+	value   float64
+	updated time.Time
+}
+
+// NewStatsD() начинает принимать метрики на UDP-адресе addr и добавлять их в acc.
+func NewStatsD(addr string, acc *metric.Accumulator, opts Options) (*StatsD, error) {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if err := opts.checkAddr(conn.LocalAddr()); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	s := &StatsD{conn: conn, acc: acc, opts: opts, gauges: make(map[string]gauge)}
+	s.wg.Add(1)
+	go s.run()
+
+	return s, nil
+}
+
+// Addr() возвращает адрес, на котором принимаются метрики.
+func (s *StatsD) Addr() net.Addr {
+	return s.conn.LocalAddr()
+}
+
+// Close() прекращает приём метрик.
+func (s *StatsD) Close() error {
+	err := s.conn.Close()
+	s.wg.Wait()
+
+	return err
+}
+
+func (s *StatsD) run() {
+	defer s.wg.Done()
+
+	l := logger.Get()
+	l.Info("[statsd] started", zap.String("address", s.Addr().String()))
+	buf := make([]byte, maxDatagramSize)
+	for {
+		n, _, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				l.Error("[statsd] unable to read datagram", zap.Error(err))
+			}
+			return
+		}
+
+		samples, err := s.parse(buf[:n])
+		if err != nil {
+			l.Debug("[statsd] invalid datagram", zap.Error(err))
+		}
+		if dropped := s.acc.AddLimited(samples, s.opts.maxNames()); dropped > 0 {
+			l.Debug("[statsd] values dropped", zap.Error(ErrTooManyNames), zap.Int("count", dropped))
+		}
+	}
+}
+
+// parse разбирает строки датаграммы. Возвращает разобранные значения вместе
+// с ошибками некорректных строк.
+func (s *StatsD) parse(data []byte) ([]metric.Sample, error) {
+	var (
+		samples []metric.Sample
+		errs    []error
+	)
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		sample, err := s.parseLine(string(line))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		samples = append(samples, sample)
+	}
+
+	return samples, errors.Join(errs...)
+}
+
+// parseLine разбирает строку вида «имя:значение|тип[|@частота][|#теги]».
+func (s *StatsD) parseLine(line string) (metric.Sample, error) {
+	name, rest, ok := strings.Cut(line, ":")
+	if !ok || name == "" {
+		return metric.Sample{}, fmt.Errorf("%w: %q", ErrInvalidLine, line)
+	}
+	parts := strings.Split(rest, "|")
+	if len(parts) < 2 {
+		return metric.Sample{}, fmt.Errorf("%w: %q", ErrInvalidLine, line)
+	}
+
+	value, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return metric.Sample{}, fmt.Errorf("%w: invalid value in %q", ErrInvalidLine, line)
+	}
+
+	switch parts[1] {
+	case "c":
+		rate := 1.0
+		for _, p := range parts[2:] {
+			if r, ok := strings.CutPrefix(p, "@"); ok {
+				if rate, err = strconv.ParseFloat(r, 64); err != nil || rate <= 0 || rate > 1 {
+					return metric.Sample{}, fmt.Errorf("%w: invalid sample rate in %q", ErrInvalidLine, line)
+				}
+			}
+		}
+		return metric.CounterSample(name, int64(math.Round(value/rate))), nil
+	case "g":
+		prev, ok := s.gauges[name]
+		if !ok && !s.reserveGauge() {
+			return metric.Sample{}, fmt.Errorf("%w: %q", ErrTooManyNames, name)
+		}
+		if parts[0][0] == '+' || parts[0][0] == '-' {
+			value += prev.value
+		}
+		s.gauges[name] = gauge{value: value, updated: time.Now()}
+		return metric.GaugeSample(name, value), nil
+	default:
+		return metric.Sample{}, fmt.Errorf("%w: %q", ErrUnsupportedType, parts[1])
+	}
+}
+
+// reserveGauge сообщает, можно ли запомнить значение gauge с новым именем.
+// При достижении лимита имён не чаще раза в минуту забываются значения,
+// не обновлявшиеся gaugeTTL.
+func (s *StatsD) reserveGauge() bool {
+	if len(s.gauges) < s.opts.maxNames() {
+		return true
+	}
+
+	now := time.Now()
+	if now.Before(s.nextSweep) {
+		return false
+	}
+	s.nextSweep = now.Add(time.Minute)
+
+	expired := now.Add(-gaugeTTL)
+	for name, g := range s.gauges {
+		if g.updated.Before(expired) {
+			delete(s.gauges, name)
+		}
+	}
+
+	return len(s.gauges) < s.opts.maxNames()
+}
@@ -0,0 +1,76 @@
+package receiver
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Xacor/go-metrics/internal/agent/metric"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsD_parse(t *testing.T) {
+	s := &StatsD{gauges: make(map[string]gauge)}
+
+	samples, err := s.parse([]byte("requests:1|c\nrequests:2|c|@0.5\n\nqueue:10|g\nqueue:-3|g\nqueue:+1|g|#env:prod\nlatency:12|ms\nbroken\n"))
+	assert.True(t, errors.Is(err, ErrUnsupportedType), err)
+	assert.True(t, errors.Is(err, ErrInvalidLine), err)
+	assert.Equal(t, []metric.Sample{
+		metric.CounterSample("requests", 1),
+		metric.CounterSample("requests", 4),
+		metric.GaugeSample("queue", 10),
+		metric.GaugeSample("queue", 7),
+		metric.GaugeSample("queue", 8),
+	}, samples)
+}
+
+func TestStatsD_MaxNames(t *testing.T) {
+	s := &StatsD{gauges: make(map[string]gauge), opts: Options{MaxNames: 1}}
+
+	samples, err := s.parse([]byte("queue:10|g\nusers:1|g\nqueue:+1|g"))
+	assert.True(t, errors.Is(err, ErrTooManyNames), err)
+	assert.Equal(t, []metric.Sample{metric.GaugeSample("queue", 10), metric.GaugeSample("queue", 11)}, samples)
+
+	// значения, давно не обновлявшиеся, уступают место новым именам
+	s.gauges["queue"] = gauge{value: 11, updated: time.Now().Add(-2 * gaugeTTL)}
+	s.nextSweep = time.Time{}
+	samples, err = s.parse([]byte("users:1|g"))
+	require.NoError(t, err)
+	assert.Equal(t, []metric.Sample{metric.GaugeSample("users", 1)}, samples)
+	assert.NotContains(t, s.gauges, "queue")
+}
+
+func TestStatsD_Receive(t *testing.T) {
+	acc := metric.NewAccumulator()
+	s, err := NewStatsD("127.0.0.1:0", acc, Options{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	conn, err := net.Dial("udp", s.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	for _, packet := range []string{"logins:2|c\nusers:42|g", "logins:3|c"} {
+		_, err = conn.Write([]byte(packet))
+		require.NoError(t, err)
+	}
+
+	want := []metric.Sample{metric.CounterSample("logins", 5), metric.GaugeSample("users", 42)}
+	var got metric.Metrics
+	assert.Eventually(t, func() bool {
+		m := acc.Drain()
+		got.Samples = append(got.Samples, m.Samples...)
+		return assert.ObjectsAreEqual(want, mergeSamples(got.Samples))
+	}, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, s.Close())
+}
+
+// mergeSamples объединяет значения нескольких отправок так же, как сервер.
+func mergeSamples(samples []metric.Sample) []metric.Sample {
+	acc := metric.NewAccumulator()
+	acc.Add(samples)
+	return acc.Drain().Samples
+}
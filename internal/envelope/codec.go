@@ -0,0 +1,87 @@
+package envelope
+
+import (
+	"crypto/rsa"
+	"fmt"
+
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/protobuf/proto"
+)
+
+// Имя кодека совпадает со стандартным, поэтому тип содержимого запросов не меняется,
+// а сервер различает зашифрованные и открытые сообщения по содержимому.
+const codecName = "proto"
+
+// ClientCodec шифрует сообщения запросов агента. Ответы сервера не шифруются.
+type ClientCodec struct {
+	pub   *rsa.PublicKey
+	keyID string
+}
+
+func NewClientCodec(pub *rsa.PublicKey, keyID string) encoding.Codec {
+	return &ClientCodec{pub: pub, keyID: keyID}
+}
+
+func (c *ClientCodec) Marshal(v interface{}) ([]byte, error) {
+	data, err := marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return Seal(c.pub, c.keyID, data)
+}
+
+func (c *ClientCodec) Unmarshal(data []byte, v interface{}) error {
+	return unmarshal(data, v)
+}
+
+func (c *ClientCodec) Name() string {
+	return codecName
+}
+
+// ServerCodec расшифровывает конверты и принимает незашифрованные сообщения.
+type ServerCodec struct {
+	keys *Keys
+}
+
+func NewServerCodec(keys *Keys) encoding.Codec {
+	return &ServerCodec{keys: keys}
+}
+
+func (c *ServerCodec) Marshal(v interface{}) ([]byte, error) {
+	return marshal(v)
+}
+
+func (c *ServerCodec) Unmarshal(data []byte, v interface{}) error {
+	if IsEnvelope(data) {
+		plaintext, err := c.keys.Open(data)
+		if err != nil {
+			return err
+		}
+		data = plaintext
+	}
+
+	return unmarshal(data, v)
+}
+
+func (c *ServerCodec) Name() string {
+	return codecName
+}
+
+func marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("failed to marshal, message is %T, want proto.Message", v)
+	}
+
+	return proto.Marshal(m)
+}
+
+func unmarshal(data []byte, v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("failed to unmarshal, message is %T, want proto.Message", v)
+	}
+
+	return proto.Unmarshal(data, m)
+}
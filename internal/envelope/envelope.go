@@ -0,0 +1,185 @@
+// Модуль envelope описывает гибридное шифрование данных агента.
+//
+// Данные шифруются случайным ключом AES-256-GCM, а сам ключ — открытым ключом RSA
+// сервера (RSA-OAEP, SHA-256). Так размер данных не ограничен размером ключа RSA.
+// Конверт содержит идентификатор ключа RSA, что позволяет серверу держать
+// несколько закрытых ключей на время их замены.
+//
+// Формат конверта:
+//
+//	magic(4) | version(1) | len(keyID)(1) | keyID | len(wrapped)(2) | wrapped | nonce(12) | ciphertext
+//
+// Заголовок до nonce включается в проверяемые GCM данные.
+package envelope
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Первый байт magic не может начинать ни сообщение protobuf, ни поток gzip,
+// поэтому конверт отличим от незашифрованных данных.
+var magic = []byte("GMEV")
+
+const (
+	version  = 1
+	keySize  = 32
+	maxKeyID = 255
+)
+
+var (
+	ErrNotEnvelope = errors.New("data is not an envelope")
+	ErrMalformed   = errors.New("malformed envelope")
+	ErrUnknownKey  = errors.New("unknown encryption key")
+	ErrDecrypt     = errors.New("unable to decrypt envelope")
+)
+
+// IsEnvelope() сообщает, являются ли данные конвертом.
+func IsEnvelope(data []byte) bool {
+	return bytes.HasPrefix(data, magic)
+}
+
+// Seal() шифрует данные открытым ключом pub с идентификатором keyID.
+func Seal(pub *rsa.PublicKey, keyID string, plaintext []byte) ([]byte, error) {
+	if len(keyID) > maxKeyID {
+		return nil, fmt.Errorf("%w: key id is too long", ErrMalformed)
+	}
+
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+
+	wrapped, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, dataKey, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 0, len(magic)+4+len(keyID)+len(wrapped))
+	header = append(header, magic...)
+	header = append(header, version, byte(len(keyID)))
+	header = append(header, keyID...)
+	header = binary.BigEndian.AppendUint16(header, uint16(len(wrapped)))
+	header = append(header, wrapped...)
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, len(header)+len(nonce)+len(plaintext)+gcm.Overhead())
+	out = append(out, header...)
+	out = append(out, nonce...)
+
+	return gcm.Seal(out, nonce, plaintext, header), nil
+}
+
+// Закрытые ключи сервера по идентификаторам.
+type Keys struct {
+	keys map[string]*rsa.PrivateKey
+}
+
+func NewKeys(keys map[string]*rsa.PrivateKey) *Keys {
+	return &Keys{keys: keys}
+}
+
+// Open() расшифровывает конверт. Конверт без идентификатора ключа
+// расшифровывается любым подходящим ключом.
+func (k *Keys) Open(data []byte) ([]byte, error) {
+	if !IsEnvelope(data) {
+		return nil, ErrNotEnvelope
+	}
+
+	rest := data[len(magic):]
+	if len(rest) < 2 || rest[0] != version {
+		return nil, fmt.Errorf("%w: unsupported version", ErrMalformed)
+	}
+
+	idLen := int(rest[1])
+	rest = rest[2:]
+	if len(rest) < idLen+2 {
+		return nil, ErrMalformed
+	}
+	keyID := string(rest[:idLen])
+	rest = rest[idLen:]
+
+	wrappedLen := int(binary.BigEndian.Uint16(rest))
+	rest = rest[2:]
+	if len(rest) < wrappedLen {
+		return nil, ErrMalformed
+	}
+	wrapped := rest[:wrappedLen]
+	rest = rest[wrappedLen:]
+	header := data[:len(data)-len(rest)]
+
+	dataKey, err := k.unwrap(keyID, wrapped)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) < gcm.NonceSize() {
+		return nil, ErrMalformed
+	}
+
+	plaintext, err := gcm.Open(nil, rest[:gcm.NonceSize()], rest[gcm.NonceSize():], header)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecrypt, err)
+	}
+
+	return plaintext, nil
+}
+
+// unwrap расшифровывает ключ данных закрытым ключом keyID.
+func (k *Keys) unwrap(keyID string, wrapped []byte) ([]byte, error) {
+	if key, ok := k.keys[keyID]; ok {
+		return decryptKey(key, wrapped)
+	}
+	if keyID != "" {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, keyID)
+	}
+
+	for _, key := range k.keys {
+		if dataKey, err := decryptKey(key, wrapped); err == nil {
+			return dataKey, nil
+		}
+	}
+
+	return nil, ErrDecrypt
+}
+
+func decryptKey(key *rsa.PrivateKey, wrapped []byte) ([]byte, error) {
+	dataKey, err := rsa.DecryptOAEP(sha256.New(), nil, key, wrapped, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecrypt, err)
+	}
+	if len(dataKey) != keySize {
+		return nil, fmt.Errorf("%w: invalid data key", ErrDecrypt)
+	}
+
+	return dataKey, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
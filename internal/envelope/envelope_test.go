@@ -0,0 +1,135 @@
+package envelope
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func generateKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	return key
+}
+
+func TestSealOpen(t *testing.T) {
+	current, next := generateKey(t), generateKey(t)
+	keys := NewKeys(map[string]*rsa.PrivateKey{"current": current, "next": next})
+
+	// пачка метрик заметно больше размера ключа RSA
+	body := bytes.Repeat([]byte(`{"id":"Alloc","type":"gauge","value":1}`), 1000)
+
+	tests := []struct {
+		name    string
+		pub     *rsa.PublicKey
+		keyID   string
+		tamper  func(data []byte)
+		wantErr error
+	}{
+		{name: "key_id", pub: &current.PublicKey, keyID: "current"},
+		{name: "next_key_id", pub: &next.PublicKey, keyID: "next"},
+		{name: "without_key_id", pub: &next.PublicKey},
+		{name: "unknown_key_id", pub: &next.PublicKey, keyID: "old", wantErr: ErrUnknownKey},
+		{name: "wrong_key_id", pub: &next.PublicKey, keyID: "current", wantErr: ErrDecrypt},
+		{
+			name:    "tampered_ciphertext",
+			pub:     &current.PublicKey,
+			keyID:   "current",
+			tamper:  func(data []byte) { data[len(data)-1] ^= 1 },
+			wantErr: ErrDecrypt,
+		},
+		{
+			name:    "tampered_version",
+			pub:     &current.PublicKey,
+			keyID:   "current",
+			tamper:  func(data []byte) { data[len(magic)] = 2 },
+			wantErr: ErrMalformed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sealed, err := Seal(tt.pub, tt.keyID, body)
+			require.NoError(t, err)
+			require.True(t, IsEnvelope(sealed))
+			if tt.tamper != nil {
+				tt.tamper(sealed)
+			}
+
+			opened, err := keys.Open(sealed)
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr), err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, body, opened)
+		})
+	}
+
+	_, err := keys.Open(body)
+	assert.True(t, errors.Is(err, ErrNotEnvelope))
+}
+
+func TestCodec(t *testing.T) {
+	key := generateKey(t)
+	client := NewClientCodec(&key.PublicKey, "current")
+	server := NewServerCodec(NewKeys(map[string]*rsa.PrivateKey{"current": key}))
+
+	msg := wrapperspb.String("metrics")
+
+	sealed, err := client.Marshal(msg)
+	require.NoError(t, err)
+	assert.True(t, IsEnvelope(sealed))
+
+	got := &wrapperspb.StringValue{}
+	require.NoError(t, server.Unmarshal(sealed, got))
+	assert.True(t, proto.Equal(msg, got))
+
+	// незашифрованные сообщения принимаются без изменений
+	plain, err := proto.Marshal(msg)
+	require.NoError(t, err)
+	got = &wrapperspb.StringValue{}
+	require.NoError(t, server.Unmarshal(plain, got))
+	assert.True(t, proto.Equal(msg, got))
+}
+
+func TestStatsHandler(t *testing.T) {
+	key := generateKey(t)
+	sealed, err := NewClientCodec(&key.PublicKey, "").Marshal(wrapperspb.String("metrics"))
+	require.NoError(t, err)
+	plain, err := proto.Marshal(wrapperspb.String("metrics"))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		data []byte
+		want bool
+	}{
+		{name: "sealed", data: sealed, want: false},
+		{name: "plaintext", data: plain, want: true},
+		{name: "empty", data: nil, want: false},
+	}
+
+	var h StatsHandler
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := h.TagRPC(context.Background(), &stats.RPCTagInfo{})
+			h.HandleRPC(ctx, &stats.InPayload{Data: tt.data})
+			assert.Equal(t, tt.want, Plaintext(ctx))
+		})
+	}
+
+	assert.False(t, Plaintext(context.Background()))
+}
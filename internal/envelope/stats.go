@@ -0,0 +1,42 @@
+package envelope
+
+import (
+	"context"
+	"sync/atomic"
+
+	"google.golang.org/grpc/stats"
+)
+
+// StatsHandler отмечает в контексте вызова gRPC, пришло ли сообщение запроса
+// без конверта. Ошибку кодека gRPC отдаёт клиенту с кодом Internal, поэтому
+// незашифрованные запросы отклоняет перехватчик по этой отметке, а не кодек.
+type StatsHandler struct{}
+
+type plaintextKey struct{}
+
+func (StatsHandler) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return context.WithValue(ctx, plaintextKey{}, new(atomic.Bool))
+}
+
+func (StatsHandler) HandleRPC(ctx context.Context, s stats.RPCStats) {
+	in, ok := s.(*stats.InPayload)
+	if !ok || in.Client {
+		return
+	}
+
+	if plaintext, ok := ctx.Value(plaintextKey{}).(*atomic.Bool); ok {
+		plaintext.Store(len(in.Data) > 0 && !IsEnvelope(in.Data))
+	}
+}
+
+func (StatsHandler) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (StatsHandler) HandleConn(context.Context, stats.ConnStats) {}
+
+// Plaintext() сообщает, что непустое сообщение запроса пришло без конверта.
+func Plaintext(ctx context.Context) bool {
+	plaintext, ok := ctx.Value(plaintextKey{}).(*atomic.Bool)
+	return ok && plaintext.Load()
+}
@@ -0,0 +1,98 @@
+// Модуль audit описывает журнал аудита сервера: кто, когда и откуда изменил
+// значения метрик или настройки сервера.
+package audit
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/Xacor/go-metrics/internal/server/auth"
+	"github.com/Xacor/go-metrics/internal/server/ipfilter"
+	"github.com/Xacor/go-metrics/internal/server/model"
+	"github.com/Xacor/go-metrics/internal/server/tenant"
+	"google.golang.org/grpc/peer"
+)
+
+// Операции, записываемые в журнал.
+const (
+	OpMetricCreate  = "metric.create"
+	OpMetricUpdate  = "metric.update"
+	OpMetricBatch   = "metric.batch"
+	OpKeyCreate     = "key.create"
+	OpKeyRevoke     = "key.revoke"
+	OpTokenIssue    = "token.issue"
+	OpKeyringReload = "keyring.reload"
+)
+
+// Изменение значения метрики.
+type Change struct {
+	Before *model.Metrics `json:"before,omitempty"`
+	After  *model.Metrics `json:"after,omitempty"`
+	Name   string         `json:"name"`
+	// Предыдущее значение не удалось прочитать. After для counter
+	// в этом случае содержит только приращение.
+	BeforeUnknown bool `json:"before_unknown,omitempty"`
+}
+
+// Запись журнала аудита.
+type Event struct {
+	Time time.Time `json:"time"`
+	// Субъект, от имени которого выполнена операция, если клиент аутентифицирован.
+	Actor  string `json:"actor,omitempty"`
+	Tenant string `json:"tenant,omitempty"`
+	// IP-адрес клиента.
+	Source    string `json:"source,omitempty"`
+	Operation string `json:"operation"`
+	// Объект операции, не являющийся метрикой: идентификатор ключа, путь к файлу и т.п.
+	Target  string   `json:"target,omitempty"`
+	Changes []Change `json:"changes,omitempty"`
+	// Порядковый номер записи. Пропуск номера означает удалённую запись.
+	Seq uint64 `json:"seq"`
+	// Хеш предыдущей записи и хеш этой записи, если включена цепочка хешей.
+	PrevHash string `json:"prev_hash,omitempty"`
+	Hash     string `json:"hash,omitempty"`
+}
+
+// Metrics() возвращает имена изменённых метрик.
+func (e *Event) Metrics() []string {
+	names := make([]string, 0, len(e.Changes))
+	for _, c := range e.Changes {
+		names = append(names, c.Name)
+	}
+
+	return names
+}
+
+// sourceFromContext возвращает адрес клиента, определённый фильтром адресов,
+// или адрес участника gRPC-вызова.
+func sourceFromContext(ctx context.Context) string {
+	if ip, ok := ipfilter.FromContext(ctx); ok {
+		return ip.String()
+	}
+
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			return host
+		}
+		return p.Addr.String()
+	}
+
+	return ""
+}
+
+// fill дополняет запись временем и сведениями о клиенте из контекста.
+func fill(ctx context.Context, e *Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	if id, ok := auth.FromContext(ctx); ok && e.Actor == "" {
+		e.Actor = id.Subject
+	}
+	if t, ok := tenant.FromContext(ctx); ok && e.Tenant == "" {
+		e.Tenant = t.ID
+	}
+	if e.Source == "" {
+		e.Source = sourceFromContext(ctx)
+	}
+}
@@ -0,0 +1,254 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+// Настройки журнала аудита.
+type Config struct {
+	// Путь к текущему файлу журнала. Заполненные файлы переименовываются
+	// в Path.1, Path.2 и т.д., Path.1 — самый новый из них.
+	Path string
+	// Размер файла в байтах, после которого начинается новый файл.
+	MaxSize int64
+	// Количество сохраняемых заполненных файлов. Более старые файлы удаляются.
+	// Если MaxSize или MaxBackups не заданы, файл не ротируется.
+	MaxBackups int
+	// Связывать записи цепочкой хешей SHA-256, чтобы изменение или удаление
+	// записи обнаруживалось при проверке журнала.
+	HashChain bool
+}
+
+// Журнал аудита в формате JSON Lines. Записи только добавляются в конец файла.
+type Log struct {
+	file *os.File
+	prev string
+	cfg  Config
+	size int64
+	seq  uint64
+	mu   sync.Mutex
+}
+
+// Open() открывает журнал и продолжает нумерацию и цепочку хешей с последней записи.
+func Open(cfg Config) (*Log, error) {
+	if cfg.Path == "" {
+		return nil, errors.New("audit log path is required")
+	}
+
+	l := &Log{cfg: cfg}
+
+	last, err := l.lastEvent()
+	if err != nil {
+		return nil, err
+	}
+	if last != nil {
+		l.seq = last.Seq
+		l.prev = last.Hash
+	}
+
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+func (l *Log) open() error {
+	f, err := os.OpenFile(l.cfg.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	l.file = f
+	l.size = info.Size()
+
+	return nil
+}
+
+// files возвращает существующие файлы журнала от самого старого к текущему.
+func (l *Log) files() ([]string, error) {
+	var files []string
+	for i := l.cfg.MaxBackups; i >= 1; i-- {
+		name := fmt.Sprintf("%s.%d", l.cfg.Path, i)
+		if _, err := os.Stat(name); err == nil {
+			files = append(files, name)
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	if _, err := os.Stat(l.cfg.Path); err == nil {
+		files = append(files, l.cfg.Path)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// lastEvent возвращает последнюю запись журнала или nil, если журнал пуст.
+func (l *Log) lastEvent() (*Event, error) {
+	files, err := l.files()
+	if err != nil {
+		return nil, err
+	}
+
+	var last *Event
+	for i := len(files) - 1; i >= 0 && last == nil; i-- {
+		err := readEvents(files[i], func(e *Event) bool {
+			last = e
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return last, nil
+}
+
+// Record() дополняет запись сведениями о клиенте из контекста и добавляет её в журнал.
+// Для nil журнала ничего не делает.
+func (l *Log) Record(ctx context.Context, e Event) error {
+	if l == nil {
+		return nil
+	}
+
+	fill(ctx, &e)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e.Seq = l.seq + 1
+	e.PrevHash, e.Hash = "", ""
+	if l.cfg.HashChain {
+		e.PrevHash = l.prev
+		hash, err := hashEvent(e)
+		if err != nil {
+			return err
+		}
+		e.Hash = hash
+	}
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	if l.cfg.MaxSize > 0 && l.cfg.MaxBackups > 0 && l.size > 0 && l.size+int64(len(line)) > l.cfg.MaxSize {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		return err
+	}
+
+	l.seq = e.Seq
+	l.prev = e.Hash
+
+	return nil
+}
+
+// rotate закрывает текущий файл и сдвигает номера заполненных файлов.
+func (l *Log) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+
+	oldest := fmt.Sprintf("%s.%d", l.cfg.Path, l.cfg.MaxBackups)
+	if err := os.Remove(oldest); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	for i := l.cfg.MaxBackups - 1; i >= 1; i-- {
+		err := os.Rename(fmt.Sprintf("%s.%d", l.cfg.Path, i), fmt.Sprintf("%s.%d", l.cfg.Path, i+1))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	if err := os.Rename(l.cfg.Path, l.cfg.Path+".1"); err != nil {
+		return err
+	}
+
+	return l.open()
+}
+
+func (l *Log) Close() error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.file.Close()
+}
+
+// hashEvent вычисляет хеш записи без поля Hash. Хеш предыдущей записи входит в данные.
+func hashEvent(e Event) (string, error) {
+	e.Hash = ""
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// readEvents читает записи файла по порядку, пока fn возвращает true.
+func readEvents(path string, fn func(e *Event) bool) error {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var e Event
+			if err := json.Unmarshal(line, &e); err != nil {
+				return fmt.Errorf("%w: %s: %v", ErrCorrupted, path, err)
+			}
+			if !fn(&e) {
+				return nil
+			}
+		}
+
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Xacor/go-metrics/internal/server/ipfilter"
+	"github.com/Xacor/go-metrics/internal/server/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gaugeChange(name string, value float64) []Change {
+	return []Change{{Name: name, After: &model.Metrics{Name: name, MType: model.TypeGauge, Value: &value}}}
+}
+
+func TestLog_RotationAndReopen(t *testing.T) {
+	cfg := Config{
+		Path:       filepath.Join(t.TempDir(), "audit.log"),
+		MaxSize:    400,
+		MaxBackups: 2,
+		HashChain:  true,
+	}
+
+	l, err := Open(cfg)
+	require.NoError(t, err)
+
+	ctx := ipfilter.NewContext(context.Background(), net.ParseIP("10.0.0.1"))
+	for i := 0; i < 5; i++ {
+		require.NoError(t, l.Record(ctx, Event{Operation: OpMetricUpdate, Changes: gaugeChange("load", float64(i))}))
+	}
+	require.NoError(t, l.Close())
+
+	// нумерация и цепочка продолжаются после повторного открытия
+	l, err = Open(cfg)
+	require.NoError(t, err)
+	defer l.Close()
+	require.NoError(t, l.Record(ctx, Event{Operation: OpKeyCreate, Target: "agent"}))
+
+	_, err = os.Stat(cfg.Path + ".1")
+	require.NoError(t, err, "log must be rotated")
+	_, err = os.Stat(cfg.Path + ".3")
+	assert.True(t, errors.Is(err, os.ErrNotExist), "only MaxBackups files are kept")
+
+	events, err := l.Query(Query{})
+	require.NoError(t, err)
+	require.NotEmpty(t, events)
+	last := events[len(events)-1]
+	assert.Equal(t, uint64(6), last.Seq)
+	assert.Equal(t, "10.0.0.1", last.Source)
+
+	count, err := l.Verify()
+	require.NoError(t, err)
+	assert.Equal(t, len(events), count)
+}
+
+func TestLog_Query(t *testing.T) {
+	l, err := Open(Config{Path: filepath.Join(t.TempDir(), "audit.log")})
+	require.NoError(t, err)
+	defer l.Close()
+
+	ctx := context.Background()
+	require.NoError(t, l.Record(ctx, Event{Actor: "agent-1", Operation: OpMetricUpdate, Changes: gaugeChange("load", 1)}))
+	require.NoError(t, l.Record(ctx, Event{Actor: "agent-2", Operation: OpMetricUpdate, Changes: gaugeChange("alloc", 2)}))
+	require.NoError(t, l.Record(ctx, Event{Actor: "admin", Operation: OpKeyRevoke, Target: "agent-2"}))
+	require.NoError(t, l.Record(ctx, Event{Actor: "agent-1", Operation: OpMetricUpdate, Changes: gaugeChange("load", 3)}))
+
+	tests := []struct {
+		name    string
+		query   Query
+		wantSeq []uint64
+	}{
+		{name: "all", query: Query{}, wantSeq: []uint64{1, 2, 3, 4}},
+		{name: "actor", query: Query{Actor: "agent-1"}, wantSeq: []uint64{1, 4}},
+		{name: "operation", query: Query{Operation: OpKeyRevoke}, wantSeq: []uint64{3}},
+		{name: "metric", query: Query{Metric: "alloc"}, wantSeq: []uint64{2}},
+		{name: "limit_keeps_latest", query: Query{Limit: 2}, wantSeq: []uint64{3, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := l.Query(tt.query)
+			require.NoError(t, err)
+
+			seqs := make([]uint64, 0, len(events))
+			for _, e := range events {
+				seqs = append(seqs, e.Seq)
+			}
+			assert.Equal(t, tt.wantSeq, seqs)
+		})
+	}
+}
+
+func TestLog_VerifyDetectsTampering(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l, err := Open(Config{Path: path, HashChain: true})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		require.NoError(t, l.Record(ctx, Event{Actor: "agent-1", Operation: OpMetricUpdate, Changes: gaugeChange("load", float64(i))}))
+	}
+	require.NoError(t, l.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.SplitAfter(string(data), "\n")
+
+	tests := []struct {
+		name   string
+		tamper func() string
+	}{
+		{
+			name:   "modified_record",
+			tamper: func() string { return strings.Replace(string(data), `"actor":"agent-1"`, `"actor":"agent-2"`, 1) },
+		},
+		{
+			name:   "deleted_record",
+			tamper: func() string { return lines[0] + lines[2] },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, os.WriteFile(path, []byte(tt.tamper()), 0o600))
+
+			l, err := Open(Config{Path: path, HashChain: true})
+			require.NoError(t, err)
+			defer l.Close()
+
+			_, err = l.Verify()
+			assert.True(t, errors.Is(err, ErrChainBroken), err)
+		})
+	}
+}
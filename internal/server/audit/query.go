@@ -0,0 +1,145 @@
+package audit
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Максимальное количество записей в ответе на запрос по умолчанию.
+const DefaultLimit = 100
+
+var (
+	ErrCorrupted   = errors.New("audit log is corrupted")
+	ErrChainBroken = errors.New("audit log hash chain is broken")
+)
+
+// Условия выборки записей журнала. Пустые поля не ограничивают выборку.
+type Query struct {
+	From      time.Time
+	To        time.Time
+	Actor     string
+	Operation string
+	Metric    string
+	Limit     int
+}
+
+func (q *Query) match(e *Event) bool {
+	if !q.From.IsZero() && e.Time.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && !e.Time.Before(q.To) {
+		return false
+	}
+	if q.Actor != "" && e.Actor != q.Actor && e.Source != q.Actor {
+		return false
+	}
+	if q.Operation != "" && e.Operation != q.Operation {
+		return false
+	}
+	if q.Metric == "" {
+		return true
+	}
+
+	for _, name := range e.Metrics() {
+		if name == q.Metric {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Query() возвращает последние q.Limit записей, подходящих под условия,
+// в порядке их добавления. Поле Actor сравнивается также с адресом клиента.
+func (l *Log) Query(q Query) ([]Event, error) {
+	if q.Limit <= 0 {
+		q.Limit = DefaultLimit
+	}
+
+	l.mu.Lock()
+	files, err := l.files()
+	l.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Event, 0, q.Limit)
+	for _, file := range files {
+		err := readEvents(file, func(e *Event) bool {
+			if !q.match(e) {
+				return true
+			}
+			if len(result) == q.Limit {
+				result = append(result[:0], result[1:]...)
+			}
+			result = append(result, *e)
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// Verify() проверяет непрерывность нумерации и цепочку хешей сохранённых записей
+// и возвращает количество проверенных записей. Первая сохранённая запись
+// считается достоверной: более ранние записи могли быть удалены ротацией.
+func (l *Log) Verify() (int, error) {
+	l.mu.Lock()
+	files, err := l.files()
+	l.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		prev    *Event
+		count   int
+		failure error
+	)
+	for _, file := range files {
+		err := readEvents(file, func(e *Event) bool {
+			failure = verifyEvent(prev, e)
+			if failure != nil {
+				return false
+			}
+			prev = e
+			count++
+			return true
+		})
+		if err != nil {
+			return count, err
+		}
+		if failure != nil {
+			return count, failure
+		}
+	}
+
+	return count, nil
+}
+
+func verifyEvent(prev, e *Event) error {
+	if prev != nil && e.Seq != prev.Seq+1 {
+		return fmt.Errorf("%w: record %d follows %d", ErrChainBroken, e.Seq, prev.Seq)
+	}
+
+	if e.Hash == "" {
+		return nil
+	}
+	if prev != nil && prev.Hash != "" && e.PrevHash != prev.Hash {
+		return fmt.Errorf("%w: record %d does not reference record %d", ErrChainBroken, e.Seq, prev.Seq)
+	}
+
+	hash, err := hashEvent(*e)
+	if err != nil {
+		return err
+	}
+	if hash != e.Hash {
+		return fmt.Errorf("%w: record %d is modified", ErrChainBroken, e.Seq)
+	}
+
+	return nil
+}
@@ -0,0 +1,207 @@
+// Модуль auth проверяет API-ключи и JWT клиентов сервера
+// и определяет права доступа по областям (scopes).
+package auth
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Область доступа.
+type Scope string
+
+const (
+	// Чтение метрик и истории.
+	ScopeRead Scope = "read"
+	// Запись метрик.
+	ScopeWrite Scope = "write"
+	// Управление ключами и отладочные ручки. Включает остальные области.
+	ScopeAdmin Scope = "admin"
+)
+
+// Заголовки HTTP-запроса с учётными данными. В gRPC используются
+// одноимённые ключи метаданных в нижнем регистре.
+const (
+	HeaderAuthorization = "Authorization"
+	HeaderAPIKey        = "X-API-Key"
+)
+
+const bearerPrefix = "Bearer "
+
+var (
+	ErrNoCredentials      = errors.New("credentials are not provided")
+	ErrInvalidKey         = errors.New("invalid api key")
+	ErrInvalidToken       = errors.New("invalid token")
+	ErrInvalidScope       = errors.New("invalid scope")
+	ErrKeyNotFound        = errors.New("api key not found")
+	ErrKeyExists          = errors.New("api key already exists")
+	ErrPermissionDenied   = errors.New("permission denied")
+	ErrJWTDisabled        = errors.New("jwt secret is not configured")
+	ErrInvalidCertificate = errors.New("invalid client certificate")
+)
+
+func ParseScope(s string) (Scope, error) {
+	switch scope := Scope(s); scope {
+	case ScopeRead, ScopeWrite, ScopeAdmin:
+		return scope, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrInvalidScope, s)
+	}
+}
+
+// Клиент, подтвердивший свою подлинность.
+type Identity struct {
+	// Идентификатор API-ключа, subject токена или CN сертификата.
+	Subject string
+	// Арендатор, к которому привязаны учётные данные. Пустой, если не привязаны.
+	Tenant string
+	Scopes []Scope
+}
+
+// Allows() сообщает, есть ли у клиента доступ к области scope.
+func (id *Identity) Allows(scope Scope) bool {
+	for _, s := range id.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Проверяет учётные данные клиентов: API-ключи из хранилища ключей,
+// JWT, подписанные по HS256, и проверенные TLS-сертификаты клиентов.
+type Authenticator struct {
+	keys      *KeyStore
+	jwtSecret []byte
+	// области доступа клиентов, предъявивших сертификат
+	certScopes []Scope
+}
+
+// NewAuthenticator создаёт проверку учётных данных. Нулевое значение любого
+// из параметров отключает соответствующий способ аутентификации.
+func NewAuthenticator(keys *KeyStore, jwtSecret []byte, certScopes []Scope) *Authenticator {
+	return &Authenticator{keys: keys, jwtSecret: jwtSecret, certScopes: certScopes}
+}
+
+// Keys() возвращает хранилище API-ключей.
+func (a *Authenticator) Keys() *KeyStore {
+	return a.keys
+}
+
+// Authenticate() проверяет учётные данные из заголовка Authorization
+// ("Bearer <JWT>" или "Bearer <API-ключ>") или из заголовка X-API-Key.
+// Если заголовков нет, клиент определяется по сертификату cert, уже
+// проверенному при установке TLS-соединения.
+func (a *Authenticator) Authenticate(authorization, apiKey string, cert *x509.Certificate) (*Identity, error) {
+	if token, ok := strings.CutPrefix(authorization, bearerPrefix); ok {
+		if strings.Count(token, ".") == 2 {
+			return a.verifyJWT(token)
+		}
+		apiKey = token
+	}
+
+	if apiKey == "" {
+		return a.fromCertificate(cert)
+	}
+
+	if a.keys == nil {
+		return nil, ErrInvalidKey
+	}
+
+	return a.keys.Verify(apiKey)
+}
+
+// fromCertificate возвращает клиента, subject которого — CN сертификата.
+func (a *Authenticator) fromCertificate(cert *x509.Certificate) (*Identity, error) {
+	if cert == nil || len(a.certScopes) == 0 {
+		return nil, ErrNoCredentials
+	}
+
+	if cert.Subject.CommonName == "" {
+		return nil, fmt.Errorf("%w: certificate without common name", ErrInvalidCertificate)
+	}
+
+	return &Identity{Subject: cert.Subject.CommonName, Scopes: a.certScopes}, nil
+}
+
+// Утверждения JWT. Области перечисляются через пробел, как в RFC 8693.
+type claims struct {
+	jwt.RegisteredClaims
+	Scope  string `json:"scope"`
+	Tenant string `json:"tenant,omitempty"`
+}
+
+func (a *Authenticator) verifyJWT(token string) (*Identity, error) {
+	if len(a.jwtSecret) == 0 {
+		return nil, ErrInvalidToken
+	}
+
+	var c claims
+	_, err := jwt.ParseWithClaims(token, &c, func(*jwt.Token) (interface{}, error) {
+		return a.jwtSecret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	// бессрочные токены не принимаются
+	if c.ExpiresAt == nil {
+		return nil, fmt.Errorf("%w: exp claim is required", ErrInvalidToken)
+	}
+
+	id := &Identity{Subject: c.Subject, Tenant: c.Tenant}
+	for _, s := range strings.Fields(c.Scope) {
+		scope, err := ParseScope(s)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+		}
+		id.Scopes = append(id.Scopes, scope)
+	}
+
+	return id, nil
+}
+
+// IssueJWT() выпускает токен с заданными областями доступа, действительный ttl.
+func (a *Authenticator) IssueJWT(subject, tenant string, scopes []Scope, ttl time.Duration) (string, error) {
+	if len(a.jwtSecret) == 0 {
+		return "", ErrJWTDisabled
+	}
+
+	names := make([]string, len(scopes))
+	for i, s := range scopes {
+		names[i] = string(s)
+	}
+
+	now := time.Now()
+	c := claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   subject,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+		Scope:  strings.Join(names, " "),
+		Tenant: tenant,
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, c).SignedString(a.jwtSecret)
+}
+
+type ctxKey struct{}
+
+// NewContext() возвращает контекст запроса клиента id.
+func NewContext(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// FromContext() возвращает клиента, выполняющего запрос.
+func FromContext(ctx context.Context) (*Identity, bool) {
+	id, ok := ctx.Value(ctxKey{}).(*Identity)
+	return id, ok && id != nil
+}
@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthenticator_Authenticate(t *testing.T) {
+	keys := NewKeyStore()
+	require.NoError(t, keys.add(Key{ID: "agent", Key: "agent-key", Scopes: []Scope{ScopeWrite}, Tenant: "team-a"}))
+
+	secret := []byte("secret")
+	a := NewAuthenticator(keys, secret, []Scope{ScopeWrite})
+
+	token, err := a.IssueJWT("dashboard", "", []Scope{ScopeRead}, time.Minute)
+	require.NoError(t, err)
+
+	expired, err := a.IssueJWT("dashboard", "", []Scope{ScopeRead}, -time.Minute)
+	require.NoError(t, err)
+
+	foreign, err := NewAuthenticator(nil, []byte("other"), nil).IssueJWT("dashboard", "", []Scope{ScopeAdmin}, time.Minute)
+	require.NoError(t, err)
+
+	noExp, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims{Scope: "admin"}).SignedString(secret)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		authorization string
+		apiKey        string
+		cert          *x509.Certificate
+		wantSubject   string
+		wantScope     Scope
+		wantErr       error
+	}{
+		{
+			name:        "api_key_header",
+			apiKey:      "agent-key",
+			wantSubject: "agent",
+			wantScope:   ScopeWrite,
+		},
+		{
+			name:          "api_key_bearer",
+			authorization: "Bearer agent-key",
+			wantSubject:   "agent",
+			wantScope:     ScopeWrite,
+		},
+		{
+			name:          "jwt",
+			authorization: "Bearer " + token,
+			wantSubject:   "dashboard",
+			wantScope:     ScopeRead,
+		},
+		{
+			name:    "unknown_key",
+			apiKey:  "other-key",
+			wantErr: ErrInvalidKey,
+		},
+		{
+			name:          "expired_jwt",
+			authorization: "Bearer " + expired,
+			wantErr:       ErrInvalidToken,
+		},
+		{
+			name:          "foreign_jwt",
+			authorization: "Bearer " + foreign,
+			wantErr:       ErrInvalidToken,
+		},
+		{
+			name:          "jwt_without_exp",
+			authorization: "Bearer " + noExp,
+			wantErr:       ErrInvalidToken,
+		},
+		{
+			name:        "certificate",
+			cert:        &x509.Certificate{Subject: pkix.Name{CommonName: "agent-1"}},
+			wantSubject: "agent-1",
+			wantScope:   ScopeWrite,
+		},
+		{
+			name:          "header_before_certificate",
+			authorization: "Bearer " + token,
+			cert:          &x509.Certificate{Subject: pkix.Name{CommonName: "agent-1"}},
+			wantSubject:   "dashboard",
+			wantScope:     ScopeRead,
+		},
+		{
+			name:    "certificate_without_cn",
+			cert:    &x509.Certificate{},
+			wantErr: ErrInvalidCertificate,
+		},
+		{
+			name:    "no_credentials",
+			wantErr: ErrNoCredentials,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := a.Authenticate(tt.authorization, tt.apiKey, tt.cert)
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr), err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantSubject, id.Subject)
+			assert.True(t, id.Allows(tt.wantScope))
+			assert.False(t, id.Allows(ScopeAdmin))
+		})
+	}
+}
+
+func TestKeyStore_CreateRevoke(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[{"id": "admin", "key": "admin-key", "scopes": ["admin"]}]`), 0o600))
+
+	keys, err := LoadKeyStore(path)
+	require.NoError(t, err)
+
+	key, err := keys.Create("agent", "team-a", []Scope{ScopeWrite})
+	require.NoError(t, err)
+
+	_, err = keys.Create("agent", "", []Scope{ScopeRead})
+	assert.True(t, errors.Is(err, ErrKeyExists))
+
+	// ключи сохранены в файл без открытых значений
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "admin-key")
+	assert.NotContains(t, string(data), key)
+
+	reloaded, err := LoadKeyStore(path)
+	require.NoError(t, err)
+	id, err := reloaded.Verify(key)
+	require.NoError(t, err)
+	assert.Equal(t, "team-a", id.Tenant)
+	_, err = reloaded.Verify("admin-key")
+	require.NoError(t, err)
+
+	require.NoError(t, reloaded.Revoke("agent"))
+	_, err = reloaded.Verify(key)
+	assert.True(t, errors.Is(err, ErrInvalidKey))
+	assert.True(t, errors.Is(reloaded.Revoke("agent"), ErrKeyNotFound))
+}
@@ -0,0 +1,217 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Префикс выпускаемых сервером API-ключей.
+const keyPrefix = "gmk_"
+
+// API-ключ. Сервер хранит только хеш ключа.
+type Key struct {
+	CreatedAt time.Time `json:"created_at"`
+	ID        string    `json:"id"`
+	// Ключ в открытом виде. Допускается только в файле ключей, заданном
+	// администратором; при сохранении файла заменяется хешем.
+	Key string `json:"key,omitempty"`
+	// Хеш SHA-256 ключа в hex.
+	Hash   string  `json:"key_hash,omitempty"`
+	Tenant string  `json:"tenant,omitempty"`
+	Scopes []Scope `json:"scopes"`
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Хранилище API-ключей. Если задан файл, изменения сохраняются в него.
+type KeyStore struct {
+	keys map[string]*Key
+	path string
+	mu   sync.RWMutex
+}
+
+func NewKeyStore() *KeyStore {
+	return &KeyStore{keys: make(map[string]*Key)}
+}
+
+// LoadKeyStore() читает ключи из JSON-файла. Отсутствующий файл
+// будет создан при добавлении первого ключа.
+func LoadKeyStore(path string) (*KeyStore, error) {
+	s := NewKeyStore()
+	s.path = path
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []Key
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("unable to parse keys file: %w", err)
+	}
+
+	for _, k := range keys {
+		if err := s.add(k); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+func (s *KeyStore) add(k Key) error {
+	if k.ID == "" {
+		return fmt.Errorf("%w: empty id", ErrInvalidKey)
+	}
+	if _, ok := s.keys[k.ID]; ok {
+		return fmt.Errorf("%w: %s", ErrKeyExists, k.ID)
+	}
+
+	if k.Key != "" {
+		k.Hash = hashKey(k.Key)
+		k.Key = ""
+	}
+	if len(k.Hash) != sha256.Size*2 {
+		return fmt.Errorf("%w: %s: key or key_hash is required", ErrInvalidKey, k.ID)
+	}
+
+	for _, scope := range k.Scopes {
+		if _, err := ParseScope(string(scope)); err != nil {
+			return fmt.Errorf("%s: %w", k.ID, err)
+		}
+	}
+
+	s.keys[k.ID] = &k
+
+	return nil
+}
+
+// Verify() возвращает владельца API-ключа.
+func (s *KeyStore) Verify(key string) (*Identity, error) {
+	hash := []byte(hashKey(key))
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, k := range s.keys {
+		if subtle.ConstantTimeCompare(hash, []byte(k.Hash)) == 1 {
+			return &Identity{Subject: k.ID, Tenant: k.Tenant, Scopes: k.Scopes}, nil
+		}
+	}
+
+	return nil, ErrInvalidKey
+}
+
+// List() возвращает описания ключей, упорядоченные по идентификатору.
+func (s *KeyStore) List() []Key {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.sorted()
+}
+
+func (s *KeyStore) sorted() []Key {
+	keys := make([]Key, 0, len(s.keys))
+	for _, k := range s.keys {
+		keys = append(keys, *k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+
+	return keys
+}
+
+// Create() выпускает новый ключ и возвращает его в открытом виде.
+// Повторно получить ключ нельзя.
+func (s *KeyStore) Create(id, tenant string, scopes []Scope) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	key := keyPrefix + base64.RawURLEncoding.EncodeToString(buf)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.add(Key{
+		CreatedAt: time.Now().UTC(),
+		ID:        id,
+		Key:       key,
+		Tenant:    tenant,
+		Scopes:    scopes,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.save(); err != nil {
+		delete(s.keys, id)
+		return "", err
+	}
+
+	return key, nil
+}
+
+// Revoke() отзывает ключ.
+func (s *KeyStore) Revoke(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k, ok := s.keys[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrKeyNotFound, id)
+	}
+	delete(s.keys, id)
+
+	if err := s.save(); err != nil {
+		s.keys[id] = k
+		return err
+	}
+
+	return nil
+}
+
+// save атомарно перезаписывает файл ключей.
+func (s *KeyStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s.sorted(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
@@ -1,26 +1,78 @@
 package config
 
 import (
+	"bytes"
 	"crypto/rsa"
+	"crypto/tls"
 	"crypto/x509"
 	"encoding/pem"
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Xacor/go-metrics/internal/envelope"
+	"github.com/Xacor/go-metrics/internal/server/audit"
+	"github.com/Xacor/go-metrics/internal/server/ipfilter"
+	"github.com/Xacor/go-metrics/internal/server/limit"
+	"github.com/Xacor/go-metrics/internal/signing"
+	"github.com/Xacor/go-metrics/internal/tlsconfig"
 )
 
 type Config struct {
 	GRPCConfig
-	Address              string `env:"ADDRESS" json:"address"`
-	LogLevel             string `env:"LOG_LEVEL" json:"log_level"`
-	FileStoragePath      string `env:"FILE_STORAGE_PATH" json:"file_storage_path"`
-	DatabaseDSN          string `env:"DATABASE_DSN" json:"database_dsn"`
-	KeyFile              string `env:"KEY" json:"key_file"`
-	CryptoKeyPrivateFile string `env:"CRYPTO_KEY" json:"crypto_key"`
-	ConfigFile           string `env:"CONFIG" json:"-"`
-	TrustedSubnet        string `env:"TRUSTED_SUBNET" json:"trusted_subnet"`
-	StoreInterval        int    `env:"STORE_INTERVAL" json:"store_interval"`
-	Restore              bool   `env:"RESTORE" json:"restore"`
+	HistoryConfig
+	Address               string   `env:"ADDRESS" json:"address"`
+	LogLevel              string   `env:"LOG_LEVEL" json:"log_level"`
+	FileStoragePath       string   `env:"FILE_STORAGE_PATH" json:"file_storage_path"`
+	DatabaseDSN           string   `env:"DATABASE_DSN" json:"database_dsn"`
+	ReplicaDSNs           []string `env:"DATABASE_REPLICA_DSN" envSeparator:"," json:"database_replica_dsns"`
+	AllowedSubnets        []string `env:"ALLOWED_SUBNETS" envSeparator:"," json:"allowed_subnets"`
+	DeniedSubnets         []string `env:"DENIED_SUBNETS" envSeparator:"," json:"denied_subnets"`
+	TrustedProxies        []string `env:"TRUSTED_PROXIES" envSeparator:"," json:"trusted_proxies"`
+	KeyFile               string   `env:"KEY" json:"key_file"`
+	KeyringFile           string   `env:"SIGN_KEYRING" json:"sign_keyring"`
+	CryptoKeyPrivateFile  string   `env:"CRYPTO_KEY" json:"crypto_key"`
+	CryptoKeysDir         string   `env:"CRYPTO_KEYS_DIR" json:"crypto_keys_dir"`
+	ConfigFile            string   `env:"CONFIG" json:"-"`
+	TrustedSubnet         string   `env:"TRUSTED_SUBNET" json:"trusted_subnet"`
+	TenantsFile           string   `env:"TENANTS_FILE" json:"tenants_file"`
+	AuthKeysFile          string   `env:"AUTH_KEYS_FILE" json:"auth_keys_file"`
+	JWTSecretFile         string   `env:"JWT_SECRET_FILE" json:"jwt_secret_file"`
+	ClientCAFile          string   `env:"CLIENT_CA_FILE" json:"client_ca_file"`
+	CertScopes            string   `env:"CERT_SCOPES" json:"cert_scopes"`
+	AuditFile             string   `env:"AUDIT_FILE" json:"audit_file"`
+	StoreInterval         int      `env:"STORE_INTERVAL" json:"store_interval"`
+	CopyThreshold         int      `env:"COPY_THRESHOLD" json:"copy_threshold"`
+	DatabaseRetries       int      `env:"DATABASE_RETRIES" json:"database_retries"`
+	DatabaseCheckInterval int      `env:"DATABASE_CHECK_INTERVAL" json:"database_check_interval"`
+	ReadYourWrites        int      `env:"READ_YOUR_WRITES" json:"read_your_writes"`
+	SignatureSkew         int      `env:"SIGNATURE_SKEW" json:"signature_skew"`
+	KeyringReload         int      `env:"SIGN_KEYRING_RELOAD" json:"sign_keyring_reload"`
+	NonceCacheSize        int      `env:"NONCE_CACHE_SIZE" json:"nonce_cache_size"`
+	SignatureMinVersion   int      `env:"SIGNATURE_MIN_VERSION" json:"signature_min_version"`
+	AuditMaxSize          int      `env:"AUDIT_MAX_SIZE" json:"audit_max_size"`
+	AuditMaxBackups       int      `env:"AUDIT_MAX_BACKUPS" json:"audit_max_backups"`
+	ClientRateLimit       float64  `env:"CLIENT_RATE_LIMIT" json:"client_rate_limit"`
+	ClientBurst           int      `env:"CLIENT_BURST" json:"client_burst"`
+	MaxInFlight           int      `env:"MAX_IN_FLIGHT" json:"max_in_flight"`
+	ShedLatency           int      `env:"SHED_LATENCY" json:"shed_latency"`
+	Restore               bool     `env:"RESTORE" json:"restore"`
+	HTTPTLS               bool     `env:"HTTP_TLS" json:"http_tls"`
+	RequireClientCert     bool     `env:"REQUIRE_CLIENT_CERT" json:"require_client_cert"`
+	AuditHashChain        bool     `env:"AUDIT_HASH_CHAIN" json:"audit_hash_chain"`
+	AllowPlaintext        bool     `env:"ALLOW_PLAINTEXT" json:"allow_plaintext"`
+}
+
+// Сроки хранения истории значений метрик в PostgreSQL. По умолчанию история
+// не записывается: каждое обновление метрики добавляет строку в таблицу истории.
+type HistoryConfig struct {
+	RawRetention    int `env:"RAW_RETENTION" json:"raw_retention"`
+	MinuteRetention int `env:"MINUTE_RETENTION" json:"minute_retention"`
+	HourRetention   int `env:"HOUR_RETENTION" json:"hour_retention"`
+	RollupInterval  int `env:"ROLLUP_INTERVAL" json:"rollup_interval"`
 }
 
 type GRPCConfig struct {
@@ -38,6 +90,85 @@ func (c *Config) GetKey() (string, error) {
 	return string(key), nil
 }
 
+// GetKeyring() возвращает ключи для проверки подписей запросов и подписи ответов
+// или nil, если они не заданы. Единственный ключ из KeyFile используется, если не задан
+// набор ключей KeyringFile. Набор без действующего ключа для подписи ответов отклоняется.
+func (c *Config) GetKeyring() (*signing.Keyring, error) {
+	if c.KeyringFile != "" {
+		keyring, err := signing.LoadKeyring(c.KeyringFile)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := keyring.SigningKey(time.Now()); err != nil {
+			return nil, err
+		}
+		return keyring, nil
+	}
+
+	if c.KeyFile == "" {
+		return nil, nil
+	}
+
+	key, err := c.GetKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return signing.NewKeyring([]signing.Key{{Secret: key}})
+}
+
+// GetVerifier() возвращает проверку подписей ключами из keyring или nil, если keyring не задан.
+func (c *Config) GetVerifier(keyring *signing.Keyring) *signing.Verifier {
+	if keyring == nil {
+		return nil
+	}
+
+	skew := time.Duration(c.SignatureSkew) * time.Second
+	return signing.NewVerifier(keyring, skew, c.NonceCacheSize, c.SignatureMinVersion)
+}
+
+// GetAuditLog() открывает журнал аудита или возвращает nil, если он не задан.
+func (c *Config) GetAuditLog() (*audit.Log, error) {
+	if c.AuditFile == "" {
+		return nil, nil
+	}
+
+	return audit.Open(audit.Config{
+		Path:       c.AuditFile,
+		MaxSize:    int64(c.AuditMaxSize) << 20,
+		MaxBackups: c.AuditMaxBackups,
+		HashChain:  c.AuditHashChain,
+	})
+}
+
+// AuthEnabled() сообщает, включена ли аутентификация клиентов.
+func (c *Config) AuthEnabled() bool {
+	return c.AuthKeysFile != "" || c.JWTSecretFile != "" || (c.ClientCAFile != "" && c.CertScopes != "")
+}
+
+// TLSEnabled() сообщает, заданы ли сертификат и ключ сервера.
+func (c *Config) TLSEnabled() bool {
+	return c.TLSCertFile != "" && c.TLSKeyFile != ""
+}
+
+// GetTLSConfig() возвращает настройки TLS, общие для HTTP и gRPC.
+func (c *Config) GetTLSConfig() (*tls.Config, error) {
+	return tlsconfig.Server(c.TLSCertFile, c.TLSKeyFile, c.ClientCAFile, c.RequireClientCert)
+}
+
+func (c *Config) GetJWTSecret() ([]byte, error) {
+	if c.JWTSecretFile == "" {
+		return nil, nil
+	}
+
+	secret, err := os.ReadFile(c.JWTSecretFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSpace(secret), nil
+}
+
 func (c *Config) GetPrivateKey() (*rsa.PrivateKey, error) {
 	key, err := os.ReadFile(c.CryptoKeyPrivateFile)
 	if err != nil {
@@ -53,6 +184,56 @@ func (c *Config) GetPrivateKey() (*rsa.PrivateKey, error) {
 	return rsaKey, nil
 }
 
+// RequireEnvelope() сообщает, что при заданных закрытых ключах сервер принимает
+// данные агентов только в конвертах. AllowPlaintext оставляет приём открытых
+// данных на время перевода агентов на шифрование.
+func (c *Config) RequireEnvelope() bool {
+	return (c.CryptoKeyPrivateFile != "" || c.CryptoKeysDir != "") && !c.AllowPlaintext
+}
+
+// GetPrivateKeys() возвращает закрытые ключи для расшифровки данных агентов или nil,
+// если они не заданы. Ключ из CryptoKeyPrivateFile имеет пустой идентификатор,
+// идентификаторы ключей из каталога CryptoKeysDir — имена файлов *.pem без расширения.
+func (c *Config) GetPrivateKeys() (*envelope.Keys, error) {
+	keys := make(map[string]*rsa.PrivateKey)
+
+	if c.CryptoKeyPrivateFile != "" {
+		key, err := c.GetPrivateKey()
+		if err != nil {
+			return nil, err
+		}
+		keys[""] = key
+	}
+
+	if c.CryptoKeysDir != "" {
+		files, err := filepath.Glob(filepath.Join(c.CryptoKeysDir, "*.pem"))
+		if err != nil {
+			return nil, err
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("no *.pem keys in %s", c.CryptoKeysDir)
+		}
+
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			key, err := bytesToPrivateKey(data)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", file, err)
+			}
+			keys[strings.TrimSuffix(filepath.Base(file), ".pem")] = key
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	return envelope.NewKeys(keys), nil
+}
+
 func bytesToPrivateKey(b []byte) (*rsa.PrivateKey, error) {
 	var err error
 
@@ -69,3 +250,24 @@ func bytesToPrivateKey(b []byte) (*rsa.PrivateKey, error) {
 
 	return private, nil
 }
+
+// GetIPFilter() возвращает правила доступа по адресам клиентов.
+// Доверенная подсеть TrustedSubnet дополняет список разрешённых подсетей.
+func (c *Config) GetIPFilter() (*ipfilter.Filter, error) {
+	allow := c.AllowedSubnets
+	if c.TrustedSubnet != "" {
+		allow = append([]string{c.TrustedSubnet}, allow...)
+	}
+
+	return ipfilter.New(allow, c.DeniedSubnets, c.TrustedProxies)
+}
+
+// GetLimiter() возвращает ограничитель частоты запросов клиентов или nil, если он отключён.
+func (c *Config) GetLimiter() *limit.Limiter {
+	return limit.NewLimiter(c.ClientRateLimit, c.ClientBurst)
+}
+
+// GetShedder() возвращает ограничитель нагрузки или nil, если он отключён.
+func (c *Config) GetShedder() *limit.Shedder {
+	return limit.NewShedder(c.MaxInFlight, time.Duration(c.ShedLatency)*time.Millisecond)
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/Xacor/go-metrics/internal/signing"
 	"github.com/caarlos0/env/v6"
 )
 
@@ -15,15 +16,61 @@ func (c *Config) ParseFlags() {
 	flag.StringVar(&c.GAddress, "g", "localhost:8081", "grpc server address")
 	flag.StringVar(&c.GRPCConfig.TLSCertFile, "tls-cert", "", "tls cert file")
 	flag.StringVar(&c.GRPCConfig.TLSKeyFile, "tls-key", "", "tls key file")
+	flag.BoolVar(&c.HTTPTLS, "http-tls", false, "serve HTTP over TLS using tls-cert and tls-key")
+	flag.StringVar(&c.ClientCAFile, "client-ca", "", "CA file to verify client certificates on both listeners")
+	flag.BoolVar(&c.RequireClientCert, "require-client-cert", false, "reject clients without a certificate signed by client-ca")
+	flag.StringVar(&c.CertScopes, "cert-scopes", "", "comma separated scopes granted to clients authenticated by certificate")
 	flag.StringVar(&c.LogLevel, "l", "info", "log level")
 	flag.StringVar(&c.FileStoragePath, "f", "/tmp/metrics-db.json", "file storage path")
-	flag.StringVar(&c.DatabaseDSN, "d", "", "database dsn e.g. host=127.0.0.1 port=5432 user=user dbname=db password=pass")
+	flag.StringVar(&c.DatabaseDSN, "d", "", "database dsn e.g. host=127.0.0.1 port=5432 user=user dbname=db password=pass or sqlite:///path/to/metrics.db")
+	flag.Func("replica-dsn", "read replica dsn, may be repeated", func(dsn string) error {
+		c.ReplicaDSNs = append(c.ReplicaDSNs, dsn)
+		return nil
+	})
 	flag.StringVar(&c.KeyFile, "k", "", "signature key")
+	flag.StringVar(&c.KeyringFile, "keyring", "", "path to JSON file with signing keys, replaces signature key")
+	flag.IntVar(&c.KeyringReload, "keyring-reload", 30, "seconds between checks of keyring file changes, 0 disables reload")
+	flag.IntVar(&c.SignatureSkew, "sign-skew", 300, "seconds of allowed clock skew for signed requests")
+	flag.IntVar(&c.NonceCacheSize, "nonce-cache", signing.DefaultNonceCacheSize, "number of recent signature nonces kept to reject replayed requests")
+	flag.IntVar(&c.SignatureMinVersion, "sign-min-version", signing.V1, "minimal accepted signature version, 2 rejects requests without replay protection")
 	flag.StringVar(&c.CryptoKeyPrivateFile, "crypto-key", "", "path to RSA private key file in PEM format")
+	flag.StringVar(&c.CryptoKeysDir, "crypto-keys-dir", "", "directory with RSA private keys <key id>.pem for agents sending a key id")
+	flag.BoolVar(&c.AllowPlaintext, "allow-plaintext", false, "accept unencrypted agent data when crypto keys are set, for migration only")
 	flag.StringVar(&c.ConfigFile, "c", "", "path to configuration file")
 	flag.StringVar(&c.TrustedSubnet, "t", "", "trusted subnet")
+	flag.Func("allow", "allowed client subnet or address, may be repeated", func(cidr string) error {
+		c.AllowedSubnets = append(c.AllowedSubnets, cidr)
+		return nil
+	})
+	flag.Func("deny", "denied client subnet or address, takes precedence over allow, may be repeated", func(cidr string) error {
+		c.DeniedSubnets = append(c.DeniedSubnets, cidr)
+		return nil
+	})
+	flag.Func("trusted-proxy", "subnet or address of proxy whose X-Forwarded-For and X-Real-IP are honoured, may be repeated", func(cidr string) error {
+		c.TrustedProxies = append(c.TrustedProxies, cidr)
+		return nil
+	})
+	flag.StringVar(&c.AuthKeysFile, "auth-keys", "", "path to JSON file with API keys, enables authentication")
+	flag.StringVar(&c.JWTSecretFile, "jwt-secret", "", "path to file with HS256 secret for JWT, enables authentication")
+	flag.StringVar(&c.AuditFile, "audit-file", "", "path to audit log file, enables audit of writes and administrative actions")
+	flag.IntVar(&c.AuditMaxSize, "audit-max-size", 100, "audit log file size in megabytes before rotation")
+	flag.IntVar(&c.AuditMaxBackups, "audit-max-backups", 10, "number of rotated audit log files to keep, 0 disables rotation")
+	flag.BoolVar(&c.AuditHashChain, "audit-hash-chain", false, "chain audit records with SHA-256 hashes for tamper evidence")
+	flag.Float64Var(&c.ClientRateLimit, "client-rate", 0, "write requests per second allowed from a client, 0 disables limit")
+	flag.IntVar(&c.ClientBurst, "client-burst", 0, "write requests a client may send at once above client-rate, defaults to client-rate")
+	flag.IntVar(&c.MaxInFlight, "max-in-flight", 0, "write requests processed concurrently before rejecting new ones, 0 disables limit")
+	flag.IntVar(&c.ShedLatency, "shed-latency", 0, "milliseconds of average write latency above which concurrent writes are cut to a quarter, 0 disables")
+	flag.StringVar(&c.TenantsFile, "tenants", "", "path to JSON file with tenants and their quotas, enables multi-tenancy")
 	flag.BoolVar(&c.Restore, "r", true, "leave true to restore previous state")
 	flag.IntVar(&c.StoreInterval, "i", 300, "time between state saves")
+	flag.IntVar(&c.DatabaseRetries, "db-retries", 10, "attempts to connect to database on startup")
+	flag.IntVar(&c.DatabaseCheckInterval, "db-check-interval", 5, "seconds between database availability checks")
+	flag.IntVar(&c.ReadYourWrites, "read-your-writes", 0, "seconds to read just written metrics from primary instead of replicas, 0 disables")
+	flag.IntVar(&c.CopyThreshold, "copy-threshold", 500, "minimal batch size loaded into PostgreSQL via COPY, 0 disables COPY")
+	flag.IntVar(&c.RawRetention, "raw-retention", 0, "days to keep raw metric samples, 0 disables history; every update adds a row, so storage grows with update rate")
+	flag.IntVar(&c.MinuteRetention, "minute-retention", 14, "days to keep 1m aggregates")
+	flag.IntVar(&c.HourRetention, "hour-retention", 365, "days to keep 1h aggregates")
+	flag.IntVar(&c.RollupInterval, "rollup-interval", 60, "seconds between history rollups")
 	flag.Parse()
 }
 
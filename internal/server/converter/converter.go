@@ -6,24 +6,37 @@ import (
 )
 
 func ModelToProto(m model.Metrics) *pb.Metric {
-	return &pb.Metric{
-		Id:    m.Name,
-		Type:  m.MType,
-		Delta: *m.Delta,
-		Value: *m.Value,
+	p := &pb.Metric{
+		Id:   m.Name,
+		Type: m.MType,
 	}
+	if m.Delta != nil {
+		p.Delta = *m.Delta
+	}
+	if m.Value != nil {
+		p.Value = *m.Value
+	}
+
+	return p
 }
 
+// ProtoToModel заполняет только поле значения, соответствующее типу метрики.
 func ProtoToModel(p *pb.Metric) model.Metrics {
-	value := p.GetValue()
-	delta := p.GetDelta()
-
-	return model.Metrics{
+	m := model.Metrics{
 		Name:  p.GetId(),
 		MType: p.GetType(),
-		Value: &value,
-		Delta: &delta,
 	}
+
+	switch p.GetType() {
+	case model.TypeCounter:
+		delta := p.GetDelta()
+		m.Delta = &delta
+	case model.TypeGauge:
+		value := p.GetValue()
+		m.Value = &value
+	}
+
+	return m
 }
 
 func SliceModelToProto(m []model.Metrics) []*pb.Metric {
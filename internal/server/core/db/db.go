@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	"github.com/Xacor/go-metrics/internal/logger"
@@ -15,14 +16,20 @@ func InitDB(cfg *config.Config) storage.Storage {
 
 	var repo storage.Storage
 
-	if cfg.DatabaseDSN != "" {
-		ctx, cancelfunc := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	if strings.HasPrefix(cfg.DatabaseDSN, storage.SQLiteScheme) {
+		ctx, cancelfunc := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancelfunc()
-		postgre, err := storage.NewPostgreStorage(ctx, cfg.DatabaseDSN, l)
+		sqlite, err := storage.NewSQLiteStorage(ctx, cfg.DatabaseDSN, l)
+		if err != nil {
+			l.Fatal("can't open sqlite db", zap.Error(err))
+		}
+		repo = sqlite
+	} else if cfg.DatabaseDSN != "" {
+		postgre, err := connectPostgre(cfg, l)
 		if err != nil {
 			l.Fatal("can't init db connection", zap.Error(err))
 		}
-		repo = postgre
+		repo = storage.NewResilientStorage(postgre, time.Duration(cfg.DatabaseCheckInterval)*time.Second, l)
 	} else {
 		fs, err := storage.NewFileStorage(cfg.FileStoragePath)
 		if err != nil {
@@ -40,3 +47,55 @@ func InitDB(cfg *config.Config) storage.Storage {
 
 	return repo
 }
+
+// Время на одну попытку подключения к PostgreSQL, включая применение миграций.
+const connectTimeout = 5 * time.Second
+
+// Предельная пауза между попытками подключения.
+const maxBackoff = 30 * time.Second
+
+// connectPostgre подключается к PostgreSQL, повторяя попытки с экспоненциально
+// растущей паузой, пока не будет исчерпано cfg.DatabaseRetries попыток.
+func connectPostgre(cfg *config.Config, l *zap.Logger) (*storage.PostgreStorage, error) {
+	pcfg := &storage.PostgreConfig{
+		Logger:        l,
+		DSN:           cfg.DatabaseDSN,
+		CopyThreshold: cfg.CopyThreshold,
+		ReplicaDSNs:   cfg.ReplicaDSNs,
+		History: storage.HistoryConfig{
+			RawRetention:    cfg.RawRetention,
+			MinuteRetention: cfg.MinuteRetention,
+			HourRetention:   cfg.HourRetention,
+			RollupInterval:  time.Duration(cfg.RollupInterval) * time.Second,
+		},
+		ReplicaCheckInterval: time.Duration(cfg.DatabaseCheckInterval) * time.Second,
+		ReadYourWrites:       time.Duration(cfg.ReadYourWrites) * time.Second,
+	}
+
+	backoff := time.Second
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+		postgre, err := storage.NewPostgreStorage(ctx, pcfg)
+		cancel()
+
+		if err == nil {
+			return postgre, nil
+		}
+
+		if attempt >= cfg.DatabaseRetries {
+			return nil, err
+		}
+
+		l.Warn("database is unavailable, retrying",
+			zap.Error(err),
+			zap.Int("attempt", attempt),
+			zap.Duration("backoff", backoff),
+		)
+		time.Sleep(backoff)
+
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
@@ -2,13 +2,19 @@ package core
 
 import (
 	"context"
+	"errors"
+	"strconv"
+	"strings"
 
 	"github.com/Xacor/go-metrics/internal/server/converter"
+	"github.com/Xacor/go-metrics/internal/server/limit"
 	"github.com/Xacor/go-metrics/internal/server/model"
 	"github.com/Xacor/go-metrics/internal/server/storage"
 	pb "github.com/Xacor/go-metrics/proto"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
 )
@@ -29,7 +35,7 @@ func NewMetricsServer(repo storage.MetricRepo, logger *zap.Logger) *MetricsServe
 func (s *MetricsServer) Get(ctx context.Context, req *pb.GetRequest) (*pb.GetResponse, error) {
 	result, err := s.repo.Get(ctx, req.GetId())
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "unable to get metric with id %v: %v", req.GetId(), err)
+		return nil, status.Errorf(codeFor(err), "unable to get metric with id %v: %v", req.GetId(), err)
 	}
 
 	return &pb.GetResponse{Metric: converter.ModelToProto(result)}, nil
@@ -38,7 +44,7 @@ func (s *MetricsServer) Get(ctx context.Context, req *pb.GetRequest) (*pb.GetRes
 func (s *MetricsServer) List(ctx context.Context, _ *emptypb.Empty) (*pb.ListResponse, error) {
 	data, err := s.repo.All(ctx)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "unable to get metrics: %v", err)
+		return nil, status.Errorf(codeFor(err), "unable to get metrics: %v", err)
 	}
 
 	return &pb.ListResponse{Metrics: converter.SliceModelToProto(data)}, nil
@@ -50,13 +56,15 @@ func (s *MetricsServer) Update(ctx context.Context, req *pb.UpdateRequest) (*pb.
 		result, err = s.repo.Create(ctx, converter.ProtoToModel(req.GetMetric()))
 		if err != nil {
 			s.logger.Error(err.Error())
-			return nil, status.Errorf(codes.Internal, "unable to create metric %+v: %v", req.Metric, err)
+			setRetryAfter(ctx, err)
+			return nil, status.Errorf(codeFor(err), "unable to create metric %+v: %v", req.Metric, err)
 		}
 	} else {
 		result, err = s.repo.Update(ctx, converter.ProtoToModel(req.GetMetric()))
 		if err != nil {
 			s.logger.Error(err.Error())
-			return nil, status.Errorf(codes.Internal, "unable to update metric %+v: %v", req.Metric, err)
+			setRetryAfter(ctx, err)
+			return nil, status.Errorf(codeFor(err), "unable to update metric %+v: %v", req.Metric, err)
 		}
 	}
 
@@ -66,8 +74,30 @@ func (s *MetricsServer) Update(ctx context.Context, req *pb.UpdateRequest) (*pb.
 func (s *MetricsServer) UpdateList(ctx context.Context, req *pb.UpdateListRequest) (*emptypb.Empty, error) {
 	if err := s.repo.UpdateBatch(ctx, converter.SliceProtoToModel(req.GetMetric())); err != nil {
 		s.logger.Error("error when updating batch", zap.Error(err), zap.Any("batch", req.GetMetric()))
-		return nil, status.Errorf(codes.Internal, "unable to update batch %+v: %v", req.Metric, err)
+		setRetryAfter(ctx, err)
+		return nil, status.Errorf(codeFor(err), "unable to update batch %+v: %v", req.Metric, err)
 	}
 
 	return &emptypb.Empty{}, nil
 }
+
+// setRetryAfter передаёт в трейлере retry-after время до повтора запроса,
+// если ошибка хранилища его указывает.
+func setRetryAfter(ctx context.Context, err error) {
+	if delay, ok := storage.RetryAfter(err); ok {
+		seconds := strconv.Itoa(limit.RetryAfterSeconds(delay))
+		_ = grpc.SetTrailer(ctx, metadata.Pairs(strings.ToLower(limit.HeaderRetryAfter), seconds))
+	}
+}
+
+// codeFor возвращает код ответа на ошибку хранилища.
+func codeFor(err error) codes.Code {
+	switch {
+	case errors.Is(err, storage.ErrQuotaExceeded):
+		return codes.ResourceExhausted
+	case errors.Is(err, storage.ErrNoTenant):
+		return codes.Unauthenticated
+	default:
+		return codes.Internal
+	}
+}
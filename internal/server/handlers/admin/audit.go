@@ -0,0 +1,85 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Xacor/go-metrics/internal/server/audit"
+	"go.uber.org/zap"
+)
+
+// Записи журнала аудита. Параметры запроса: from и to в RFC 3339, actor —
+// субъект или IP-адрес клиента, operation, metric и limit.
+//
+// GET: /admin/audit
+func (api *API) QueryAudit(w http.ResponseWriter, r *http.Request) {
+	if api.audit == nil {
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+
+	params := r.URL.Query()
+	q := audit.Query{
+		Actor:     params.Get("actor"),
+		Operation: params.Get("operation"),
+		Metric:    params.Get("metric"),
+	}
+
+	var err error
+	if q.From, err = parseTime(params.Get("from")); err != nil {
+		http.Error(w, "invalid from: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if q.To, err = parseTime(params.Get("to")); err != nil {
+		http.Error(w, "invalid to: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if limit := params.Get("limit"); limit != "" {
+		if q.Limit, err = strconv.Atoi(limit); err != nil || q.Limit < 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	events, err := api.audit.Query(q)
+	if err != nil {
+		api.logger.Error("unable to query audit log", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, events)
+}
+
+// Проверка целостности журнала аудита.
+//
+// GET: /admin/audit/verify
+func (api *API) VerifyAudit(w http.ResponseWriter, r *http.Request) {
+	if api.audit == nil {
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+
+	count, err := api.audit.Verify()
+	if errors.Is(err, audit.ErrChainBroken) || errors.Is(err, audit.ErrCorrupted) {
+		writeJSON(w, http.StatusConflict, map[string]any{"valid": false, "verified": count, "error": err.Error()})
+		return
+	}
+	if err != nil {
+		api.logger.Error("unable to verify audit log", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"valid": true, "verified": count})
+}
+
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,59 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/Xacor/go-metrics/internal/server/audit"
+	"github.com/Xacor/go-metrics/internal/server/auth"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+type API struct {
+	auth   *auth.Authenticator
+	audit  *audit.Log
+	logger *zap.Logger
+}
+
+// NewAPI() создаёт API администратора. Журнал аудита может быть nil.
+func NewAPI(a *auth.Authenticator, auditLog *audit.Log, logger *zap.Logger) *API {
+	return &API{auth: a, audit: auditLog, logger: logger}
+}
+
+func (api *API) RegisterRoutes(router *chi.Mux) {
+	router.Route("/admin", func(r chi.Router) {
+		r.Use(requireAdmin)
+		r.Get("/keys", api.ListKeys)
+		r.Post("/keys", api.CreateKey)
+		r.Delete("/keys/{keyID}", api.RevokeKey)
+		r.Post("/tokens", api.IssueToken)
+		r.Get("/audit", api.QueryAudit)
+		r.Get("/audit/verify", api.VerifyAudit)
+	})
+}
+
+// requireAdmin пропускает только запросы клиентов с правами администратора.
+// Учётные данные проверяет WithAuth, а здесь права проверяются повторно,
+// чтобы API не оказался открытым, если проверка не была подключена.
+func requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, ok := auth.FromContext(r.Context())
+		if !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if !id.Allows(auth.ScopeAdmin) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// record записывает действие администратора в журнал аудита.
+func (api *API) record(r *http.Request, e audit.Event) {
+	if err := api.audit.Record(r.Context(), e); err != nil {
+		api.logger.Error("unable to write audit event", zap.Error(err), zap.String("operation", e.Operation))
+	}
+}
@@ -0,0 +1,32 @@
+package admin
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Xacor/go-metrics/internal/server/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireAdmin(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want int
+	}{
+		{name: "anonymous", ctx: context.Background(), want: http.StatusUnauthorized},
+		{name: "writer", ctx: auth.NewContext(context.Background(), &auth.Identity{Subject: "agent", Scopes: []auth.Scope{auth.ScopeWrite}}), want: http.StatusForbidden},
+		{name: "admin", ctx: auth.NewContext(context.Background(), &auth.Identity{Subject: "ops", Scopes: []auth.Scope{auth.ScopeAdmin}}), want: http.StatusOK},
+	}
+
+	handler := requireAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/keys", nil).WithContext(tt.ctx))
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
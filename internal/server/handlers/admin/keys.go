@@ -0,0 +1,165 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Xacor/go-metrics/internal/server/audit"
+	"github.com/Xacor/go-metrics/internal/server/auth"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+// Срок действия выпускаемых токенов по умолчанию.
+const defaultTokenTTL = 24 * time.Hour
+
+// Запрос на выпуск API-ключа или токена.
+type credentialsRequest struct {
+	ID     string       `json:"id"`
+	Tenant string       `json:"tenant,omitempty"`
+	Scopes []auth.Scope `json:"scopes"`
+	// Срок действия токена в секундах.
+	TTL int `json:"ttl,omitempty"`
+}
+
+func (req *credentialsRequest) validate() error {
+	if req.ID == "" {
+		return errors.New("id is required")
+	}
+	if len(req.Scopes) == 0 {
+		return errors.New("at least one scope is required")
+	}
+	for _, s := range req.Scopes {
+		if _, err := auth.ParseScope(string(s)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func decodeRequest(w http.ResponseWriter, r *http.Request) (credentialsRequest, bool) {
+	var req credentialsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return req, false
+	}
+
+	if err := req.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return req, false
+	}
+
+	return req, true
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	resp, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(resp)
+}
+
+// Список API-ключей без самих ключей.
+//
+// GET: /admin/keys
+func (api *API) ListKeys(w http.ResponseWriter, r *http.Request) {
+	if api.auth.Keys() == nil {
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, api.auth.Keys().List())
+}
+
+// Выпуск API-ключа. Ключ возвращается в ответе один раз.
+//
+// POST: /admin/keys
+func (api *API) CreateKey(w http.ResponseWriter, r *http.Request) {
+	if api.auth.Keys() == nil {
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+
+	req, ok := decodeRequest(w, r)
+	if !ok {
+		return
+	}
+
+	key, err := api.auth.Keys().Create(req.ID, req.Tenant, req.Scopes)
+	if errors.Is(err, auth.ErrKeyExists) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		api.logger.Error("unable to create api key", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	api.logger.Info("api key created", zap.String("id", req.ID), zap.Any("scopes", req.Scopes))
+	api.record(r, audit.Event{Operation: audit.OpKeyCreate, Target: req.ID})
+	writeJSON(w, http.StatusCreated, map[string]string{"id": req.ID, "key": key})
+}
+
+// Отзыв API-ключа.
+//
+// DELETE: /admin/keys/{keyID}
+func (api *API) RevokeKey(w http.ResponseWriter, r *http.Request) {
+	if api.auth.Keys() == nil {
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+
+	id := chi.URLParam(r, "keyID")
+	err := api.auth.Keys().Revoke(id)
+	if errors.Is(err, auth.ErrKeyNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		api.logger.Error("unable to revoke api key", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	api.logger.Info("api key revoked", zap.String("id", id))
+	api.record(r, audit.Event{Operation: audit.OpKeyRevoke, Target: id})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Выпуск JWT, подписанного секретом сервера.
+//
+// POST: /admin/tokens
+func (api *API) IssueToken(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeRequest(w, r)
+	if !ok {
+		return
+	}
+
+	ttl := defaultTokenTTL
+	if req.TTL > 0 {
+		ttl = time.Duration(req.TTL) * time.Second
+	}
+
+	token, err := api.auth.IssueJWT(req.ID, req.Tenant, req.Scopes, ttl)
+	if errors.Is(err, auth.ErrJWTDisabled) {
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+	if err != nil {
+		api.logger.Error("unable to issue token", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	api.record(r, audit.Event{Operation: audit.OpTokenIssue, Target: req.ID})
+	writeJSON(w, http.StatusCreated, map[string]string{"token": token})
+}
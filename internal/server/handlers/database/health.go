@@ -2,9 +2,22 @@ package database
 
 import (
 	"net/http"
+
+	"github.com/Xacor/go-metrics/internal/server/storage"
 )
 
+// Проверка доступности БД. Если хранилище работает в деградированном режиме,
+// возвращается 503 с телом "degraded".
+//
+// GET: /ping
 func (h *HealthService) Ping(w http.ResponseWriter, r *http.Request) {
+	if d, ok := h.db.(storage.Degrader); ok && d.Degraded() {
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("degraded"))
+		return
+	}
+
 	if err := h.db.Ping(r.Context()); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
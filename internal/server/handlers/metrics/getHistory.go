@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Xacor/go-metrics/internal/server/storage"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+// Период истории по умолчанию.
+const defaultHistorySpan = time.Hour
+
+// Получение истории значений метрики за период. Параметры from и to
+// задаются в формате RFC3339, по умолчанию возвращается последний час.
+//
+// GET: /history/{metricID}?from=...&to=...
+func (api *API) HistoryHandler(w http.ResponseWriter, r *http.Request) {
+	repo, ok := api.repo.(storage.HistoryRepo)
+	if !ok {
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+
+	metricID := chi.URLParam(r, "metricID")
+	if metricID == "" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	to := time.Now()
+	if v := r.URL.Query().Get("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		to = t
+	}
+
+	from := to.Add(-defaultHistorySpan)
+	if v := r.URL.Query().Get("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		from = t
+	}
+
+	if !from.Before(to) {
+		http.Error(w, "from must be before to", http.StatusBadRequest)
+		return
+	}
+
+	history, err := repo.History(r.Context(), metricID, from, to)
+	if errors.Is(err, storage.ErrHistoryUnsupported) {
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+	if err != nil {
+		api.logger.Error("unable to read history", zap.Error(err), zap.String("id", metricID))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	resp, err := json.Marshal(history)
+	if err != nil {
+		api.logger.Error(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
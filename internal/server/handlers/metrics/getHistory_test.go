@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mock_storage "github.com/Xacor/go-metrics/internal/server/mocks"
+	"github.com/Xacor/go-metrics/internal/server/model"
+	"github.com/Xacor/go-metrics/internal/server/storage"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// historyStorage запоминает запрошенный период и возвращает history или err.
+type historyStorage struct {
+	storage.Storage
+	from, to time.Time
+	err      error
+	calls    int
+}
+
+func (s *historyStorage) History(_ context.Context, name string, from, to time.Time) (model.History, error) {
+	s.calls++
+	s.from, s.to = from, to
+	if s.err != nil {
+		return model.History{}, s.err
+	}
+
+	return model.History{Name: name, Resolution: model.ResolutionRaw, Samples: []model.Sample{}}, nil
+}
+
+func TestAPI_HistoryHandler(t *testing.T) {
+	from := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		query    string
+		err      error
+		wantCode int
+		wantFrom time.Time
+		wantTo   time.Time
+	}{
+		{name: "range", query: "?from=2026-10-19T10:00:00Z&to=2026-10-19T12:00:00Z", wantCode: http.StatusOK, wantFrom: from, wantTo: to},
+		{name: "default_span", query: "?to=2026-10-19T12:00:00Z", wantCode: http.StatusOK, wantFrom: to.Add(-time.Hour), wantTo: to},
+		{name: "bad_from", query: "?from=yesterday", wantCode: http.StatusBadRequest},
+		{name: "bad_to", query: "?to=2026-10-19", wantCode: http.StatusBadRequest},
+		{name: "from_after_to", query: "?from=2026-10-19T12:00:00Z&to=2026-10-19T10:00:00Z", wantCode: http.StatusBadRequest},
+		{name: "empty_range", query: "?from=2026-10-19T12:00:00Z&to=2026-10-19T12:00:00Z", wantCode: http.StatusBadRequest},
+		{name: "disabled", err: storage.ErrHistoryUnsupported, wantCode: http.StatusNotImplemented},
+		{name: "storage_error", err: errors.New("connection refused"), wantCode: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &historyStorage{err: tt.err}
+			router := chi.NewRouter()
+			NewAPI(repo, zap.NewNop()).RegisterRoutes(router)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/history/Alloc"+tt.query, nil))
+
+			assert.Equal(t, tt.wantCode, w.Code)
+			if tt.wantCode == http.StatusBadRequest {
+				assert.Zero(t, repo.calls)
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+
+			assert.True(t, tt.wantFrom.Equal(repo.from), repo.from)
+			assert.True(t, tt.wantTo.Equal(repo.to), repo.to)
+
+			var history model.History
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &history))
+			assert.Equal(t, "Alloc", history.Name)
+			assert.Equal(t, model.ResolutionRaw, history.Resolution)
+		})
+	}
+}
+
+func TestAPI_HistoryHandler_Unsupported(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	api := &API{repo: mock_storage.NewMockStorage(ctrl), logger: zap.NewNop()}
+	router := chi.NewRouter()
+	api.RegisterRoutes(router)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/history/Alloc", nil))
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+}
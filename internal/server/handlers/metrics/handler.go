@@ -1,6 +1,11 @@
 package metrics
 
 import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Xacor/go-metrics/internal/server/limit"
 	"github.com/Xacor/go-metrics/internal/server/storage"
 	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
@@ -30,4 +35,27 @@ func (api *API) RegisterRoutes(router *chi.Mux) {
 	})
 
 	router.Post("/updates/", api.UpdateMetrics)
+
+	router.Get("/history/{metricID}", api.HistoryHandler)
+}
+
+// writeStatus отвечает статусом, соответствующим ошибке записи в хранилище,
+// и сообщает время до повтора запроса, если оно известно.
+func writeStatus(w http.ResponseWriter, err error) {
+	if delay, ok := storage.RetryAfter(err); ok {
+		w.Header().Set(limit.HeaderRetryAfter, strconv.Itoa(limit.RetryAfterSeconds(delay)))
+	}
+	w.WriteHeader(statusFor(err))
+}
+
+// statusFor возвращает статус ответа на ошибку записи в хранилище.
+func statusFor(err error) int {
+	switch {
+	case errors.Is(err, storage.ErrQuotaExceeded):
+		return http.StatusTooManyRequests
+	case errors.Is(err, storage.ErrNoTenant):
+		return http.StatusUnauthorized
+	default:
+		return http.StatusInternalServerError
+	}
 }
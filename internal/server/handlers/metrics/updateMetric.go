@@ -73,14 +73,14 @@ func (api *API) UpdateHandler(w http.ResponseWriter, r *http.Request) {
 	if _, err := api.repo.Get(r.Context(), metricID); err != nil {
 		if _, err = api.repo.Create(r.Context(), metric); err != nil {
 			api.logger.Error(err.Error())
-			w.WriteHeader(http.StatusInternalServerError)
+			writeStatus(w, err)
 		}
 		return
 	}
 
 	if _, err := api.repo.Update(r.Context(), metric); err != nil {
 		api.logger.Error(err.Error())
-		w.WriteHeader(http.StatusInternalServerError)
+		writeStatus(w, err)
 		return
 	}
 
@@ -116,13 +116,14 @@ func (api *API) UpdateJSON(w http.ResponseWriter, r *http.Request) {
 		result, err = api.repo.Create(r.Context(), metric)
 		if err != nil {
 			api.logger.Error(err.Error())
-			w.WriteHeader(http.StatusInternalServerError)
+			writeStatus(w, err)
+			return
 		}
 	} else {
 		result, err = api.repo.Update(r.Context(), metric)
 		if err != nil {
 			api.logger.Error(err.Error())
-			w.WriteHeader(http.StatusInternalServerError)
+			writeStatus(w, err)
 			return
 		}
 	}
@@ -158,7 +159,7 @@ func (api *API) UpdateMetrics(w http.ResponseWriter, r *http.Request) {
 
 	if err := api.repo.UpdateBatch(r.Context(), metrics); err != nil {
 		api.logger.Error("error when updating batch", zap.Error(err), zap.Any("batch", metrics))
-		w.WriteHeader(http.StatusInternalServerError)
+		writeStatus(w, err)
 		return
 	}
 
@@ -0,0 +1,69 @@
+package interceptors
+
+import (
+	"context"
+	"crypto/x509"
+
+	"github.com/Xacor/go-metrics/internal/server/auth"
+	pb "github.com/Xacor/go-metrics/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Области доступа, необходимые для вызова методов. Методы, которых нет
+// в таблице, требуют прав администратора.
+var methodScopes = map[string]auth.Scope{
+	pb.Metrics_Get_FullMethodName:        auth.ScopeRead,
+	pb.Metrics_List_FullMethodName:       auth.ScopeRead,
+	pb.Metrics_Update_FullMethodName:     auth.ScopeWrite,
+	pb.Metrics_UpdateList_FullMethodName: auth.ScopeWrite,
+}
+
+// InitAuth проверяет учётные данные клиента и его права на вызываемый метод.
+// Без Authenticator запросы пропускаются без изменений.
+func InitAuth(a *auth.Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+
+		if a == nil {
+			return handler(ctx, req)
+		}
+
+		md, _ := metadata.FromIncomingContext(ctx)
+		id, err := a.Authenticate(first(md, auth.HeaderAuthorization), first(md, auth.HeaderAPIKey), peerCertificate(ctx))
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+
+		scope, ok := methodScopes[info.FullMethod]
+		if !ok {
+			scope = auth.ScopeAdmin
+		}
+		if !id.Allows(scope) {
+			return nil, status.Errorf(codes.PermissionDenied, "%v: %s requires %s scope", auth.ErrPermissionDenied, info.FullMethod, scope)
+		}
+
+		return handler(auth.NewContext(ctx, id), req)
+	}
+}
+
+// peerCertificate возвращает проверенный сертификат клиента или nil.
+func peerCertificate(ctx context.Context) *x509.Certificate {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 {
+		return nil
+	}
+
+	return info.State.VerifiedChains[0][0]
+}
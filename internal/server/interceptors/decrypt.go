@@ -0,0 +1,26 @@
+package interceptors
+
+import (
+	"context"
+
+	"github.com/Xacor/go-metrics/internal/envelope"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// InitRequireEnvelope отклоняет запросы, сообщение которых пришло без конверта.
+// Отметку о конверте ставит envelope.StatsHandler.
+func InitRequireEnvelope(required bool) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+
+		if required && envelope.Plaintext(ctx) {
+			return nil, status.Error(codes.InvalidArgument, "request must be encrypted")
+		}
+
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,49 @@
+package interceptors
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Xacor/go-metrics/internal/server/limit"
+	"github.com/Xacor/go-metrics/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// InitRateLimit ограничивает частоту вызовов клиента и отклоняет вызовы при перегрузке
+// сервера с кодом ResourceExhausted. Время до повтора передаётся в трейлере retry-after.
+// Ограничиваются только вызовы, записывающие метрики.
+func InitRateLimit(limiter *limit.Limiter, shedder *limit.Shedder) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+
+		if info.FullMethod != proto.Metrics_Update_FullMethodName && info.FullMethod != proto.Metrics_UpdateList_FullMethodName {
+			return handler(ctx, req)
+		}
+
+		if delay, err := limiter.Allow(limit.Client(ctx), time.Now()); err != nil {
+			return nil, resourceExhausted(ctx, delay, err)
+		}
+
+		release, err := shedder.Acquire()
+		if err != nil {
+			return nil, resourceExhausted(ctx, limit.ShedRetryAfter, err)
+		}
+		defer release()
+
+		return handler(ctx, req)
+	}
+}
+
+func resourceExhausted(ctx context.Context, retryAfter time.Duration, err error) error {
+	seconds := strconv.Itoa(limit.RetryAfterSeconds(retryAfter))
+	_ = grpc.SetTrailer(ctx, metadata.Pairs(strings.ToLower(limit.HeaderRetryAfter), seconds))
+
+	return status.Error(codes.ResourceExhausted, err.Error())
+}
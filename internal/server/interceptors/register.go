@@ -1,28 +1,40 @@
 package interceptors
 
 import (
-	"net"
-
 	"github.com/Xacor/go-metrics/internal/logger"
+	"github.com/Xacor/go-metrics/internal/server/auth"
 	"github.com/Xacor/go-metrics/internal/server/config"
+	"github.com/Xacor/go-metrics/internal/server/limit"
+	"github.com/Xacor/go-metrics/internal/server/tenant"
+	"github.com/Xacor/go-metrics/internal/signing"
 	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/logging"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 )
 
-func RegisterUnaryInterceptorChain(cfg config.Config) grpc.ServerOption {
+func RegisterUnaryInterceptorChain(cfg config.Config, authenticator *auth.Authenticator, verifier *signing.Verifier, limiter *limit.Limiter, shedder *limit.Shedder) grpc.ServerOption {
 	l := logger.Get()
-	var ipNet *net.IPNet
-	if cfg.TrustedSubnet != "" {
-		_, trustedNet, err := net.ParseCIDR(cfg.TrustedSubnet)
-		ipNet = trustedNet
+	filter, err := cfg.GetIPFilter()
+	if err != nil {
+		l.Fatal("unable to parse client address rules", zap.Error(err))
+	}
+
+	var registry *tenant.Registry
+	if cfg.TenantsFile != "" {
+		r, err := tenant.LoadRegistry(cfg.TenantsFile)
 		if err != nil {
-			l.Fatal("unable to parse trusted subnet", zap.Error(err))
+			l.Fatal("unable to load tenants", zap.Error(err))
 		}
+		registry = r
 	}
+
 	return grpc.ChainUnaryInterceptor(
-		InitCheckSubnet(ipNet),
-		InitVerifySignature(cfg.KeyFile),
+		InitCheckSubnet(filter),
+		InitAuth(authenticator),
+		InitRateLimit(limiter, shedder),
+		InitTenant(registry),
+		InitRequireEnvelope(cfg.RequireEnvelope()),
+		InitVerifySignature(verifier),
 		logging.UnaryServerInterceptor(InterceptorLogger(l)),
 	)
 }
@@ -2,49 +2,52 @@ package interceptors
 
 import (
 	"context"
-	"crypto/hmac"
-	"crypto/sha256"
 	"encoding/json"
 
+	"github.com/Xacor/go-metrics/internal/signing"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
-func InitVerifySignature(signKey string) grpc.UnaryServerInterceptor {
+// InitVerifySignature проверяет подпись запроса из метаданных. Подпись версии 2
+// вычисляется от детерминированного protobuf-представления запроса,
+// версии 1 — от его JSON-представления.
+func InitVerifySignature(v *signing.Verifier) grpc.UnaryServerInterceptor {
 	return func(ctx context.Context,
 		req interface{},
 		info *grpc.UnaryServerInfo,
 		handler grpc.UnaryHandler) (interface{}, error) {
 
-		if signKey == "" {
+		if v == nil {
 			return handler(ctx, req)
 		}
 
-		md, ok := metadata.FromIncomingContext(ctx)
-		if !ok {
-			return nil, status.Error(codes.Internal, "uanble to get request metadata")
-		}
-
-		data, err := json.Marshal(req)
+		md, _ := metadata.FromIncomingContext(ctx)
+		sign, err := signing.Parse(func(key string) string { return first(md, key) })
 		if err != nil {
-			return nil, status.Error(codes.Internal, "unable to marshall request")
+			return nil, status.Error(codes.PermissionDenied, err.Error())
 		}
 
-		h := hmac.New(sha256.New, []byte(signKey))
-		_, err = h.Write(data)
+		data, err := signedBytes(sign.Version, req)
 		if err != nil {
-			return nil, status.Error(codes.Internal, "unable to calculate request hash")
+			return nil, status.Error(codes.Internal, "unable to marshall request")
 		}
 
-		csign := []byte(md.Get("HashSHA256")[0])
-		ssign := h.Sum(nil)
-
-		if !hmac.Equal(csign, ssign) {
-			return nil, status.Error(codes.PermissionDenied, "signatures are't equal")
+		if err := v.Verify(sign, data); err != nil {
+			return nil, status.Error(codes.PermissionDenied, err.Error())
 		}
 
 		return handler(ctx, req)
 	}
 }
+
+func signedBytes(version int, req interface{}) ([]byte, error) {
+	if m, ok := req.(proto.Message); ok && version != signing.V1 {
+		return proto.MarshalOptions{Deterministic: true}.Marshal(m)
+	}
+
+	return json.Marshal(req)
+}
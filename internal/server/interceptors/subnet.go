@@ -2,34 +2,41 @@ package interceptors
 
 import (
 	"context"
-	"net"
 
+	"github.com/Xacor/go-metrics/internal/server/ipfilter"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
 )
 
-func InitCheckSubnet(allowedSubnet *net.IPNet) grpc.UnaryServerInterceptor {
+// InitCheckSubnet определяет адрес клиента по адресу участника вызова и метаданным доверенных прокси,
+// сохраняет его в контексте и отклоняет вызовы с адресов, не прошедших правила доступа.
+func InitCheckSubnet(filter *ipfilter.Filter) grpc.UnaryServerInterceptor {
 	return func(ctx context.Context,
 		req interface{},
 		info *grpc.UnaryServerInfo,
 		handler grpc.UnaryHandler) (interface{}, error) {
 
-		if allowedSubnet == nil {
-			return handler(ctx, req)
+		var remoteAddr string
+		if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+			remoteAddr = p.Addr.String()
 		}
+		md, _ := metadata.FromIncomingContext(ctx)
 
-		md, ok := metadata.FromIncomingContext(ctx)
-		if !ok {
-			return nil, status.Error(codes.Internal, "unable to get request metadata")
+		ip, err := filter.ClientIP(remoteAddr, func(key string) string { return first(md, key) })
+		if err != nil {
+			if filter.Enabled() {
+				return nil, status.Error(codes.PermissionDenied, "unable to determine client address")
+			}
+			return handler(ctx, req)
 		}
 
-		cIP := net.ParseIP(md.Get("X-Real-IP")[0])
-		if allowedSubnet.Contains(cIP) {
-			return nil, status.Error(codes.PermissionDenied, "subnet not allowed")
+		if !filter.Allowed(ip) {
+			return nil, status.Error(codes.PermissionDenied, "client address not allowed")
 		}
 
-		return handler(ctx, req)
+		return handler(ipfilter.NewContext(ctx, ip), req)
 	}
 }
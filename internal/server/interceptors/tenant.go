@@ -0,0 +1,49 @@
+package interceptors
+
+import (
+	"context"
+
+	"github.com/Xacor/go-metrics/internal/server/auth"
+	"github.com/Xacor/go-metrics/internal/server/tenant"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// InitTenant определяет арендатора по метаданным запроса или по учётным данным
+// клиента, если они привязаны к арендатору, и добавляет его в контекст.
+// Без реестра арендаторов запросы пропускаются без изменений.
+func InitTenant(registry *tenant.Registry) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+
+		if registry == nil {
+			return handler(ctx, req)
+		}
+
+		var bound string
+		if id, ok := auth.FromContext(ctx); ok {
+			bound = id.Tenant
+		}
+
+		md, _ := metadata.FromIncomingContext(ctx)
+		t, err := registry.Resolve(bound, first(md, tenant.HeaderID), first(md, tenant.HeaderToken))
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+
+		return handler(tenant.NewContext(ctx, t), req)
+	}
+}
+
+// first возвращает первое значение ключа метаданных или пустую строку.
+func first(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
@@ -0,0 +1,165 @@
+// Модуль ipfilter определяет IP-адрес клиента и проверяет его по правилам доступа.
+//
+// Адрес клиента берётся из адреса соединения. Заголовки X-Forwarded-For и X-Real-IP
+// учитываются, только если соединение установлено с доверенного прокси.
+package ipfilter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Заголовки, в которых прокси передают адрес клиента. В gRPC используются
+// одноимённые ключи метаданных в нижнем регистре.
+const (
+	HeaderForwardedFor = "X-Forwarded-For"
+	HeaderRealIP       = "X-Real-IP"
+)
+
+var (
+	ErrInvalidCIDR = errors.New("invalid CIDR")
+	ErrNoAddress   = errors.New("unable to determine client address")
+)
+
+// Правила доступа по IP-адресам клиентов.
+type Filter struct {
+	allow   []*net.IPNet
+	deny    []*net.IPNet
+	proxies []*net.IPNet
+}
+
+// New() создаёт фильтр. Запрещающие правила deny имеют приоритет над разрешающими allow;
+// пустой список allow разрешает все адреса. Через proxies задаются доверенные прокси.
+// Правила задаются подсетями IPv4 и IPv6 или отдельными адресами.
+func New(allow, deny, proxies []string) (*Filter, error) {
+	var (
+		f   Filter
+		err error
+	)
+
+	if f.allow, err = ParseCIDRs(allow); err != nil {
+		return nil, err
+	}
+	if f.deny, err = ParseCIDRs(deny); err != nil {
+		return nil, err
+	}
+	if f.proxies, err = ParseCIDRs(proxies); err != nil {
+		return nil, err
+	}
+
+	return &f, nil
+}
+
+// ParseCIDRs() разбирает подсети. Адрес без маски означает подсеть из одного адреса.
+func ParseCIDRs(values []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(values))
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+
+		if !strings.Contains(v, "/") {
+			ip := net.ParseIP(v)
+			if ip == nil {
+				return nil, fmt.Errorf("%w: %q", ErrInvalidCIDR, v)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, n, err := net.ParseCIDR(v)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidCIDR, v)
+		}
+		nets = append(nets, n)
+	}
+
+	return nets, nil
+}
+
+func contains(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Enabled() сообщает, заданы ли правила доступа.
+func (f *Filter) Enabled() bool {
+	return len(f.allow) > 0 || len(f.deny) > 0
+}
+
+// Allowed() проверяет адрес клиента по правилам доступа.
+func (f *Filter) Allowed(ip net.IP) bool {
+	if ip == nil {
+		return !f.Enabled()
+	}
+	if contains(f.deny, ip) {
+		return false
+	}
+
+	return len(f.allow) == 0 || contains(f.allow, ip)
+}
+
+// ClientIP() определяет адрес клиента по адресу соединения remoteAddr и заголовкам,
+// которые возвращает header. Цепочка X-Forwarded-For просматривается справа налево
+// до первого адреса, не принадлежащего доверенному прокси.
+func (f *Filter) ClientIP(remoteAddr string, header func(key string) string) (net.IP, error) {
+	host := remoteAddr
+	if h, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		host = h
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil, fmt.Errorf("%w: %q", ErrNoAddress, remoteAddr)
+	}
+	if !contains(f.proxies, ip) {
+		return ip, nil
+	}
+
+	if forwarded := header(HeaderForwardedFor); forwarded != "" {
+		hops := strings.Split(forwarded, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := net.ParseIP(strings.TrimSpace(hops[i]))
+			if hop == nil {
+				return nil, fmt.Errorf("%w: invalid %s", ErrNoAddress, HeaderForwardedFor)
+			}
+			ip = hop
+			if !contains(f.proxies, hop) {
+				break
+			}
+		}
+		return ip, nil
+	}
+
+	if real := net.ParseIP(strings.TrimSpace(header(HeaderRealIP))); real != nil {
+		return real, nil
+	}
+
+	return ip, nil
+}
+
+type ctxKey struct{}
+
+// NewContext() возвращает контекст запроса клиента с адресом ip.
+func NewContext(ctx context.Context, ip net.IP) context.Context {
+	return context.WithValue(ctx, ctxKey{}, ip)
+}
+
+// FromContext() возвращает адрес клиента, выполняющего запрос.
+func FromContext(ctx context.Context) (net.IP, bool) {
+	ip, ok := ctx.Value(ctxKey{}).(net.IP)
+	return ip, ok && ip != nil
+}
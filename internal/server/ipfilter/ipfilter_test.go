@@ -0,0 +1,106 @@
+package ipfilter
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilter_Allowed(t *testing.T) {
+	f, err := New([]string{"10.0.0.0/8", "2001:db8::/32"}, []string{"10.0.0.13", "2001:db8:dead::/48"}, nil)
+	require.NoError(t, err)
+
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{ip: "10.1.2.3", want: true},
+		{ip: "10.0.0.13", want: false},
+		{ip: "192.168.0.1", want: false},
+		{ip: "2001:db8::1", want: true},
+		{ip: "2001:db8:dead::1", want: false},
+		{ip: "::ffff:10.1.2.3", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			assert.Equal(t, tt.want, f.Allowed(net.ParseIP(tt.ip)))
+		})
+	}
+
+	// без правил разрешены все адреса
+	open, err := New(nil, nil, nil)
+	require.NoError(t, err)
+	assert.True(t, open.Allowed(net.ParseIP("192.168.0.1")))
+}
+
+func TestFilter_ClientIP(t *testing.T) {
+	f, err := New(nil, nil, []string{"10.0.0.1", "fd00::/8"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		header     http.Header
+		want       string
+		wantErr    error
+	}{
+		{
+			name:       "untrusted_peer_ignores_headers",
+			remoteAddr: "203.0.113.7:5000",
+			header:     http.Header{"X-Real-Ip": {"10.1.1.1"}, "X-Forwarded-For": {"10.1.1.1"}},
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "trusted_proxy_forwarded_for",
+			remoteAddr: "10.0.0.1:5000",
+			header:     http.Header{"X-Forwarded-For": {"198.51.100.1, 203.0.113.9, 10.0.0.1"}},
+			want:       "203.0.113.9",
+		},
+		{
+			name:       "trusted_proxy_real_ip",
+			remoteAddr: "[fd00::5]:443",
+			header:     http.Header{"X-Real-Ip": {"2001:db8::7"}},
+			want:       "2001:db8::7",
+		},
+		{
+			name:       "trusted_proxy_without_headers",
+			remoteAddr: "10.0.0.1:5000",
+			header:     http.Header{},
+			want:       "10.0.0.1",
+		},
+		{
+			name:       "invalid_forwarded_for",
+			remoteAddr: "10.0.0.1:5000",
+			header:     http.Header{"X-Forwarded-For": {"garbage"}},
+			wantErr:    ErrNoAddress,
+		},
+		{
+			name:       "invalid_remote_addr",
+			remoteAddr: "",
+			header:     http.Header{},
+			wantErr:    ErrNoAddress,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ip, err := f.ClientIP(tt.remoteAddr, tt.header.Get)
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr), err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, ip.String())
+		})
+	}
+}
+
+func TestNew_InvalidCIDR(t *testing.T) {
+	_, err := New([]string{"10.0.0.0/33"}, nil, nil)
+	assert.True(t, errors.Is(err, ErrInvalidCIDR))
+}
@@ -0,0 +1,84 @@
+package limit
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Xacor/go-metrics/internal/server/auth"
+	"github.com/Xacor/go-metrics/internal/server/ipfilter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiter_Allow(t *testing.T) {
+	l := NewLimiter(2, 3)
+	now := time.Now()
+
+	for i := 0; i < 3; i++ {
+		_, err := l.Allow("agent-1", now)
+		require.NoError(t, err, "request %d fits burst", i)
+	}
+
+	delay, err := l.Allow("agent-1", now)
+	assert.True(t, errors.Is(err, ErrRateLimited))
+	assert.Equal(t, 500*time.Millisecond, delay)
+
+	_, err = l.Allow("agent-2", now)
+	assert.NoError(t, err, "clients have separate buckets")
+
+	_, err = l.Allow("agent-1", now.Add(500*time.Millisecond))
+	assert.NoError(t, err, "token is refilled after delay")
+
+	// состояние простаивающих клиентов удаляется
+	_, err = l.Allow("agent-3", now.Add(2*time.Minute))
+	require.NoError(t, err)
+	assert.Len(t, l.buckets, 1)
+
+	var disabled *Limiter
+	_, err = disabled.Allow("agent-1", now)
+	assert.NoError(t, err)
+}
+
+func TestShedder_Acquire(t *testing.T) {
+	s := NewShedder(4, 10*time.Millisecond)
+
+	releases := make([]func(), 0, 4)
+	for i := 0; i < 4; i++ {
+		release, err := s.Acquire()
+		require.NoError(t, err)
+		releases = append(releases, release)
+	}
+	_, err := s.Acquire()
+	assert.True(t, errors.Is(err, ErrOverloaded), "in flight limit reached")
+
+	time.Sleep(20 * time.Millisecond)
+	for _, release := range releases {
+		release()
+	}
+
+	// при высокой задержке допускается четверть от предела
+	release, err := s.Acquire()
+	require.NoError(t, err)
+	_, err = s.Acquire()
+	assert.True(t, errors.Is(err, ErrOverloaded), "latency limit reached")
+	release()
+
+	assert.Nil(t, NewShedder(0, 0))
+}
+
+func TestClient(t *testing.T) {
+	ctx := ipfilter.NewContext(context.Background(), net.ParseIP("10.0.0.1"))
+	assert.Equal(t, "ip:10.0.0.1", Client(ctx))
+
+	ctx = auth.NewContext(ctx, &auth.Identity{Subject: "agent-1"})
+	assert.Equal(t, "id:agent-1", Client(ctx))
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	assert.Equal(t, 1, RetryAfterSeconds(0))
+	assert.Equal(t, 1, RetryAfterSeconds(200*time.Millisecond))
+	assert.Equal(t, 3, RetryAfterSeconds(2100*time.Millisecond))
+}
@@ -0,0 +1,131 @@
+// Модуль limit защищает сервер от перегрузки: ограничивает частоту запросов
+// каждого клиента и отклоняет запросы, когда сервер не успевает их обрабатывать.
+package limit
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/Xacor/go-metrics/internal/server/auth"
+	"github.com/Xacor/go-metrics/internal/server/ipfilter"
+	"golang.org/x/time/rate"
+)
+
+// Заголовок HTTP-ответа с количеством секунд, через которое можно повторить запрос.
+// В gRPC передаётся одноимённый ключ метаданных трейлера в нижнем регистре.
+const HeaderRetryAfter = "Retry-After"
+
+var (
+	ErrRateLimited = errors.New("client rate limit exceeded")
+	ErrOverloaded  = errors.New("server is overloaded")
+)
+
+// Ограничение частоты запросов клиента.
+type bucket struct {
+	lastSeen time.Time
+	limiter  *rate.Limiter
+}
+
+// Ограничивает частоту запросов каждого клиента алгоритмом token bucket.
+type Limiter struct {
+	lastSweep time.Time
+	buckets   map[string]*bucket
+	limit     rate.Limit
+	burst     int
+	idle      time.Duration
+	mu        sync.Mutex
+}
+
+// NewLimiter() создаёт ограничитель, допускающий rps запросов в секунду от клиента
+// со всплеском до burst запросов. Возвращает nil, если rps не задан.
+func NewLimiter(rps float64, burst int) *Limiter {
+	if rps <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = int(math.Ceil(rps))
+	}
+
+	// за это время корзина клиента наполняется полностью, поэтому состояние
+	// простаивающего клиента можно удалить без изменения поведения
+	idle := time.Duration(float64(burst) / rps * float64(time.Second))
+	if idle < time.Minute {
+		idle = time.Minute
+	}
+
+	return &Limiter{
+		buckets: make(map[string]*bucket),
+		limit:   rate.Limit(rps),
+		burst:   burst,
+		idle:    idle,
+	}
+}
+
+// Allow() расходует токен клиента client. Если токенов нет, возвращает ErrRateLimited
+// и время, через которое появится следующий токен.
+func (l *Limiter) Allow(client string, now time.Time) (time.Duration, error) {
+	if l == nil {
+		return 0, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.buckets[client] = b
+	}
+	b.lastSeen = now
+
+	r := b.limiter.ReserveN(now, 1)
+	if delay := r.DelayFrom(now); delay > 0 {
+		r.CancelAt(now)
+		return delay, ErrRateLimited
+	}
+
+	return 0, nil
+}
+
+// sweep удаляет состояние клиентов, не обращавшихся к серверу дольше idle.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.idle {
+		return
+	}
+	l.lastSweep = now
+
+	for client, b := range l.buckets {
+		if now.Sub(b.lastSeen) > l.idle {
+			delete(l.buckets, client)
+		}
+	}
+}
+
+// Client() возвращает ключ клиента для ограничения частоты запросов:
+// субъект аутентифицированного клиента или его IP-адрес.
+func Client(ctx context.Context) string {
+	if id, ok := auth.FromContext(ctx); ok && id.Subject != "" {
+		return "id:" + id.Subject
+	}
+	if ip, ok := ipfilter.FromContext(ctx); ok {
+		return "ip:" + ip.String()
+	}
+
+	return ""
+}
+
+// RetryAfterSeconds() округляет задержку до целого числа секунд, не меньше одной,
+// как требует заголовок Retry-After.
+func RetryAfterSeconds(d time.Duration) int {
+	seconds := int(math.Ceil(d.Seconds()))
+	if seconds < 1 {
+		return 1
+	}
+
+	return seconds
+}
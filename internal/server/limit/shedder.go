@@ -0,0 +1,83 @@
+package limit
+
+import (
+	"sync"
+	"time"
+)
+
+// Вес последнего запроса в скользящем среднем задержки.
+const latencyWeight = 0.2
+
+// Время, через которое клиенту предлагается повторить отклонённый из-за перегрузки запрос.
+const ShedRetryAfter = time.Second
+
+// Отклоняет запросы при перегрузке сервера: когда число одновременно обрабатываемых
+// запросов достигло предела или когда растёт задержка обработки, например при
+// замедлении хранилища.
+type Shedder struct {
+	maxInFlight int
+	maxLatency  time.Duration
+	inFlight    int
+	// скользящее среднее задержки обработки запроса
+	latency time.Duration
+	mu      sync.Mutex
+}
+
+// NewShedder() создаёт ограничитель нагрузки. Одновременно обрабатывается не более
+// maxInFlight запросов; пока средняя задержка обработки выше maxLatency, предел
+// снижается до четверти, чтобы хранилище успело разгрузиться. Нулевые значения
+// отключают соответствующую проверку. Возвращает nil, если обе проверки отключены.
+func NewShedder(maxInFlight int, maxLatency time.Duration) *Shedder {
+	if maxInFlight <= 0 && maxLatency <= 0 {
+		return nil
+	}
+
+	return &Shedder{maxInFlight: maxInFlight, maxLatency: maxLatency}
+}
+
+// Acquire() допускает запрос к обработке. После обработки нужно вызвать
+// возвращённую функцию, которая учитывает задержку запроса.
+// При перегрузке возвращает ErrOverloaded.
+func (s *Shedder) Acquire() (func(), error) {
+	if s == nil {
+		return func() {}, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if limit := s.limit(); limit > 0 && s.inFlight >= limit {
+		return nil, ErrOverloaded
+	}
+	s.inFlight++
+
+	start := time.Now()
+	return func() { s.release(time.Since(start)) }, nil
+}
+
+// limit возвращает текущий предел одновременных запросов, 0 — без ограничений.
+func (s *Shedder) limit() int {
+	if s.maxLatency <= 0 || s.latency <= s.maxLatency {
+		return s.maxInFlight
+	}
+
+	// при высокой задержке запросы продолжают поступать по одному,
+	// чтобы среднее обновлялось и ограничение снималось после восстановления
+	if limit := s.maxInFlight / 4; limit > 1 {
+		return limit
+	}
+
+	return 1
+}
+
+func (s *Shedder) release(elapsed time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.inFlight--
+	if s.latency == 0 {
+		s.latency = elapsed
+		return
+	}
+	s.latency += time.Duration(latencyWeight * float64(elapsed-s.latency))
+}
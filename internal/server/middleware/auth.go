@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"crypto/x509"
+	"net/http"
+	"strings"
+
+	"github.com/Xacor/go-metrics/internal/logger"
+	"github.com/Xacor/go-metrics/internal/server/auth"
+	"go.uber.org/zap"
+)
+
+// Область доступа, необходимая для запроса. Маршруты проверяются по порядку,
+// подходит первый, у которого совпадают метод и префикс пути.
+type routeScope struct {
+	method string
+	prefix string
+	scope  auth.Scope
+	// маршрут доступен без аутентификации
+	public bool
+}
+
+var routeScopes = []routeScope{
+	{method: http.MethodGet, prefix: "/ping", public: true},
+	{prefix: "/admin/", scope: auth.ScopeAdmin},
+	{prefix: "/debug/", scope: auth.ScopeAdmin},
+	{method: http.MethodPost, prefix: "/update/", scope: auth.ScopeWrite},
+	{method: http.MethodPost, prefix: "/updates/", scope: auth.ScopeWrite},
+	{prefix: "/value/", scope: auth.ScopeRead},
+	{method: http.MethodGet, prefix: "/history/", scope: auth.ScopeRead},
+}
+
+// scopeFor возвращает область доступа, необходимую для запроса.
+// Запросы к неизвестным маршрутам требуют прав администратора.
+func scopeFor(r *http.Request) routeScope {
+	if r.Method == http.MethodGet && r.URL.Path == "/" {
+		return routeScope{scope: auth.ScopeRead}
+	}
+
+	for _, rs := range routeScopes {
+		if (rs.method == "" || rs.method == r.Method) && strings.HasPrefix(r.URL.Path, rs.prefix) {
+			return rs
+		}
+	}
+
+	return routeScope{scope: auth.ScopeAdmin}
+}
+
+// WithAuth проверяет учётные данные клиента и его права на запрошенный маршрут.
+func WithAuth(a *auth.Authenticator) func(next http.Handler) http.Handler {
+	l := logger.Get()
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rs := scopeFor(r)
+			if rs.public {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var cert *x509.Certificate
+			if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+				cert = r.TLS.VerifiedChains[0][0]
+			}
+
+			id, err := a.Authenticate(r.Header.Get(auth.HeaderAuthorization), r.Header.Get(auth.HeaderAPIKey), cert)
+			if err != nil {
+				l.Warn("authentication failed", zap.Error(err), zap.String("path", r.URL.Path))
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			if !id.Allows(rs.scope) {
+				l.Warn("access denied", zap.String("subject", id.Subject), zap.String("path", r.URL.Path))
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), id)))
+		})
+	}
+}
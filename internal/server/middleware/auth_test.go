@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Xacor/go-metrics/internal/server/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithAuth(t *testing.T) {
+	a := auth.NewAuthenticator(nil, []byte("secret"), nil)
+
+	issue := func(scopes ...auth.Scope) string {
+		token, err := a.IssueJWT("client", "", scopes, time.Minute)
+		require.NoError(t, err)
+		return "Bearer " + token
+	}
+	reader := issue(auth.ScopeRead)
+	writer := issue(auth.ScopeWrite)
+	admin := issue(auth.ScopeAdmin)
+
+	tests := []struct {
+		name          string
+		method        string
+		path          string
+		authorization string
+		want          int
+	}{
+		{name: "public_ping", method: http.MethodGet, path: "/ping", want: http.StatusOK},
+		{name: "no_credentials", method: http.MethodGet, path: "/", want: http.StatusUnauthorized},
+		{name: "read_all", method: http.MethodGet, path: "/", authorization: reader, want: http.StatusOK},
+		{name: "read_value", method: http.MethodPost, path: "/value/", authorization: reader, want: http.StatusOK},
+		{name: "reader_updates", method: http.MethodPost, path: "/updates/", authorization: reader, want: http.StatusForbidden},
+		{name: "writer_updates", method: http.MethodPost, path: "/update/counter/c/1", authorization: writer, want: http.StatusOK},
+		{name: "writer_reads", method: http.MethodGet, path: "/value/counter/c", authorization: writer, want: http.StatusForbidden},
+		{name: "writer_admin", method: http.MethodGet, path: "/admin/keys", authorization: writer, want: http.StatusForbidden},
+		{name: "admin_keys", method: http.MethodGet, path: "/admin/keys", authorization: admin, want: http.StatusOK},
+		{name: "admin_reads", method: http.MethodGet, path: "/history/c", authorization: admin, want: http.StatusOK},
+		{name: "unknown_route", method: http.MethodGet, path: "/unknown", authorization: reader, want: http.StatusForbidden},
+	}
+
+	handler := WithAuth(a)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.authorization != "" {
+				r.Header.Set(auth.HeaderAuthorization, tt.authorization)
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, r)
+
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
@@ -2,60 +2,50 @@ package middleware
 
 import (
 	"bytes"
-	"crypto"
-	"crypto/rsa"
 	"io"
 	"net/http"
 
+	"github.com/Xacor/go-metrics/internal/envelope"
 	"github.com/Xacor/go-metrics/internal/logger"
 	"go.uber.org/zap"
 )
 
-type decryptReader struct {
-	r  io.ReadCloser
-	br *bytes.Reader
-}
-
-func newDecryptReader(r io.ReadCloser, b []byte) *decryptReader {
-	return &decryptReader{
-		r:  r,
-		br: bytes.NewReader(b),
-	}
-}
-
-func (c *decryptReader) Read(p []byte) (n int, err error) {
-	return c.br.Read(p)
-}
-
-func (c *decryptReader) Close() error {
-	if err := c.r.Close(); err != nil {
-		return err
-	}
-	return nil
-}
-
-func WithRsaDecrypt(key *rsa.PrivateKey) func(next http.Handler) http.Handler {
+// WithDecrypt расшифровывает тело запроса, если агент передал его в конверте.
+// Если requireEnvelope, незашифрованные запросы с телом отклоняются, кроме
+// запросов к /admin/, иначе пропускаются без изменений.
+func WithDecrypt(keys *envelope.Keys, requireEnvelope bool) func(next http.Handler) http.Handler {
 	l := logger.Get()
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			sendsEncrypted := key != nil
-			if sendsEncrypted {
-				var body []byte
-				if _, err := r.Body.Read(body); err != nil {
-					return
-				}
+			if keys == nil || r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
 
-				decryptedBytes, err := key.Decrypt(nil, body, &rsa.OAEPOptions{Hash: crypto.SHA256})
+			data, err := io.ReadAll(r.Body)
+			if err != nil {
+				l.Error("unable to read body", zap.Error(err))
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			if envelope.IsEnvelope(data) {
+				data, err = keys.Open(data)
 				if err != nil {
-					l.Error("unable to configure decrypt body", zap.Error(err))
+					l.Warn("unable to decrypt body", zap.Error(err))
+					w.WriteHeader(http.StatusBadRequest)
 					return
 				}
-
-				r.Body = newDecryptReader(r.Body, decryptedBytes)
+			} else if requireEnvelope && len(data) > 0 && !isAdmin(r) {
+				l.Warn("unencrypted body rejected", zap.String("path", r.URL.Path))
+				http.Error(w, "request body must be encrypted", http.StatusBadRequest)
+				return
 			}
 
+			r.Body = io.NopCloser(bytes.NewReader(data))
+			r.ContentLength = int64(len(data))
+
 			next.ServeHTTP(w, r)
 		})
 	}
-
 }
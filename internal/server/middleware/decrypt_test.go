@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Xacor/go-metrics/internal/envelope"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithDecrypt(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	keys := envelope.NewKeys(map[string]*rsa.PrivateKey{"": key})
+
+	body := []byte(`[{"id":"c","type":"counter","delta":1}]`)
+	sealed, err := envelope.Seal(&key.PublicKey, "", body)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		required bool
+		path     string
+		body     []byte
+		want     int
+	}{
+		{name: "sealed", required: true, path: "/updates/", body: sealed, want: http.StatusOK},
+		{name: "plaintext", required: true, path: "/updates/", body: body, want: http.StatusBadRequest},
+		{name: "plaintext_allowed", required: false, path: "/updates/", body: body, want: http.StatusOK},
+		{name: "admin", required: true, path: "/admin/keys", body: body, want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received []byte
+			handler := WithDecrypt(keys, tt.required)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				received, _ = io.ReadAll(r.Body)
+			}))
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, tt.path, bytes.NewReader(tt.body)))
+			assert.Equal(t, tt.want, w.Code)
+			if tt.want == http.StatusOK {
+				assert.Equal(t, body, received)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Xacor/go-metrics/internal/logger"
+	"github.com/Xacor/go-metrics/internal/server/limit"
+	"go.uber.org/zap"
+)
+
+// WithRateLimit ограничивает частоту запросов клиента и отклоняет запросы при перегрузке
+// сервера, отвечая 429 с заголовком Retry-After. Запросы GET и HEAD не изменяют метрики
+// и не ограничиваются. Клиент определяется после аутентификации и проверки адреса,
+// поэтому middleware подключается после WithAuth и WithCheckSubnet.
+func WithRateLimit(limiter *limit.Limiter, shedder *limit.Shedder) func(next http.Handler) http.Handler {
+	l := logger.Get()
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			client := limit.Client(r.Context())
+			if delay, err := limiter.Allow(client, time.Now()); err != nil {
+				l.Debug("request rejected", zap.Error(err), zap.String("client", client))
+				tooManyRequests(w, delay)
+				return
+			}
+
+			release, err := shedder.Acquire()
+			if err != nil {
+				l.Warn("request rejected", zap.Error(err), zap.String("client", client))
+				tooManyRequests(w, limit.ShedRetryAfter)
+				return
+			}
+			defer release()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func tooManyRequests(w http.ResponseWriter, retryAfter time.Duration) {
+	w.Header().Set(limit.HeaderRetryAfter, strconv.Itoa(limit.RetryAfterSeconds(retryAfter)))
+	w.WriteHeader(http.StatusTooManyRequests)
+}
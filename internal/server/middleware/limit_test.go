@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Xacor/go-metrics/internal/server/limit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithRateLimit(t *testing.T) {
+	handler := WithRateLimit(limit.NewLimiter(1, 1), nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	send := func(method string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/updates/", nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, send(http.MethodPost).Code)
+
+	w := send(http.MethodPost)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get(limit.HeaderRetryAfter))
+
+	assert.Equal(t, http.StatusOK, send(http.MethodGet).Code, "safe method")
+}
@@ -1,46 +1,51 @@
 package middleware
 
 import (
-	"net"
-
+	"github.com/Xacor/go-metrics/internal/server/auth"
 	"github.com/Xacor/go-metrics/internal/server/config"
+	"github.com/Xacor/go-metrics/internal/server/limit"
+	"github.com/Xacor/go-metrics/internal/server/tenant"
+	"github.com/Xacor/go-metrics/internal/signing"
 	"github.com/go-chi/chi/v5"
 	chimiddleware "github.com/go-chi/chi/v5/middleware"
 )
 
-func RegisterMiddlewares(r *chi.Mux, cfg *config.Config) (chi.Middlewares, error) {
-	var signKey string
-	if cfg.KeyFile != "" {
-		key, err := cfg.GetKey()
-		if err != nil {
-			return nil, err
-		}
-		signKey = key
-	}
-
+func RegisterMiddlewares(r *chi.Mux, cfg *config.Config, authenticator *auth.Authenticator, keyring *signing.Keyring, verifier *signing.Verifier, limiter *limit.Limiter, shedder *limit.Shedder) (chi.Middlewares, error) {
 	r.Use(WithLogging)
 
-	if cfg.TrustedSubnet != "" {
-		_, trustedNet, err := net.ParseCIDR(cfg.TrustedSubnet)
-		if err != nil {
-			return nil, err
-		}
-		r.Use(WithCheckSubnet(trustedNet))
+	filter, err := cfg.GetIPFilter()
+	if err != nil {
+		return nil, err
 	}
+	r.Use(WithCheckSubnet(filter))
 
-	r.Use(WithCheckSignature(signKey))
-	r.Use(WithCompressRead)
+	if authenticator != nil {
+		r.Use(WithAuth(authenticator))
+	}
+
+	r.Use(WithRateLimit(limiter, shedder))
 
-	if cfg.CryptoKeyPrivateFile != "" {
-		pkey, err := cfg.GetPrivateKey()
+	if cfg.TenantsFile != "" {
+		registry, err := tenant.LoadRegistry(cfg.TenantsFile)
 		if err != nil {
 			return nil, err
 		}
-		r.Use(WithRsaDecrypt(pkey))
+		r.Use(WithTenant(registry))
 	}
 
+	// Агент сжимает тело, затем шифрует его, а подписывает исходные данные,
+	// поэтому сервер расшифровывает, распаковывает и только затем проверяет подпись.
+	keys, err := cfg.GetPrivateKeys()
+	if err != nil {
+		return nil, err
+	}
+	r.Use(WithDecrypt(keys, cfg.RequireEnvelope()))
+
+	r.Use(WithCompressRead)
+	r.Use(WithCheckSignature(verifier))
+
 	r.Use(WithCompressWrite)
-	r.Use(WithSignature(signKey))
+	r.Use(WithSignature(keyring))
 	r.Use(chimiddleware.Recoverer)
 
 	r.Mount("/debug", chimiddleware.Profiler())
@@ -1,45 +1,44 @@
 package middleware
 
 import (
-	"crypto/hmac"
-	"crypto/sha256"
+	"bytes"
+	"io"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/Xacor/go-metrics/internal/logger"
+	"github.com/Xacor/go-metrics/internal/signing"
 	"go.uber.org/zap"
 )
 
-func WithCheckSignature(key string) func(next http.Handler) http.Handler {
+// WithCheckSignature проверяет подпись тела запроса. Запросы GET и HEAD не изменяют
+// метрики и пропускаются без проверки. Запросы к API администратора подписывает
+// не агент, их права проверяет WithAuth. Тело проверяется после расшифровки и распаковки,
+// поэтому middleware подключается после WithDecrypt и WithCompressRead.
+func WithCheckSignature(v *signing.Verifier) func(next http.Handler) http.Handler {
 	l := logger.Get()
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if key == "" {
+			if v == nil || r.Method == http.MethodGet || r.Method == http.MethodHead || isAdmin(r) {
 				next.ServeHTTP(w, r)
 				return
 			}
 
-			data := make([]byte, r.ContentLength)
-
-			_, err := r.Body.Read(data)
+			data, err := io.ReadAll(r.Body)
 			if err != nil {
 				l.Error("WithCheckSignature", zap.Error(err))
 				w.WriteHeader(http.StatusInternalServerError)
 				return
 			}
+			r.Body = io.NopCloser(bytes.NewReader(data))
 
-			h := hmac.New(sha256.New, []byte(key))
-			_, err = h.Write(data)
-			if err != nil {
-				l.Error("WithCheckSignature", zap.Error(err))
-				w.WriteHeader(http.StatusInternalServerError)
-				return
+			sign, err := signing.FromHeader(r.Header)
+			if err == nil {
+				err = v.Verify(sign, data)
 			}
-
-			csign := []byte(r.Header.Get("HashSHA256"))
-			ssign := h.Sum(nil)
-
-			if !hmac.Equal(csign, ssign) {
-				l.Warn("signatures are't equal", zap.ByteString("client sign", csign))
+			if err != nil {
+				l.Warn("signature rejected", zap.Error(err), zap.String("client sign", sign.Value))
 				w.WriteHeader(http.StatusBadRequest)
 				return
 			}
@@ -49,13 +48,21 @@ func WithCheckSignature(key string) func(next http.Handler) http.Handler {
 	}
 }
 
+// isAdmin сообщает, относится ли запрос к API администратора.
+func isAdmin(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, "/admin/")
+}
+
+// signWriter накапливает ответ, чтобы записать подпись тела в заголовок до отправки.
 type signWriter struct {
-	w   http.ResponseWriter
-	key []byte
+	w      http.ResponseWriter
+	key    signing.Key
+	buf    bytes.Buffer
+	status int
 }
 
-func newSignWriter(w http.ResponseWriter) *signWriter {
-	return &signWriter{w: w}
+func newSignWriter(w http.ResponseWriter, key signing.Key) *signWriter {
+	return &signWriter{w: w, key: key}
 }
 
 func (s *signWriter) Header() http.Header {
@@ -63,32 +70,51 @@ func (s *signWriter) Header() http.Header {
 }
 
 func (s *signWriter) Write(body []byte) (int, error) {
-	h := hmac.New(sha256.New, s.key)
-	n, err := h.Write(body)
-	if err != nil {
-		return 0, err
-	}
-
-	sign := h.Sum(nil)
-	s.w.Header().Set("HashSHA256", string(sign))
-
-	return n, nil
+	return s.buf.Write(body)
 }
 
 func (s *signWriter) WriteHeader(statusCode int) {
-	s.w.WriteHeader(statusCode)
+	if s.status == 0 {
+		s.status = statusCode
+	}
 }
 
-func WithSignature(key string) func(next http.Handler) http.Handler {
+func (s *signWriter) flush() error {
+	sign := signing.SignV1([]byte(s.key.Secret), s.buf.Bytes())
+	sign.KeyID = s.key.ID
+	sign.SetHeader(s.w.Header())
+	if s.status != 0 {
+		s.w.WriteHeader(s.status)
+	}
+
+	_, err := s.w.Write(s.buf.Bytes())
+	return err
+}
+
+// WithSignature подписывает тело ответа действующим ключом из keyring и передаёт
+// идентификатор ключа в заголовке HeaderKeyID. Без keyring ответы не подписываются.
+func WithSignature(keyring *signing.Keyring) func(next http.Handler) http.Handler {
+	l := logger.Get()
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			ow := w
-			if key != "" {
-				sw := newSignWriter(w)
-				ow = sw
+			if keyring == nil {
+				next.ServeHTTP(w, r)
+				return
 			}
 
-			next.ServeHTTP(ow, r)
+			key, err := keyring.SigningKey(time.Now())
+			if err != nil {
+				l.Error("WithSignature", zap.Error(err))
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			sw := newSignWriter(w, key)
+			next.ServeHTTP(sw, r)
+
+			if err := sw.flush(); err != nil {
+				l.Error("WithSignature", zap.Error(err))
+			}
 		})
 	}
 }
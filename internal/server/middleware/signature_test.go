@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Xacor/go-metrics/internal/signing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithCheckSignature(t *testing.T) {
+	key := []byte("secret")
+	body := []byte(`[{"id":"c","type":"counter","delta":1}]`)
+
+	sign, err := signing.Sign(key, body)
+	require.NoError(t, err)
+
+	keyring, err := signing.NewKeyring([]signing.Key{{Secret: string(key)}})
+	require.NoError(t, err)
+
+	var received []byte
+	handler := WithCheckSignature(signing.NewVerifier(keyring, time.Minute, 10, signing.V1))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received, _ = io.ReadAll(r.Body)
+		}))
+
+	send := func(method, path string, sign *signing.Signature) int {
+		r := httptest.NewRequest(method, path, bytes.NewReader(body))
+		if sign != nil {
+			sign.SetHeader(r.Header)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, send(http.MethodPost, "/updates/", &sign))
+	assert.Equal(t, body, received, "handler must receive the verified body")
+
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodPost, "/updates/", &sign), "replayed request")
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodPost, "/updates/", nil), "unsigned request")
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/updates/", nil), "safe method")
+	assert.Equal(t, http.StatusOK, send(http.MethodPost, "/admin/keys", nil), "admin api")
+}
+
+func TestWithSignature(t *testing.T) {
+	retireAt := time.Now().Add(time.Hour)
+	keyring, err := signing.NewKeyring([]signing.Key{
+		{ID: "old", Secret: "old-secret", RetireAt: &retireAt},
+		{ID: "current", Secret: "secret"},
+	})
+	require.NoError(t, err)
+
+	handler := WithSignature(keyring)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("first "))
+		w.Write([]byte("second"))
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "first second", w.Body.String())
+	assert.Equal(t, signing.SignV1([]byte("secret"), []byte("first second")).Value, w.Header().Get(signing.HeaderSignature))
+	assert.Equal(t, "current", w.Header().Get(signing.HeaderKeyID))
+}
@@ -1,20 +1,37 @@
 package middleware
 
 import (
-	"net"
 	"net/http"
+
+	"github.com/Xacor/go-metrics/internal/logger"
+	"github.com/Xacor/go-metrics/internal/server/ipfilter"
+	"go.uber.org/zap"
 )
 
-func WithCheckSubnet(trustedNet *net.IPNet) func(next http.Handler) http.Handler {
+// WithCheckSubnet определяет адрес клиента по адресу соединения и заголовкам доверенных прокси,
+// сохраняет его в контексте запроса и отклоняет запросы с адресов, не прошедших правила доступа.
+func WithCheckSubnet(filter *ipfilter.Filter) func(next http.Handler) http.Handler {
+	l := logger.Get()
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			cIP := net.ParseIP(r.Header.Get("X-Real-IP"))
-			if !trustedNet.Contains(cIP) {
+			ip, err := filter.ClientIP(r.RemoteAddr, r.Header.Get)
+			if err != nil {
+				l.Debug("unable to determine client address", zap.Error(err))
+				if filter.Enabled() {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !filter.Allowed(ip) {
+				l.Debug("client address not allowed", zap.String("ip", ip.String()))
 				w.WriteHeader(http.StatusForbidden)
 				return
 			}
-			next.ServeHTTP(w, r)
+
+			next.ServeHTTP(w, r.WithContext(ipfilter.NewContext(r.Context(), ip)))
 		})
 	}
-
 }
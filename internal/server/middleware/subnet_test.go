@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Xacor/go-metrics/internal/server/ipfilter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithCheckSubnet(t *testing.T) {
+	filter, err := ipfilter.New([]string{"192.168.1.0/24"}, nil, []string{"10.0.0.1"})
+	require.NoError(t, err)
+
+	var client net.IP
+	handler := WithCheckSubnet(filter)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client, _ = ipfilter.FromContext(r.Context())
+	}))
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		realIP     string
+		wantCode   int
+		wantIP     string
+	}{
+		{name: "allowed_peer", remoteAddr: "192.168.1.10:4000", wantCode: http.StatusOK, wantIP: "192.168.1.10"},
+		{name: "spoofed_header", remoteAddr: "203.0.113.1:4000", realIP: "192.168.1.10", wantCode: http.StatusForbidden},
+		{name: "trusted_proxy", remoteAddr: "10.0.0.1:4000", realIP: "192.168.1.20", wantCode: http.StatusOK, wantIP: "192.168.1.20"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client = nil
+			r := httptest.NewRequest(http.MethodPost, "/update/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.realIP != "" {
+				r.Header.Set(ipfilter.HeaderRealIP, tt.realIP)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+			if tt.wantIP != "" {
+				assert.Equal(t, tt.wantIP, client.String())
+			}
+		})
+	}
+}
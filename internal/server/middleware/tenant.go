@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/Xacor/go-metrics/internal/logger"
+	"github.com/Xacor/go-metrics/internal/server/auth"
+	"github.com/Xacor/go-metrics/internal/server/tenant"
+	"go.uber.org/zap"
+)
+
+// Пути, доступные без указания арендатора.
+var tenantFreePaths = []string{"/ping", "/debug/", "/admin/"}
+
+// WithTenant определяет арендатора по заголовкам запроса или по учётным данным
+// клиента, если они привязаны к арендатору, и добавляет его в контекст.
+func WithTenant(registry *tenant.Registry) func(next http.Handler) http.Handler {
+	l := logger.Get()
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, path := range tenantFreePaths {
+				if strings.HasPrefix(r.URL.Path, path) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			var bound string
+			if id, ok := auth.FromContext(r.Context()); ok {
+				bound = id.Tenant
+			}
+
+			t, err := registry.Resolve(bound, r.Header.Get(tenant.HeaderID), r.Header.Get(tenant.HeaderToken))
+			if err != nil {
+				l.Warn("tenant authentication failed", zap.Error(err), zap.String("path", r.URL.Path))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(tenant.NewContext(r.Context(), t)))
+		})
+	}
+}
@@ -1,6 +1,8 @@
 // Модуль описывает используемые модели данных.
 package model
 
+import "time"
+
 // Возможные типы метрик.
 const (
 	TypeCounter = "counter"
@@ -14,3 +16,29 @@ type Metrics struct {
 	Name  string   `json:"id"`
 	MType string   `json:"type"`
 }
+
+// Разрешения, с которыми хранится история значений метрик.
+const (
+	ResolutionRaw    = "raw"
+	ResolutionMinute = "1m"
+	ResolutionHour   = "1h"
+)
+
+// Значение метрики за интервал времени. Для gauge заполняются Min, Max, Avg и Last,
+// для counter — Delta, сумма приращений за интервал.
+type Sample struct {
+	Time  time.Time `json:"time"`
+	Min   *float64  `json:"min,omitempty"`
+	Max   *float64  `json:"max,omitempty"`
+	Avg   *float64  `json:"avg,omitempty"`
+	Last  *float64  `json:"last,omitempty"`
+	Delta *int64    `json:"delta,omitempty"`
+	Count int64     `json:"count"`
+}
+
+// История значений метрики за период.
+type History struct {
+	Name       string   `json:"id"`
+	Resolution string   `json:"resolution"`
+	Samples    []Sample `json:"samples"`
+}
@@ -0,0 +1,189 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Xacor/go-metrics/internal/logger"
+	"github.com/Xacor/go-metrics/internal/server/audit"
+	"github.com/Xacor/go-metrics/internal/server/model"
+	"go.uber.org/zap"
+)
+
+// Реализует интерфейс Storage, записывая в журнал аудита каждое изменение
+// метрик вместе с предыдущим и новым значением.
+type AuditStorage struct {
+	repo Storage
+	log  *audit.Log
+}
+
+func NewAuditStorage(repo Storage, log *audit.Log) *AuditStorage {
+	return &AuditStorage{repo: repo, log: log}
+}
+
+func (s *AuditStorage) Ping(ctx context.Context) error {
+	return s.repo.Ping(ctx)
+}
+
+func (s *AuditStorage) All(ctx context.Context) ([]model.Metrics, error) {
+	return s.repo.All(ctx)
+}
+
+func (s *AuditStorage) Get(ctx context.Context, name string) (model.Metrics, error) {
+	return s.repo.Get(ctx, name)
+}
+
+func (s *AuditStorage) History(ctx context.Context, name string, from, to time.Time) (model.History, error) {
+	repo, ok := s.repo.(HistoryRepo)
+	if !ok {
+		return model.History{}, ErrHistoryUnsupported
+	}
+
+	return repo.History(ctx, name, from, to)
+}
+
+func (s *AuditStorage) Create(ctx context.Context, metric model.Metrics) (model.Metrics, error) {
+	return s.write(ctx, audit.OpMetricCreate, metric, s.repo.Create)
+}
+
+func (s *AuditStorage) Update(ctx context.Context, metric model.Metrics) (model.Metrics, error) {
+	return s.write(ctx, audit.OpMetricUpdate, metric, s.repo.Update)
+}
+
+func (s *AuditStorage) write(ctx context.Context, op string, metric model.Metrics,
+	fn func(context.Context, model.Metrics) (model.Metrics, error)) (model.Metrics, error) {
+	before, known := s.current(ctx, metric.Name)
+
+	result, err := fn(ctx, metric)
+	if err != nil {
+		return model.Metrics{}, err
+	}
+
+	after := result
+	s.record(ctx, audit.Event{
+		Operation: op,
+		Changes:   []audit.Change{{Name: metric.Name, Before: before, After: &after, BeforeUnknown: !known}},
+	})
+
+	return result, nil
+}
+
+// current возвращает текущее значение метрики или nil, если её ещё нет.
+// Если значение прочитать не удалось, known равно false: запись при этом
+// не прерывается, чтобы при недоступности БД она попала в буфер
+// деградированного режима.
+func (s *AuditStorage) current(ctx context.Context, name string) (m *model.Metrics, known bool) {
+	if s.Degraded() {
+		return nil, false
+	}
+
+	stored, err := s.repo.Get(ctx, name)
+	if errors.Is(err, ErrMetricNotFound) {
+		return nil, true
+	}
+	if err != nil {
+		logger.Get().Warn("unable to read metric before write", zap.Error(err), zap.String("name", name))
+		return nil, false
+	}
+
+	return &stored, true
+}
+
+func (s *AuditStorage) UpdateBatch(ctx context.Context, metrics []model.Metrics) error {
+	before := s.batchBefore(ctx, metrics)
+
+	if err := s.repo.UpdateBatch(ctx, metrics); err != nil {
+		return err
+	}
+
+	s.record(ctx, audit.Event{
+		Operation: audit.OpMetricBatch,
+		Changes:   batchChanges(before, metrics),
+	})
+
+	return nil
+}
+
+// batchBefore читает значения метрик пачки до записи одним запросом; для метрик,
+// которых ещё нет, значение nil. Если прочитать значения не удалось, возвращает
+// nil: предыдущие значения всех метрик пачки неизвестны.
+func (s *AuditStorage) batchBefore(ctx context.Context, metrics []model.Metrics) map[string]*model.Metrics {
+	if s.Degraded() {
+		return nil
+	}
+
+	before := make(map[string]*model.Metrics, len(metrics))
+	names := make([]string, 0, len(metrics))
+	for _, m := range metrics {
+		if _, ok := before[m.Name]; !ok {
+			before[m.Name] = nil
+			names = append(names, m.Name)
+		}
+	}
+
+	stored, err := getBatch(ctx, s.repo, names)
+	if err != nil {
+		logger.Get().Warn("unable to read metrics before write", zap.Error(err), zap.Int("count", len(names)))
+		return nil
+	}
+	for i := range stored {
+		before[stored[i].Name] = &stored[i]
+	}
+
+	return before
+}
+
+// batchChanges вычисляет изменения значений метрик после записи пачки:
+// приращения counter суммируются, значения gauge заменяются.
+func batchChanges(before map[string]*model.Metrics, metrics []model.Metrics) []audit.Change {
+	index := make(map[string]int, len(metrics))
+	changes := make([]audit.Change, 0, len(metrics))
+	for _, m := range metrics {
+		i, ok := index[m.Name]
+		if !ok {
+			i = len(changes)
+			index[m.Name] = i
+			prev, known := before[m.Name]
+			changes = append(changes, audit.Change{Name: m.Name, Before: prev, BeforeUnknown: !known})
+		}
+
+		after := apply(changes[i].After, changes[i].Before, m)
+		changes[i].After = &after
+	}
+
+	return changes
+}
+
+// apply возвращает значение метрики после записи m поверх last или,
+// если метрика ещё не записывалась в пачке, поверх before.
+func apply(last, before *model.Metrics, m model.Metrics) model.Metrics {
+	base := last
+	if base == nil {
+		base = before
+	}
+	if m.MType != model.TypeCounter || m.Delta == nil || base == nil || base.Delta == nil {
+		return m
+	}
+
+	delta := *base.Delta + *m.Delta
+	m.Delta = &delta
+
+	return m
+}
+
+func (s *AuditStorage) record(ctx context.Context, e audit.Event) {
+	if err := s.log.Record(ctx, e); err != nil {
+		logger.Get().Error("unable to write audit event", zap.Error(err), zap.String("operation", e.Operation))
+	}
+}
+
+func (s *AuditStorage) Close() error {
+	return s.repo.Close()
+}
+
+// Degraded() сообщает, работает ли хранилище в деградированном режиме.
+func (s *AuditStorage) Degraded() bool {
+	d, ok := s.repo.(Degrader)
+	return ok && d.Degraded()
+}
@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Xacor/go-metrics/internal/server/audit"
+	"github.com/Xacor/go-metrics/internal/server/auth"
+	"github.com/Xacor/go-metrics/internal/server/ipfilter"
+	"github.com/Xacor/go-metrics/internal/server/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestAuditStorage(t *testing.T) {
+	log, err := audit.Open(audit.Config{Path: filepath.Join(t.TempDir(), "audit.log")})
+	require.NoError(t, err)
+	defer log.Close()
+
+	s := NewAuditStorage(newTestMemStorage(), log)
+	ctx := ipfilter.NewContext(auth.NewContext(context.Background(), &auth.Identity{Subject: "agent-1"}), net.ParseIP("10.0.0.5"))
+
+	_, err = s.Create(ctx, counter("requests", 2))
+	require.NoError(t, err)
+
+	value := 1.5
+	gauge := model.Metrics{Name: "load", MType: model.TypeGauge, Value: &value}
+	require.NoError(t, s.UpdateBatch(ctx, []model.Metrics{counter("requests", 3), gauge, counter("requests", 4)}))
+
+	events, err := log.Query(audit.Query{})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+
+	created := events[0]
+	assert.Equal(t, audit.OpMetricCreate, created.Operation)
+	assert.Equal(t, "agent-1", created.Actor)
+	assert.Equal(t, "10.0.0.5", created.Source)
+	require.Len(t, created.Changes, 1)
+	assert.Nil(t, created.Changes[0].Before)
+	assert.Equal(t, int64(2), *created.Changes[0].After.Delta)
+
+	batch := events[1]
+	assert.Equal(t, audit.OpMetricBatch, batch.Operation)
+	require.Len(t, batch.Changes, 2)
+	assert.Equal(t, "requests", batch.Changes[0].Name)
+	assert.Equal(t, int64(2), *batch.Changes[0].Before.Delta)
+	assert.Equal(t, int64(9), *batch.Changes[0].After.Delta)
+	assert.Nil(t, batch.Changes[1].Before)
+	assert.Equal(t, value, *batch.Changes[1].After.Value)
+
+	// значение после пачки совпадает с сохранённым
+	stored, err := s.Get(ctx, "requests")
+	require.NoError(t, err)
+	assert.Equal(t, *stored.Delta, *batch.Changes[0].After.Delta)
+}
+
+func TestAuditStorage_ReadFailure(t *testing.T) {
+	log, err := audit.Open(audit.Config{Path: filepath.Join(t.TempDir(), "audit.log")})
+	require.NoError(t, err)
+	defer log.Close()
+
+	primary := &flappingStorage{MemStorage: newTestMemStorage()}
+	resilient := NewResilientStorage(primary, time.Hour, zap.NewNop())
+	defer resilient.Close()
+	s := NewAuditStorage(resilient, log)
+	ctx := context.Background()
+
+	require.NoError(t, s.UpdateBatch(ctx, []model.Metrics{counter("requests", 2)}))
+
+	// запись при недоступной БД уходит в буфер, а не прерывается чтением
+	primary.down.Store(true)
+	_, err = s.Update(ctx, counter("requests", 3))
+	require.NoError(t, err)
+	require.NoError(t, s.UpdateBatch(ctx, []model.Metrics{counter("requests", 4)}))
+
+	events, err := log.Query(audit.Query{})
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+
+	assert.False(t, events[0].Changes[0].BeforeUnknown)
+	for _, e := range events[1:] {
+		require.Len(t, e.Changes, 1)
+		assert.True(t, e.Changes[0].BeforeUnknown, e.Operation)
+		assert.Nil(t, e.Changes[0].Before)
+	}
+}
+
+// Хранилище, считающее обращения на чтение.
+type countingStorage struct {
+	*MemStorage
+	gets, batches, alls int
+}
+
+func (s *countingStorage) Get(ctx context.Context, name string) (model.Metrics, error) {
+	s.gets++
+	return s.MemStorage.Get(ctx, name)
+}
+
+func (s *countingStorage) GetBatch(ctx context.Context, names []string) ([]model.Metrics, error) {
+	s.batches++
+	return s.MemStorage.GetBatch(ctx, names)
+}
+
+func (s *countingStorage) All(ctx context.Context) ([]model.Metrics, error) {
+	s.alls++
+	return s.MemStorage.All(ctx)
+}
+
+func TestAuditStorage_BatchReads(t *testing.T) {
+	log, err := audit.Open(audit.Config{Path: filepath.Join(t.TempDir(), "audit.log")})
+	require.NoError(t, err)
+	defer log.Close()
+
+	repo := &countingStorage{MemStorage: newTestMemStorage()}
+	s := NewAuditStorage(NewResilientStorage(repo, time.Hour, zap.NewNop()), log)
+
+	batch := make([]model.Metrics, 0, 100)
+	for i := 0; i < 100; i++ {
+		batch = append(batch, counter(fmt.Sprintf("c%d", i%50), 1))
+	}
+	require.NoError(t, s.UpdateBatch(context.Background(), batch))
+	require.NoError(t, s.UpdateBatch(context.Background(), batch))
+
+	// одно чтение на пачку независимо от её размера
+	assert.Equal(t, 2, repo.batches)
+	assert.Zero(t, repo.gets)
+	assert.Zero(t, repo.alls)
+
+	events, err := log.Query(audit.Query{})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Len(t, events[1].Changes, 50)
+	assert.Equal(t, int64(2), *events[1].Changes[0].Before.Delta)
+	assert.Equal(t, int64(4), *events[1].Changes[0].After.Delta)
+}
@@ -1,14 +1,44 @@
 package storage
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 var (
-	ErrMetricNotFound   = errors.New("metric not found")
-	ErrMetricExists     = errors.New("metric already exists")
-	ErrMetricNotCreated = errors.New("failed to create metric")
-	ErrMetricNotUpdated = errors.New("failed to update metric")
-	ErrEmptyDSN         = errors.New("empty dsn")
-	ErrTableCreation    = errors.New("failed to create table")
-	ErrMigrationFailed  = errors.New("migration failed")
-	ErrInvalidMetric    = errors.New("invalid metric values")
+	ErrMetricNotFound     = errors.New("metric not found")
+	ErrMetricExists       = errors.New("metric already exists")
+	ErrMetricNotCreated   = errors.New("failed to create metric")
+	ErrMetricNotUpdated   = errors.New("failed to update metric")
+	ErrEmptyDSN           = errors.New("empty dsn")
+	ErrTableCreation      = errors.New("failed to create table")
+	ErrMigrationFailed    = errors.New("migration failed")
+	ErrInvalidMetric      = errors.New("invalid metric values")
+	ErrHistoryUnsupported = errors.New("storage does not keep metrics history")
+	ErrNoTenant           = errors.New("tenant is not specified")
+	ErrQuotaExceeded      = errors.New("tenant quota exceeded")
 )
+
+// Ошибка, после которой запрос можно повторить через RetryAfter.
+type RetryError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *RetryError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+// RetryAfter() возвращает время, через которое можно повторить запрос, завершившийся ошибкой err.
+func RetryAfter(err error) (time.Duration, bool) {
+	var re *RetryError
+	if errors.As(err, &re) {
+		return re.RetryAfter, true
+	}
+
+	return 0, false
+}
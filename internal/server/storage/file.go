@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"sync"
 
 	"github.com/Xacor/go-metrics/internal/server/model"
 )
@@ -12,6 +13,7 @@ import (
 // Реализует логику для сохранения и загрузки метрик из файла.
 type FileStorage struct {
 	file *os.File
+	mu   sync.Mutex
 }
 
 func NewFileStorage(path string) (*FileStorage, error) {
@@ -28,6 +30,10 @@ func (fs *FileStorage) Save(repo Storage) error {
 	if err != nil {
 		return err
 	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
 	fs.file.Truncate(0)
 	fs.file.Seek(0, 0)
 
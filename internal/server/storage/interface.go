@@ -3,6 +3,7 @@ package storage
 
 import (
 	"context"
+	"time"
 
 	"github.com/Xacor/go-metrics/internal/server/model"
 )
@@ -33,7 +34,33 @@ type MetricRepo interface {
 	Close() error
 }
 
+// Интерфейс хранилища, умеющего выбирать метрики по префиксу имени.
+type PrefixLister interface {
+	// AllWithPrefix() возвращает значения метрик, имена которых начинаются с prefix.
+	AllWithPrefix(ctx context.Context, prefix string) ([]model.Metrics, error)
+}
+
+// Интерфейс хранилища, умеющего читать несколько метрик одним запросом.
+type BatchGetter interface {
+	// GetBatch() возвращает значения метрик с именами names. Отсутствующие метрики пропускаются.
+	GetBatch(ctx context.Context, names []string) ([]model.Metrics, error)
+}
+
 // Интерфейс позволяет проверить подключение к БД.
 type Pinger interface {
 	Ping(ctx context.Context) error
 }
+
+// Интерфейс хранилища, сохраняющего историю значений метрик.
+type HistoryRepo interface {
+	// History() возвращает значения метрики за период [from, to) с разрешением,
+	// подобранным по длине периода.
+	History(ctx context.Context, name string, from, to time.Time) (model.History, error)
+}
+
+// Интерфейс хранилища, способного работать в деградированном режиме
+// при недоступности БД.
+type Degrader interface {
+	// Degraded() сообщает, работает ли хранилище в деградированном режиме.
+	Degraded() bool
+}
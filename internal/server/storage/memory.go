@@ -2,126 +2,266 @@ package storage
 
 import (
 	"context"
-	"errors"
 	"fmt"
+	"math"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Xacor/go-metrics/internal/server/model"
 	"go.uber.org/zap"
 )
 
+// Количество сегментов in-memory хранилища. Степень двойки,
+// чтобы номер сегмента вычислялся маской.
+const shardCount = 64
+
+// Значение метрики. Тип не меняется после создания,
+// а значение обновляется атомарно без захвата блокировки сегмента.
+type memEntry struct {
+	mtype string
+	delta atomic.Int64
+	// биты float64, см. math.Float64bits
+	value atomic.Uint64
+}
+
+func newMemEntry(metric model.Metrics) (*memEntry, error) {
+	e := &memEntry{mtype: metric.MType}
+
+	switch metric.MType {
+	case model.TypeCounter:
+		if metric.Delta == nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidMetric, metric.Name)
+		}
+		e.delta.Store(*metric.Delta)
+
+	case model.TypeGauge:
+		if metric.Value == nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidMetric, metric.Name)
+		}
+		e.value.Store(math.Float64bits(*metric.Value))
+
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrInvalidMetric, metric.Name)
+	}
+
+	return e, nil
+}
+
+// apply добавляет приращение счётчика или заменяет значение gauge.
+func (e *memEntry) apply(name string, metric model.Metrics) error {
+	switch e.mtype {
+	case model.TypeCounter:
+		if metric.Delta == nil {
+			return fmt.Errorf("%w: %s", ErrInvalidMetric, name)
+		}
+		e.delta.Add(*metric.Delta)
+
+	default:
+		if metric.Value == nil {
+			return fmt.Errorf("%w: %s", ErrInvalidMetric, name)
+		}
+		e.value.Store(math.Float64bits(*metric.Value))
+	}
+
+	return nil
+}
+
+func (e *memEntry) snapshot(name string) model.Metrics {
+	m := model.Metrics{Name: name, MType: e.mtype}
+
+	switch e.mtype {
+	case model.TypeCounter:
+		delta := e.delta.Load()
+		m.Delta = &delta
+	default:
+		value := math.Float64frombits(e.value.Load())
+		m.Value = &value
+	}
+
+	return m
+}
+
+type memShard struct {
+	data map[string]*memEntry
+	mu   sync.RWMutex
+}
+
 // Реализует интерфейс Storage для in-memory хранилища.
+//
+// Метрики распределены по сегментам, у каждого из которых своя блокировка,
+// поэтому запись разных метрик не конкурирует за общий мьютекс. Блокировка
+// на запись нужна только для добавления новой метрики, обновление значения
+// существующей выполняется атомарно под блокировкой на чтение.
 type MemStorage struct {
-	data          map[string]model.Metrics
 	fs            *FileStorage
 	l             *zap.Logger
+	shards        [shardCount]memShard
 	storeInterval int
-	mu            sync.RWMutex
 }
 
 func NewMemStorage(backup *FileStorage, storeInterval int, logger *zap.Logger) *MemStorage {
 	mem := &MemStorage{
-		data:          make(map[string]model.Metrics),
 		storeInterval: storeInterval,
 		fs:            backup,
 		l:             logger,
 	}
+	for i := range mem.shards {
+		mem.shards[i].data = make(map[string]*memEntry)
+	}
 
 	go mem.store()
 	return mem
 }
 
+// shard возвращает сегмент метрики по хешу FNV-1a её имени.
+func (mem *MemStorage) shard(name string) *memShard {
+	h := uint32(2166136261)
+	for i := 0; i < len(name); i++ {
+		h ^= uint32(name[i])
+		h *= 16777619
+	}
+
+	return &mem.shards[h&(shardCount-1)]
+}
+
+func (mem *MemStorage) lookup(name string) (*memShard, *memEntry) {
+	sh := mem.shard(name)
+
+	sh.mu.RLock()
+	e := sh.data[name]
+	sh.mu.RUnlock()
+
+	return sh, e
+}
+
 func (mem *MemStorage) Ping(ctx context.Context) error {
 	return nil
 }
 
 func (mem *MemStorage) All(ctx context.Context) ([]model.Metrics, error) {
-	mem.mu.RLock()
-	defer mem.mu.RUnlock()
+	return mem.AllWithPrefix(ctx, "")
+}
+
+func (mem *MemStorage) AllWithPrefix(ctx context.Context, prefix string) ([]model.Metrics, error) {
+	result := make([]model.Metrics, 0)
+
+	for i := range mem.shards {
+		sh := &mem.shards[i]
 
-	result := make([]model.Metrics, 0, len(mem.data))
-	for _, v := range mem.data {
-		result = append(result, v)
+		sh.mu.RLock()
+		for name, e := range sh.data {
+			if strings.HasPrefix(name, prefix) {
+				result = append(result, e.snapshot(name))
+			}
+		}
+		sh.mu.RUnlock()
 	}
 
 	return result, nil
 }
 
 func (mem *MemStorage) Get(ctx context.Context, name string) (model.Metrics, error) {
-	mem.mu.RLock()
-	defer mem.mu.RUnlock()
-
-	val, ok := mem.data[name]
-	if !ok {
+	_, e := mem.lookup(name)
+	if e == nil {
 		return model.Metrics{}, fmt.Errorf("%w: %s", ErrMetricNotFound, name)
 	}
-	return val, nil
+
+	return e.snapshot(name), nil
 }
 
-func (mem *MemStorage) Create(ctx context.Context, metric model.Metrics) (model.Metrics, error) {
-	_, err := mem.Get(ctx, metric.Name)
-	if !errors.Is(err, ErrMetricNotFound) {
-		return model.Metrics{}, ErrMetricExists
+func (mem *MemStorage) GetBatch(ctx context.Context, names []string) ([]model.Metrics, error) {
+	result := make([]model.Metrics, 0, len(names))
+	for _, name := range names {
+		if _, e := mem.lookup(name); e != nil {
+			result = append(result, e.snapshot(name))
+		}
 	}
 
-	mem.mu.Lock()
-	defer mem.mu.Unlock()
+	return result, nil
+}
+
+func (mem *MemStorage) Create(ctx context.Context, metric model.Metrics) (model.Metrics, error) {
+	e, err := newMemEntry(metric)
+	if err != nil {
+		return model.Metrics{}, err
+	}
 
-	mem.data[metric.Name] = metric
+	sh := mem.shard(metric.Name)
 
-	if err := mem.syncStore(); err != nil {
-		mem.l.Error("failed to syncStore", zap.Error(err))
+	sh.mu.Lock()
+	if _, ok := sh.data[metric.Name]; ok {
+		sh.mu.Unlock()
+		return model.Metrics{}, ErrMetricExists
 	}
+	sh.data[metric.Name] = e
+	sh.mu.Unlock()
+
+	mem.syncStore()
 
-	return mem.data[metric.Name], nil
+	return e.snapshot(metric.Name), nil
 }
 
 func (mem *MemStorage) Update(ctx context.Context, metric model.Metrics) (model.Metrics, error) {
+	_, e := mem.lookup(metric.Name)
+	if e == nil {
+		return model.Metrics{}, fmt.Errorf("%w: %s", ErrMetricNotFound, metric.Name)
+	}
 
-	obj, err := mem.Get(ctx, metric.Name)
-	if errors.Is(err, ErrMetricNotFound) {
+	if err := e.apply(metric.Name, metric); err != nil {
 		return model.Metrics{}, err
 	}
 
-	mem.mu.Lock()
-	defer mem.mu.Unlock()
+	mem.syncStore()
 
-	switch obj.MType {
-	case model.TypeCounter:
-		addDelta(metric.Delta, &obj)
+	return e.snapshot(metric.Name), nil
+}
 
-	case model.TypeGauge:
-		setValue(metric.Value, &obj)
+func (mem *MemStorage) UpdateBatch(ctx context.Context, metrics []model.Metrics) error {
+	for _, m := range metrics {
+		if err := mem.upsert(m); err != nil {
+			return fmt.Errorf("%w: %v", ErrMetricNotUpdated, err)
+		}
 	}
 
-	mem.data[metric.Name] = obj
+	mem.syncStore()
 
-	if err := mem.syncStore(); err != nil {
-		mem.l.Error("failed to syncStore", zap.Error(err))
+	return nil
+}
+
+// upsert обновляет метрику, создавая её при необходимости.
+func (mem *MemStorage) upsert(metric model.Metrics) error {
+	sh, e := mem.lookup(metric.Name)
+	if e != nil {
+		return e.apply(metric.Name, metric)
 	}
 
-	return mem.data[metric.Name], nil
-}
+	created, err := newMemEntry(metric)
+	if err != nil {
+		return err
+	}
 
-func (mem *MemStorage) UpdateBatch(ctx context.Context, metrics []model.Metrics) error {
-	for _, m := range metrics {
-		_, ok := mem.Get(ctx, m.Name)
-		if ok != nil {
-			if _, err := mem.Create(ctx, m); err != nil {
-				return fmt.Errorf("%w: %v", ErrMetricNotCreated, m)
-			}
-		} else {
-			if _, err := mem.Update(ctx, m); err != nil {
-				return fmt.Errorf("%w: %v", ErrMetricNotUpdated, m)
-			}
-		}
+	sh.mu.Lock()
+	e, ok := sh.data[metric.Name]
+	if !ok {
+		sh.data[metric.Name] = created
+	}
+	sh.mu.Unlock()
+
+	// метрику успели создать в другой горутине
+	if ok {
+		return e.apply(metric.Name, metric)
 	}
 
 	return nil
 }
 
 func (mem *MemStorage) Close() error {
+	if mem.fs == nil {
+		return nil
+	}
+
 	if err := mem.fs.Save(mem); err != nil {
 		return err
 	}
@@ -130,7 +270,7 @@ func (mem *MemStorage) Close() error {
 }
 
 func (mem *MemStorage) store() {
-	if mem.storeInterval <= 0 {
+	if mem.storeInterval <= 0 || mem.fs == nil {
 		return
 	}
 
@@ -143,18 +283,14 @@ func (mem *MemStorage) store() {
 	}
 }
 
-func (mem *MemStorage) syncStore() error {
-	if mem.storeInterval != 0 {
-		return nil
+// syncStore сохраняет состояние в файл после каждой записи,
+// если периодическое сохранение отключено.
+func (mem *MemStorage) syncStore() {
+	if mem.storeInterval != 0 || mem.fs == nil {
+		return
 	}
 
-	return mem.fs.Save(mem)
-}
-
-func addDelta(delta *int64, dst *model.Metrics) {
-	*dst.Delta = *dst.Delta + *delta
-}
-
-func setValue(value *float64, dst *model.Metrics) {
-	*dst.Value = *value
+	if err := mem.fs.Save(mem); err != nil {
+		mem.l.Error("failed to syncStore", zap.Error(err))
+	}
 }
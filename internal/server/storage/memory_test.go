@@ -0,0 +1,196 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/Xacor/go-metrics/internal/server/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newTestMemStorage() *MemStorage {
+	return NewMemStorage(nil, -1, zap.NewNop())
+}
+
+func TestMemStorage_CreateUpdate(t *testing.T) {
+	ctx := context.Background()
+	mem := newTestMemStorage()
+
+	_, err := mem.Get(ctx, "counter1")
+	assert.True(t, errors.Is(err, ErrMetricNotFound))
+
+	var delta int64 = 2
+	_, err = mem.Create(ctx, model.Metrics{Name: "counter1", MType: model.TypeCounter, Delta: &delta})
+	require.NoError(t, err)
+
+	_, err = mem.Create(ctx, model.Metrics{Name: "counter1", MType: model.TypeCounter, Delta: &delta})
+	assert.True(t, errors.Is(err, ErrMetricExists))
+
+	got, err := mem.Update(ctx, model.Metrics{Name: "counter1", MType: model.TypeCounter, Delta: &delta})
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), *got.Delta)
+	assert.Equal(t, int64(2), delta, "caller's value must not be modified")
+
+	value := 1.5
+	_, err = mem.Create(ctx, model.Metrics{Name: "gauge1", MType: model.TypeGauge, Value: &value})
+	require.NoError(t, err)
+
+	value = 3.5
+	got, err = mem.Update(ctx, model.Metrics{Name: "gauge1", MType: model.TypeGauge, Value: &value})
+	require.NoError(t, err)
+	assert.Equal(t, 3.5, *got.Value)
+
+	_, err = mem.Update(ctx, model.Metrics{Name: "gauge1", MType: model.TypeGauge})
+	assert.True(t, errors.Is(err, ErrInvalidMetric))
+
+	all, err := mem.All(ctx)
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+}
+
+func TestMemStorage_ConcurrentUpdateBatch(t *testing.T) {
+	ctx := context.Background()
+	mem := newTestMemStorage()
+
+	const (
+		workers = 16
+		rounds  = 1000
+	)
+
+	var one int64 = 1
+	batch := []model.Metrics{
+		{Name: "counter1", MType: model.TypeCounter, Delta: &one},
+		{Name: "counter2", MType: model.TypeCounter, Delta: &one},
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				assert.NoError(t, mem.UpdateBatch(ctx, batch))
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, name := range []string{"counter1", "counter2"} {
+		got, err := mem.Get(ctx, name)
+		require.NoError(t, err)
+		assert.Equal(t, int64(workers*rounds), *got.Delta)
+	}
+}
+
+// Реализация хранилища с единственным мьютексом, повторяющая прежнюю MemStorage.
+// Используется как точка отсчёта в бенчмарках.
+type mutexStorage struct {
+	data map[string]model.Metrics
+	mu   sync.RWMutex
+}
+
+func (s *mutexStorage) Get(ctx context.Context, name string) (model.Metrics, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	val, ok := s.data[name]
+	if !ok {
+		return model.Metrics{}, ErrMetricNotFound
+	}
+	return val, nil
+}
+
+func (s *mutexStorage) UpdateBatch(ctx context.Context, metrics []model.Metrics) error {
+	for _, m := range metrics {
+		obj, err := s.Get(ctx, m.Name)
+
+		s.mu.Lock()
+		if err != nil {
+			delta := *m.Delta
+			m.Delta = &delta
+			s.data[m.Name] = m
+		} else {
+			*obj.Delta += *m.Delta
+			s.data[m.Name] = obj
+		}
+		s.mu.Unlock()
+	}
+
+	return nil
+}
+
+type benchRepo interface {
+	Get(ctx context.Context, name string) (model.Metrics, error)
+	UpdateBatch(ctx context.Context, metrics []model.Metrics) error
+}
+
+func benchBatch(n int) []model.Metrics {
+	var one int64 = 1
+	batch := make([]model.Metrics, 0, n)
+	for i := 0; i < n; i++ {
+		batch = append(batch, model.Metrics{Name: fmt.Sprintf("counter%d", i), MType: model.TypeCounter, Delta: &one})
+	}
+
+	return batch
+}
+
+func benchmarkRepos() map[string]func() benchRepo {
+	return map[string]func() benchRepo{
+		"mutex":   func() benchRepo { return &mutexStorage{data: make(map[string]model.Metrics)} },
+		"sharded": func() benchRepo { return newTestMemStorage() },
+	}
+}
+
+func BenchmarkMemStorage_UpdateBatch(b *testing.B) {
+	ctx := context.Background()
+	batch := benchBatch(64)
+
+	for name, newRepo := range benchmarkRepos() {
+		b.Run(name, func(b *testing.B) {
+			repo := newRepo()
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if err := repo.UpdateBatch(ctx, batch); err != nil {
+						b.Error(err)
+					}
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkMemStorage_Mixed(b *testing.B) {
+	ctx := context.Background()
+	batch := benchBatch(64)
+
+	for name, newRepo := range benchmarkRepos() {
+		b.Run(name, func(b *testing.B) {
+			repo := newRepo()
+			if err := repo.UpdateBatch(ctx, batch); err != nil {
+				b.Fatal(err)
+			}
+
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					// на одну пачку обновлений приходится десять чтений
+					if i%10 == 0 {
+						if err := repo.UpdateBatch(ctx, batch[:8]); err != nil {
+							b.Error(err)
+						}
+					} else if _, err := repo.Get(ctx, batch[i%len(batch)].Name); err != nil {
+						b.Error(err)
+					}
+					i++
+				}
+			})
+		})
+	}
+}
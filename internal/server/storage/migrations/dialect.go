@@ -0,0 +1,56 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+)
+
+// Dialect инкапсулирует особенности конкретной СУБД,
+// необходимые для применения миграций.
+type Dialect interface {
+	// Lock() захватывает эксклюзивную блокировку миграций на соединении conn,
+	// чтобы несколько экземпляров сервера не применяли миграции одновременно.
+	Lock(ctx context.Context, conn *sql.Conn) error
+
+	// Unlock() освобождает блокировку, захваченную Lock().
+	Unlock(ctx context.Context, conn *sql.Conn) error
+
+	// Placeholder() возвращает обозначение n-го параметра запроса.
+	Placeholder(n int) string
+}
+
+// Произвольный ключ advisory-блокировки, общий для всех экземпляров сервера.
+const postgresLockKey int64 = 7263541092
+
+type postgresDialect struct{}
+
+func (postgresDialect) Lock(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", postgresLockKey)
+	return err
+}
+
+func (postgresDialect) Unlock(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", postgresLockKey)
+	return err
+}
+
+func (postgresDialect) Placeholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+// SQLite не поддерживает advisory-блокировки. Встроенная БД используется
+// единственным процессом, поэтому блокировка не требуется.
+type sqliteDialect struct{}
+
+func (sqliteDialect) Lock(ctx context.Context, conn *sql.Conn) error {
+	return nil
+}
+
+func (sqliteDialect) Unlock(ctx context.Context, conn *sql.Conn) error {
+	return nil
+}
+
+func (sqliteDialect) Placeholder(n int) string {
+	return "?"
+}
@@ -0,0 +1,128 @@
+// Модуль migrations содержит версионированные миграции схемы БД
+// и механизм их применения и отката.
+package migrations
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	//go:embed postgres/*.sql
+	postgresFS embed.FS
+
+	//go:embed sqlite/*.sql
+	sqliteFS embed.FS
+)
+
+var (
+	ErrInvalidFileName  = errors.New("invalid migration file name")
+	ErrDuplicateVersion = errors.New("duplicate migration version")
+	ErrMissingStep      = errors.New("migration must have both up and down steps")
+)
+
+// Migration описывает один шаг изменения схемы.
+type Migration struct {
+	Name    string
+	Up      string
+	Down    string
+	Version int
+}
+
+// Load читает миграции из каталога dir. Файлы должны называться
+// по шаблону NNNN_name.up.sql и NNNN_name.down.sql.
+// Результат отсортирован по возрастанию версии.
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".sql") {
+			continue
+		}
+
+		version, name, direction, err := parseFileName(e.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+		if m.Name != name {
+			return nil, fmt.Errorf("%w: %d", ErrDuplicateVersion, version)
+		}
+
+		switch direction {
+		case "up":
+			m.Up = string(data)
+		case "down":
+			m.Down = string(data)
+		}
+	}
+
+	result := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("%w: %04d_%s", ErrMissingStep, m.Version, m.Name)
+		}
+		result = append(result, *m)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Version < result[j].Version
+	})
+
+	return result, nil
+}
+
+// Postgres возвращает встроенный набор миграций для PostgreSQL.
+func Postgres() ([]Migration, error) {
+	return Load(postgresFS, "postgres")
+}
+
+// SQLite возвращает встроенный набор миграций для SQLite.
+func SQLite() ([]Migration, error) {
+	return Load(sqliteFS, "sqlite")
+}
+
+func parseFileName(file string) (version int, name string, direction string, err error) {
+	base := strings.TrimSuffix(file, ".sql")
+
+	dot := strings.LastIndex(base, ".")
+	if dot < 0 {
+		return 0, "", "", fmt.Errorf("%w: %s", ErrInvalidFileName, file)
+	}
+	base, direction = base[:dot], base[dot+1:]
+	if direction != "up" && direction != "down" {
+		return 0, "", "", fmt.Errorf("%w: %s", ErrInvalidFileName, file)
+	}
+
+	num, name, ok := strings.Cut(base, "_")
+	if !ok || name == "" {
+		return 0, "", "", fmt.Errorf("%w: %s", ErrInvalidFileName, file)
+	}
+
+	version, err = strconv.Atoi(num)
+	if err != nil || version <= 0 {
+		return 0, "", "", fmt.Errorf("%w: %s", ErrInvalidFileName, file)
+	}
+
+	return version, name, direction, nil
+}
@@ -0,0 +1,93 @@
+package migrations
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbedded(t *testing.T) {
+	sets := map[string]func() ([]Migration, error){
+		"postgres": Postgres,
+		"sqlite":   SQLite,
+	}
+
+	for name, load := range sets {
+		t.Run(name, func(t *testing.T) {
+			migrations, err := load()
+			require.NoError(t, err)
+			require.NotEmpty(t, migrations)
+
+			for i, m := range migrations {
+				assert.Equal(t, i+1, m.Version, "versions must be sequential")
+				assert.NotEmpty(t, m.Up)
+				assert.NotEmpty(t, m.Down)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		fsys    fstest.MapFS
+		wantErr error
+		name    string
+		want    []int
+	}{
+		{
+			name: "sorted",
+			fsys: fstest.MapFS{
+				"m/0002_second.up.sql":   {Data: []byte("up2")},
+				"m/0002_second.down.sql": {Data: []byte("down2")},
+				"m/0001_first.up.sql":    {Data: []byte("up1")},
+				"m/0001_first.down.sql":  {Data: []byte("down1")},
+				"m/README.md":            {Data: []byte("ignored")},
+			},
+			want: []int{1, 2},
+		},
+		{
+			name: "missing_down",
+			fsys: fstest.MapFS{
+				"m/0001_first.up.sql": {Data: []byte("up1")},
+			},
+			wantErr: ErrMissingStep,
+		},
+		{
+			name: "duplicate_version",
+			fsys: fstest.MapFS{
+				"m/0001_first.up.sql":    {Data: []byte("up1")},
+				"m/0001_first.down.sql":  {Data: []byte("down1")},
+				"m/0001_second.up.sql":   {Data: []byte("up1")},
+				"m/0001_second.down.sql": {Data: []byte("down1")},
+			},
+			wantErr: ErrDuplicateVersion,
+		},
+		{
+			name: "invalid_name",
+			fsys: fstest.MapFS{
+				"m/first.up.sql": {Data: []byte("up1")},
+			},
+			wantErr: ErrInvalidFileName,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Load(tt.fsys, "m")
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr), "got error %v", err)
+				return
+			}
+			require.NoError(t, err)
+
+			versions := make([]int, 0, len(got))
+			for _, m := range got {
+				versions = append(versions, m.Version)
+			}
+			assert.Equal(t, tt.want, versions)
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const createVersionTable = `CREATE TABLE IF NOT EXISTS schema_version (
+	version INTEGER PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);`
+
+// Status описывает состояние одной миграции в БД.
+type Status struct {
+	AppliedAt time.Time
+	Name      string
+	Version   int
+	Applied   bool
+}
+
+// Migrator применяет и откатывает миграции, ведя учёт в таблице schema_version.
+type Migrator struct {
+	db         *sql.DB
+	dialect    Dialect
+	l          *zap.Logger
+	migrations []Migration
+}
+
+func NewMigrator(db *sql.DB, dialect Dialect, migrations []Migration, logger *zap.Logger) *Migrator {
+	return &Migrator{
+		db:         db,
+		dialect:    dialect,
+		migrations: migrations,
+		l:          logger,
+	}
+}
+
+// NewPostgres создаёт Migrator со встроенным набором миграций для PostgreSQL.
+func NewPostgres(db *sql.DB, logger *zap.Logger) (*Migrator, error) {
+	migrations, err := Postgres()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewMigrator(db, postgresDialect{}, migrations, logger), nil
+}
+
+// NewSQLite создаёт Migrator со встроенным набором миграций для SQLite.
+func NewSQLite(db *sql.DB, logger *zap.Logger) (*Migrator, error) {
+	migrations, err := SQLite()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewMigrator(db, sqliteDialect{}, migrations, logger), nil
+}
+
+// Up() применяет все ещё не применённые миграции и возвращает их количество.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	applied := 0
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		current, err := m.versions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, mg := range m.migrations {
+			if _, ok := current[mg.Version]; ok {
+				continue
+			}
+
+			m.l.Info("applying migration", zap.Int("version", mg.Version), zap.String("name", mg.Name))
+			insert := fmt.Sprintf("INSERT INTO schema_version (version, name) VALUES (%s, %s);",
+				m.dialect.Placeholder(1), m.dialect.Placeholder(2))
+			if err := m.step(ctx, conn, mg.Up, insert, mg.Version, mg.Name); err != nil {
+				return fmt.Errorf("migration %04d_%s: %w", mg.Version, mg.Name, err)
+			}
+			applied++
+		}
+
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down() откатывает steps последних применённых миграций и возвращает их количество.
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	reverted := 0
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		current, err := m.versions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && reverted < steps; i-- {
+			mg := m.migrations[i]
+			if _, ok := current[mg.Version]; !ok {
+				continue
+			}
+
+			m.l.Info("reverting migration", zap.Int("version", mg.Version), zap.String("name", mg.Name))
+			del := fmt.Sprintf("DELETE FROM schema_version WHERE version = %s;", m.dialect.Placeholder(1))
+			if err := m.step(ctx, conn, mg.Down, del, mg.Version); err != nil {
+				return fmt.Errorf("migration %04d_%s: %w", mg.Version, mg.Name, err)
+			}
+			reverted++
+		}
+
+		return nil
+	})
+
+	return reverted, err
+}
+
+// Status() возвращает состояние всех известных миграций.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var result []Status
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		current, err := m.versions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		result = make([]Status, 0, len(m.migrations))
+		for _, mg := range m.migrations {
+			appliedAt, ok := current[mg.Version]
+			result = append(result, Status{
+				Version:   mg.Version,
+				Name:      mg.Name,
+				Applied:   ok,
+				AppliedAt: appliedAt,
+			})
+			delete(current, mg.Version)
+		}
+
+		for v := range current {
+			m.l.Warn("database has unknown schema version", zap.Int("version", v))
+		}
+
+		return nil
+	})
+
+	return result, err
+}
+
+// withLock выполняет fn на выделенном соединении под блокировкой миграций.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := m.dialect.Lock(ctx, conn); err != nil {
+		return fmt.Errorf("unable to acquire migration lock: %w", err)
+	}
+	defer func() {
+		if err := m.dialect.Unlock(context.Background(), conn); err != nil {
+			m.l.Error("unable to release migration lock", zap.Error(err))
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, createVersionTable); err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+// versions возвращает применённые версии и время их применения.
+func (m *Migrator) versions(ctx context.Context, conn *sql.Conn) (map[int]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, applied_at FROM schema_version;")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[int]time.Time)
+	for rows.Next() {
+		var (
+			version   int
+			appliedAt time.Time
+		)
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		result[version] = appliedAt
+	}
+
+	return result, rows.Err()
+}
+
+// step выполняет скрипт миграции и учётный запрос в одной транзакции.
+func (m *Migrator) step(ctx context.Context, conn *sql.Conn, script, bookkeeping string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, bookkeeping, args...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
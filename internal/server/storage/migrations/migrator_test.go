@@ -0,0 +1,53 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	_ "modernc.org/sqlite"
+)
+
+func TestMigrator_SQLite(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "migrations.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	m, err := NewSQLite(db, zap.NewNop())
+	require.NoError(t, err)
+	total := len(m.migrations)
+
+	n, err := m.Up(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, total, n)
+
+	n, err = m.Up(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n, "second run must be a no-op")
+
+	status, err := m.Status(ctx)
+	require.NoError(t, err)
+	require.Len(t, status, total)
+	for _, st := range status {
+		assert.True(t, st.Applied)
+		assert.False(t, st.AppliedAt.IsZero())
+	}
+
+	n, err = m.Down(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	status, err = m.Status(ctx)
+	require.NoError(t, err)
+	assert.False(t, status[total-1].Applied)
+
+	n, err = m.Up(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+}
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS metrics;
+
+DROP TABLE IF EXISTS metric_types;
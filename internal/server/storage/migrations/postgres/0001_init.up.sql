@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS metric_types (
+    type VARCHAR(10) PRIMARY KEY
+);
+
+INSERT INTO metric_types (type) VALUES
+    ('counter'),
+    ('gauge')
+    ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS metrics (
+    id serial PRIMARY KEY,
+    name VARCHAR(255) UNIQUE NOT NULL,
+    mtype VARCHAR(10) references metric_types(type) NOT NULL,
+    delta BIGINT CHECK ((delta IS NOT NULL AND mtype = 'counter') OR (delta IS NULL AND mtype = 'gauge')),
+    value DOUBLE PRECISION CHECK ((value IS NOT NULL AND mtype = 'gauge') OR (value IS NULL AND mtype = 'counter'))
+);
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS rollup_state;
+
+DROP TABLE IF EXISTS metric_samples_1h;
+
+DROP TABLE IF EXISTS metric_samples_1m;
+
+DROP TABLE IF EXISTS metric_samples;
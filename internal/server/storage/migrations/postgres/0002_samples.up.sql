@@ -0,0 +1,40 @@
+CREATE TABLE IF NOT EXISTS metric_samples (
+    name VARCHAR(255) NOT NULL,
+    mtype VARCHAR(10) references metric_types(type) NOT NULL,
+    ts TIMESTAMPTZ NOT NULL,
+    delta BIGINT,
+    value DOUBLE PRECISION
+) PARTITION BY RANGE (ts);
+
+CREATE INDEX IF NOT EXISTS metric_samples_name_ts_idx ON metric_samples (name, ts);
+
+CREATE TABLE IF NOT EXISTS metric_samples_1m (
+    name VARCHAR(255) NOT NULL,
+    mtype VARCHAR(10) references metric_types(type) NOT NULL,
+    ts TIMESTAMPTZ NOT NULL,
+    min DOUBLE PRECISION,
+    max DOUBLE PRECISION,
+    sum DOUBLE PRECISION,
+    last DOUBLE PRECISION,
+    delta BIGINT,
+    count BIGINT NOT NULL,
+    PRIMARY KEY (name, ts)
+) PARTITION BY RANGE (ts);
+
+CREATE TABLE IF NOT EXISTS metric_samples_1h (
+    name VARCHAR(255) NOT NULL,
+    mtype VARCHAR(10) references metric_types(type) NOT NULL,
+    ts TIMESTAMPTZ NOT NULL,
+    min DOUBLE PRECISION,
+    max DOUBLE PRECISION,
+    sum DOUBLE PRECISION,
+    last DOUBLE PRECISION,
+    delta BIGINT,
+    count BIGINT NOT NULL,
+    PRIMARY KEY (name, ts)
+) PARTITION BY RANGE (ts);
+
+CREATE TABLE IF NOT EXISTS rollup_state (
+    resolution VARCHAR(8) PRIMARY KEY,
+    rolled_until TIMESTAMPTZ NOT NULL
+);
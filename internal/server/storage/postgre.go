@@ -3,18 +3,47 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
 
 	"github.com/Xacor/go-metrics/internal/server/model"
+	"github.com/Xacor/go-metrics/internal/server/storage/migrations"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"go.uber.org/zap"
 )
 
+// Параметры подключения и работы PostgreStorage.
+type PostgreConfig struct {
+	Logger  *zap.Logger
+	DSN     string
+	History HistoryConfig
+	// Минимальный размер пачки, начиная с которого UpdateBatch использует COPY.
+	// Нулевое значение отключает COPY.
+	CopyThreshold int
+	// Реплики, на которые направляются запросы чтения.
+	ReplicaDSNs []string
+	// Период проверки доступности реплик.
+	ReplicaCheckInterval time.Duration
+	// В течение этого времени после записи метрика читается с основного сервера,
+	// даже если реплика ещё не получила изменения. Нулевое значение отключает
+	// read-your-writes.
+	ReadYourWrites time.Duration
+}
+
 // Реализиует интерфейс Storage для взаимодействия с PostgreSQL
 type PostgreStorage struct {
-	db *pgxpool.Pool
-	l  *zap.Logger
+	db            *pgxpool.Pool
+	l             *zap.Logger
+	replicas      *replicaSet
+	stop          context.CancelFunc
+	dsn           string
+	history       HistoryConfig
+	wg            sync.WaitGroup
+	copyThreshold int
 }
 
 type sqlResponse struct {
@@ -24,135 +53,226 @@ type sqlResponse struct {
 	value sql.NullFloat64
 }
 
-func NewPostgreStorage(ctx context.Context, dsn string, logger *zap.Logger) (*PostgreStorage, error) {
-	if dsn == "" {
+func (r sqlResponse) toModel() (model.Metrics, error) {
+	m := model.Metrics{Name: r.name, MType: r.mtype}
+
+	if r.delta.Valid {
+		m.Delta = &r.delta.Int64
+
+	} else if r.value.Valid {
+		m.Value = &r.value.Float64
+
+	} else {
+		return model.Metrics{}, ErrInvalidMetric
+	}
+	return m, nil
+}
+
+func NewPostgreStorage(ctx context.Context, cfg *PostgreConfig) (*PostgreStorage, error) {
+	if cfg.DSN == "" {
 		return nil, ErrEmptyDSN
 	}
-	conn, err := pgxpool.New(ctx, dsn)
+	conn, err := pgxpool.New(ctx, cfg.DSN)
 	if err != nil {
 		return nil, err
 	}
 
 	if err := conn.Ping(ctx); err != nil {
+		conn.Close()
 		return nil, err
 	}
 
-	postgre := PostgreStorage{db: conn, l: logger}
+	postgre := &PostgreStorage{
+		db:            conn,
+		l:             cfg.Logger,
+		dsn:           cfg.DSN,
+		history:       cfg.History,
+		copyThreshold: cfg.CopyThreshold,
+	}
 	if err := postgre.Migrate(ctx); err != nil {
+		conn.Close()
 		return nil, err
 	}
 
-	return &postgre, nil
+	if len(cfg.ReplicaDSNs) > 0 {
+		replicas, err := newReplicaSet(ctx, cfg.ReplicaDSNs, cfg.ReadYourWrites, cfg.Logger)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		replicas.start(cfg.ReplicaCheckInterval)
+		postgre.replicas = replicas
+	}
+
+	if postgre.history.enabled() {
+		if err := postgre.ensurePartitions(ctx, time.Now()); err != nil {
+			postgre.Close()
+			return nil, err
+		}
+		postgre.startMaintenance()
+	}
+
+	return postgre, nil
 }
 
+// Migrate() применяет к БД все ещё не применённые миграции схемы.
 func (s *PostgreStorage) Migrate(ctx context.Context) error {
-	createType := `CREATE TABLE IF NOT EXISTS metric_types (
-        type VARCHAR(10) PRIMARY KEY
-	);`
-	if _, err := s.db.Exec(ctx, createType); err != nil {
+	db, err := sql.Open("pgx", s.dsn)
+	if err != nil {
 		return err
 	}
+	defer db.Close()
 
-	insertType := `INSERT INTO metric_types (type) VALUES 
-		('counter'),
-		('gauge')
-		ON CONFLICT DO NOTHING
-		;`
-	if _, err := s.db.Exec(ctx, insertType); err != nil {
-		return err
+	migrator, err := migrations.NewPostgres(db, s.l)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrMigrationFailed, err)
 	}
 
-	createMetrics := `CREATE TABLE IF NOT EXISTS metrics (
-    	id serial PRIMARY KEY, 
-        name VARCHAR(255) UNIQUE NOT NULL,
-		mtype VARCHAR(10) references metric_types(type) NOT NULL,
-        delta BIGINT CHECK ((delta IS NOT NULL AND mtype = 'counter') OR (delta IS NULL AND mtype = 'gauge')),
-        value DOUBLE PRECISION CHECK ((value IS NOT NULL AND mtype = 'gauge') OR (value IS NULL AND mtype = 'counter'))
-	);`
-	if _, err := s.db.Exec(ctx, createMetrics); err != nil {
-		return err
+	if _, err := migrator.Up(ctx); err != nil {
+		return fmt.Errorf("%w: %v", ErrMigrationFailed, err)
 	}
 
 	return nil
 }
 
+// All() читает метрики с реплики, если она доступна.
 func (s *PostgreStorage) All(ctx context.Context) ([]model.Metrics, error) {
+	var metrics []model.Metrics
+
+	err := s.read(ctx, "", func(db *pgxpool.Pool) error {
+		metrics = nil
+		return s.all(ctx, db, &metrics, "SELECT name, mtype, delta, value FROM metrics;")
+	})
+
+	return metrics, err
+}
+
+func (s *PostgreStorage) AllWithPrefix(ctx context.Context, prefix string) ([]model.Metrics, error) {
+	var metrics []model.Metrics
+
+	query := "SELECT name, mtype, delta, value FROM metrics WHERE starts_with(name, $1);"
+	err := s.read(ctx, "", func(db *pgxpool.Pool) error {
+		metrics = nil
+		return s.all(ctx, db, &metrics, query, prefix)
+	})
 
-	query := "SELECT name, mtype, delta, value FROM metrics;"
-	rows, err := s.db.Query(ctx, query)
+	return metrics, err
+}
+
+func (s *PostgreStorage) all(ctx context.Context, db *pgxpool.Pool, metrics *[]model.Metrics, query string, args ...any) error {
+	rows, err := db.Query(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer rows.Close()
 
-	var metrics []model.Metrics
-
 	for rows.Next() {
 		var sql sqlResponse
 
 		if err := rows.Scan(&sql.name, &sql.mtype, &sql.delta, &sql.value); err != nil {
-			return metrics, err
+			return err
 		}
 
-		m := model.Metrics{Name: sql.name, MType: sql.mtype}
-		if sql.delta.Valid {
-			m.Delta = &sql.delta.Int64
-
-		} else if sql.value.Valid {
-			m.Value = &sql.value.Float64
-
-		} else {
-			return metrics, ErrInvalidMetric
+		m, err := sql.toModel()
+		if err != nil {
+			return err
 		}
-		metrics = append(metrics, m)
+		*metrics = append(*metrics, m)
 	}
 
-	return metrics, nil
+	return rows.Err()
 }
 
+// Get() читает метрику с реплики, если она доступна и метрика
+// не записывалась в пределах окна read-your-writes.
 func (s *PostgreStorage) Get(ctx context.Context, name string) (model.Metrics, error) {
+	var m model.Metrics
+
+	err := s.read(ctx, name, func(db *pgxpool.Pool) (err error) {
+		m, err = s.get(ctx, db, name)
+		return err
+	})
+
+	return m, err
+}
+
+func (s *PostgreStorage) get(ctx context.Context, db *pgxpool.Pool, name string) (model.Metrics, error) {
 	query := "SELECT name, mtype, delta, value FROM metrics WHERE name = $1;"
 	var sql sqlResponse
 
-	if err := s.db.QueryRow(ctx, query, name).Scan(&sql.name, &sql.mtype, &sql.delta, &sql.value); err != nil {
+	err := db.QueryRow(ctx, query, name).Scan(&sql.name, &sql.mtype, &sql.delta, &sql.value)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.Metrics{}, fmt.Errorf("%w: %s", ErrMetricNotFound, name)
+	}
+	if err != nil {
 		return model.Metrics{}, err
 	}
 
-	m := model.Metrics{Name: sql.name, MType: sql.mtype}
-
-	if sql.delta.Valid {
-		m.Delta = &sql.delta.Int64
+	return sql.toModel()
+}
 
-	} else if sql.value.Valid {
-		m.Value = &sql.value.Float64
+// GetBatch() читает метрики с основного сервера: значения нужны перед записью,
+// а реплика может отставать.
+func (s *PostgreStorage) GetBatch(ctx context.Context, names []string) ([]model.Metrics, error) {
+	var metrics []model.Metrics
 
-	} else {
-		return model.Metrics{}, ErrInvalidMetric
+	query := "SELECT name, mtype, delta, value FROM metrics WHERE name = ANY($1);"
+	if err := s.all(ctx, s.db, &metrics, query, names); err != nil {
+		return nil, err
 	}
-	return m, nil
+
+	return metrics, nil
 }
 
 func (s *PostgreStorage) Create(ctx context.Context, m model.Metrics) (model.Metrics, error) {
 	insert := "INSERT INTO metrics (name, mtype, delta, value) VALUES($1,$2,$3,$4);"
 
-	if _, err := s.db.Exec(ctx, insert, m.Name, m.MType, m.Delta, m.Value); err != nil {
-		return model.Metrics{}, nil
+	batch := &pgx.Batch{}
+	batch.Queue(insert, m.Name, m.MType, m.Delta, m.Value)
+	s.queueSamples(batch, m)
+
+	if err := s.db.SendBatch(ctx, batch).Close(); err != nil {
+		return model.Metrics{}, err
 	}
+	s.markWritten(m)
 
-	return s.Get(ctx, m.Name)
+	// результат записи читается с основного сервера, реплика может отставать
+	return s.get(ctx, s.db, m.Name)
 }
 
 func (s *PostgreStorage) Update(ctx context.Context, m model.Metrics) (model.Metrics, error) {
 	update := "UPDATE metrics SET delta = metrics.delta + $1, value = $2 WHERE name = $3;"
 
-	if _, err := s.db.Exec(ctx, update, m.Delta, m.Value, m.Name); err != nil {
+	batch := &pgx.Batch{}
+	batch.Queue(update, m.Delta, m.Value, m.Name)
+	s.queueSamples(batch, m)
+
+	if err := s.db.SendBatch(ctx, batch).Close(); err != nil {
 		return model.Metrics{}, err
 	}
+	s.markWritten(m)
 
-	return s.Get(ctx, m.Name)
+	// результат записи читается с основного сервера, реплика может отставать
+	return s.get(ctx, s.db, m.Name)
 }
 
+// UpdateBatch() выбирает способ записи по размеру пачки: небольшие пачки отправляются
+// набором INSERT ... ON CONFLICT, крупные загружаются через COPY.
 func (s *PostgreStorage) UpdateBatch(ctx context.Context, metrics []model.Metrics) error {
+	update := s.updateBatchQueue
+	if s.copyThreshold > 0 && len(metrics) >= s.copyThreshold {
+		update = s.updateBatchCopy
+	}
+
+	if err := update(ctx, metrics); err != nil {
+		return err
+	}
+	s.markWritten(metrics...)
+
+	return nil
+}
+
+func (s *PostgreStorage) updateBatchQueue(ctx context.Context, metrics []model.Metrics) error {
 	query := `INSERT INTO metrics (name, mtype, delta, value) 
 		VALUES($1,$2,$3,$4) 
 		ON CONFLICT ON CONSTRAINT metrics_name_key 
@@ -163,6 +283,7 @@ func (s *PostgreStorage) UpdateBatch(ctx context.Context, metrics []model.Metric
 	for _, m := range metrics {
 		batch.Queue(query, m.Name, m.MType, m.Delta, m.Value)
 	}
+	s.queueSamples(batch, metrics...)
 
 	return s.db.SendBatch(ctx, batch).Close()
 }
@@ -172,7 +293,21 @@ func (s *PostgreStorage) Ping(ctx context.Context) error {
 }
 
 func (s *PostgreStorage) Close() error {
+	if s.stop != nil {
+		s.stop()
+		s.wg.Wait()
+	}
+	if s.replicas != nil {
+		s.replicas.close()
+	}
 	s.db.Close()
 
 	return nil
 }
+
+// markWritten отмечает запись метрик для read-your-writes.
+func (s *PostgreStorage) markWritten(metrics ...model.Metrics) {
+	if s.replicas != nil {
+		s.replicas.markWritten(metrics...)
+	}
+}
@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/Xacor/go-metrics/internal/server/model"
+	"github.com/jackc/pgx/v5"
+)
+
+// updateBatchCopy загружает пачку во временную таблицу через COPY и переносит её
+// в metrics одним запросом. Повторы метрики внутри пачки схлопываются:
+// приращения счётчиков суммируются, для gauge берётся последнее значение.
+func (s *PostgreStorage) updateBatchCopy(ctx context.Context, metrics []model.Metrics) error {
+	stage := `CREATE TEMP TABLE metrics_stage (
+		ord INTEGER NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		mtype VARCHAR(10) NOT NULL,
+		delta BIGINT,
+		value DOUBLE PRECISION
+	) ON COMMIT DROP;`
+
+	merge := `INSERT INTO metrics (name, mtype, delta, value)
+		SELECT name, mtype,
+			CASE WHEN mtype = 'counter' THEN delta END,
+			CASE WHEN mtype = 'gauge' THEN value END
+		FROM (
+			SELECT name,
+				(array_agg(mtype ORDER BY ord DESC))[1] AS mtype,
+				sum(delta)::BIGINT AS delta,
+				(array_agg(value ORDER BY ord DESC))[1] AS value
+			FROM metrics_stage
+			GROUP BY name
+		) AS staged
+		ON CONFLICT ON CONSTRAINT metrics_name_key
+		DO
+		UPDATE SET delta = metrics.delta + EXCLUDED.delta, value = EXCLUDED.value;`
+
+	samples := `INSERT INTO metric_samples (name, mtype, ts, delta, value)
+		SELECT name, mtype, now(),
+			CASE WHEN mtype = 'counter' THEN delta END,
+			CASE WHEN mtype = 'gauge' THEN value END
+		FROM metrics_stage;`
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, stage); err != nil {
+		return err
+	}
+
+	_, err = tx.CopyFrom(ctx,
+		pgx.Identifier{"metrics_stage"},
+		[]string{"ord", "name", "mtype", "delta", "value"},
+		pgx.CopyFromSlice(len(metrics), func(i int) ([]any, error) {
+			m := metrics[i]
+			return []any{i, m.Name, m.MType, m.Delta, m.Value}, nil
+		}),
+	)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, merge); err != nil {
+		return err
+	}
+
+	if s.history.enabled() {
+		if _, err := tx.Exec(ctx, samples); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
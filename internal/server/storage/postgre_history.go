@@ -0,0 +1,336 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Xacor/go-metrics/internal/server/model"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// Настройки хранения истории значений метрик. Сроки хранения задаются в днях,
+// нулевой RawRetention отключает запись истории.
+type HistoryConfig struct {
+	RawRetention    int
+	MinuteRetention int
+	HourRetention   int
+	RollupInterval  time.Duration
+}
+
+func (h HistoryConfig) enabled() bool {
+	return h.RawRetention > 0
+}
+
+// Периоды длиннее указанных читаются с более грубым разрешением.
+const (
+	maxRawSpan    = 6 * time.Hour
+	maxMinuteSpan = 7 * 24 * time.Hour
+)
+
+// На сколько дней вперёд создаются секции таблиц истории.
+const partitionsAhead = 2
+
+const day = 24 * time.Hour
+
+// Таблица истории с заданным разрешением.
+type sampleTable struct {
+	name string
+	// Срок хранения в днях.
+	retention func(HistoryConfig) int
+}
+
+var sampleTables = []sampleTable{
+	{
+		name:      "metric_samples",
+		retention: func(h HistoryConfig) int { return h.RawRetention },
+	},
+	{
+		name:      "metric_samples_1m",
+		retention: func(h HistoryConfig) int { return h.MinuteRetention },
+	},
+	{
+		name:      "metric_samples_1h",
+		retention: func(h HistoryConfig) int { return h.HourRetention },
+	},
+}
+
+// History() возвращает значения метрики за период [from, to). Разрешение выбирается
+// по длине периода и срокам хранения: сырые значения, минутные или часовые агрегаты.
+// Если запись истории отключена, возвращается ErrHistoryUnsupported.
+func (s *PostgreStorage) History(ctx context.Context, name string, from, to time.Time) (model.History, error) {
+	if !s.history.enabled() {
+		return model.History{}, ErrHistoryUnsupported
+	}
+
+	resolution := s.resolutionFor(time.Now(), from, to)
+	result := model.History{Name: name, Resolution: resolution, Samples: []model.Sample{}}
+
+	var query string
+	switch resolution {
+	case model.ResolutionRaw:
+		query = `SELECT ts, value, value, value, value, delta, 1
+			FROM metric_samples WHERE name = $1 AND ts >= $2 AND ts < $3 ORDER BY ts;`
+	case model.ResolutionMinute:
+		query = `SELECT ts, min, max, sum / NULLIF(count, 0), last, delta, count
+			FROM metric_samples_1m WHERE name = $1 AND ts >= $2 AND ts < $3 ORDER BY ts;`
+	default:
+		query = `SELECT ts, min, max, sum / NULLIF(count, 0), last, delta, count
+			FROM metric_samples_1h WHERE name = $1 AND ts >= $2 AND ts < $3 ORDER BY ts;`
+	}
+
+	err := s.read(ctx, name, func(db *pgxpool.Pool) error {
+		result.Samples = result.Samples[:0]
+
+		rows, err := db.Query(ctx, query, name, from, to)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var smp model.Sample
+			if err := rows.Scan(&smp.Time, &smp.Min, &smp.Max, &smp.Avg, &smp.Last, &smp.Delta, &smp.Count); err != nil {
+				return err
+			}
+			result.Samples = append(result.Samples, smp)
+		}
+
+		return rows.Err()
+	})
+
+	return result, err
+}
+
+func (s *PostgreStorage) resolutionFor(now, from, to time.Time) string {
+	span := to.Sub(from)
+	age := now.Sub(from)
+
+	if span <= maxRawSpan && age <= time.Duration(s.history.RawRetention)*day {
+		return model.ResolutionRaw
+	}
+	if span <= maxMinuteSpan && age <= time.Duration(s.history.MinuteRetention)*day {
+		return model.ResolutionMinute
+	}
+
+	return model.ResolutionHour
+}
+
+// queueSamples добавляет в batch запись сырых значений метрик в историю.
+func (s *PostgreStorage) queueSamples(batch *pgx.Batch, metrics ...model.Metrics) {
+	if !s.history.enabled() {
+		return
+	}
+
+	query := "INSERT INTO metric_samples (name, mtype, ts, delta, value) VALUES ($1, $2, now(), $3, $4);"
+	for _, m := range metrics {
+		switch m.MType {
+		case model.TypeCounter:
+			batch.Queue(query, m.Name, m.MType, m.Delta, nil)
+		case model.TypeGauge:
+			batch.Queue(query, m.Name, m.MType, nil, m.Value)
+		}
+	}
+}
+
+func (s *PostgreStorage) startMaintenance() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.stop = cancel
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		interval := s.history.RollupInterval
+		if interval <= 0 {
+			interval = time.Minute
+		}
+
+		t := time.NewTicker(interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-t.C:
+				if err := s.maintain(ctx, now); err != nil {
+					s.l.Error("history maintenance failed", zap.Error(err))
+				}
+			}
+		}
+	}()
+}
+
+// maintain создаёт секции на ближайшие дни, агрегирует сырые значения
+// и удаляет секции с истёкшим сроком хранения.
+func (s *PostgreStorage) maintain(ctx context.Context, now time.Time) error {
+	s.l.Debug("history maintenance", zap.Time("now", now))
+
+	if err := s.ensurePartitions(ctx, now); err != nil {
+		return err
+	}
+
+	if err := s.rollupMinutes(ctx); err != nil {
+		return fmt.Errorf("rollup 1m: %w", err)
+	}
+
+	if err := s.rollupHours(ctx); err != nil {
+		return fmt.Errorf("rollup 1h: %w", err)
+	}
+
+	return s.dropExpired(ctx, now)
+}
+
+func partitionName(table string, date time.Time) string {
+	return fmt.Sprintf("%s_p%s", table, date.Format("20060102"))
+}
+
+func (s *PostgreStorage) ensurePartitions(ctx context.Context, now time.Time) error {
+	today := now.UTC().Truncate(day)
+
+	for _, table := range sampleTables {
+		for i := 0; i <= partitionsAhead; i++ {
+			from := today.Add(time.Duration(i) * day)
+			to := from.Add(day)
+			query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s PARTITION OF %s FOR VALUES FROM ('%s') TO ('%s');",
+				partitionName(table.name, from), table.name, from.Format(time.RFC3339), to.Format(time.RFC3339))
+			if _, err := s.db.Exec(ctx, query); err != nil {
+				return fmt.Errorf("unable to create partition of %s: %w", table.name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *PostgreStorage) dropExpired(ctx context.Context, now time.Time) error {
+	query := `SELECT c.relname FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		JOIN pg_class p ON p.oid = i.inhparent
+		WHERE p.relname = $1;`
+
+	for _, table := range sampleTables {
+		cutoff := now.UTC().Add(-time.Duration(table.retention(s.history)) * day)
+
+		rows, err := s.db.Query(ctx, query, table.name)
+		if err != nil {
+			return err
+		}
+		partitions, err := pgx.CollectRows(rows, pgx.RowTo[string])
+		if err != nil {
+			return err
+		}
+
+		prefix := table.name + "_p"
+		for _, p := range partitions {
+			date, err := time.Parse("20060102", strings.TrimPrefix(p, prefix))
+			if err != nil || !strings.HasPrefix(p, prefix) {
+				continue
+			}
+
+			// секция хранит значения за сутки, начиная с date
+			if date.Add(day).After(cutoff) {
+				continue
+			}
+
+			s.l.Info("dropping expired partition", zap.String("partition", p))
+			if _, err := s.db.Exec(ctx, fmt.Sprintf("DROP TABLE IF EXISTS %s;", p)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *PostgreStorage) rollupMinutes(ctx context.Context) error {
+	// значения текущей минуты ещё могут поступать
+	untilQuery := "SELECT date_trunc('minute', now());"
+	fromQuery := `SELECT COALESCE(
+		(SELECT rolled_until FROM rollup_state WHERE resolution = '1m'),
+		(SELECT date_trunc('minute', min(ts)) FROM metric_samples)
+	);`
+	rollup := `INSERT INTO metric_samples_1m (name, mtype, ts, min, max, sum, last, delta, count)
+		SELECT name, mtype, date_trunc('minute', ts),
+			min(value), max(value), sum(value),
+			(array_agg(value ORDER BY ts DESC))[1],
+			sum(delta)::BIGINT, count(*)
+		FROM metric_samples
+		WHERE ts >= $1 AND ts < $2
+		GROUP BY name, mtype, date_trunc('minute', ts)
+		ON CONFLICT (name, ts) DO UPDATE SET
+			min = LEAST(metric_samples_1m.min, EXCLUDED.min),
+			max = GREATEST(metric_samples_1m.max, EXCLUDED.max),
+			sum = metric_samples_1m.sum + EXCLUDED.sum,
+			last = EXCLUDED.last,
+			delta = metric_samples_1m.delta + EXCLUDED.delta,
+			count = metric_samples_1m.count + EXCLUDED.count;`
+
+	return s.rollup(ctx, model.ResolutionMinute, untilQuery, fromQuery, rollup)
+}
+
+func (s *PostgreStorage) rollupHours(ctx context.Context) error {
+	// часовые агрегаты строятся только по полностью агрегированным минутам
+	untilQuery := `SELECT LEAST(date_trunc('hour', now()),
+		date_trunc('hour', (SELECT rolled_until FROM rollup_state WHERE resolution = '1m')));`
+	fromQuery := `SELECT COALESCE(
+		(SELECT rolled_until FROM rollup_state WHERE resolution = '1h'),
+		(SELECT date_trunc('hour', min(ts)) FROM metric_samples_1m)
+	);`
+	rollup := `INSERT INTO metric_samples_1h (name, mtype, ts, min, max, sum, last, delta, count)
+		SELECT name, mtype, date_trunc('hour', ts),
+			min(min), max(max), sum(sum),
+			(array_agg(last ORDER BY ts DESC))[1],
+			sum(delta)::BIGINT, sum(count)::BIGINT
+		FROM metric_samples_1m
+		WHERE ts >= $1 AND ts < $2
+		GROUP BY name, mtype, date_trunc('hour', ts)
+		ON CONFLICT (name, ts) DO UPDATE SET
+			min = LEAST(metric_samples_1h.min, EXCLUDED.min),
+			max = GREATEST(metric_samples_1h.max, EXCLUDED.max),
+			sum = metric_samples_1h.sum + EXCLUDED.sum,
+			last = EXCLUDED.last,
+			delta = metric_samples_1h.delta + EXCLUDED.delta,
+			count = metric_samples_1h.count + EXCLUDED.count;`
+
+	return s.rollup(ctx, model.ResolutionHour, untilQuery, fromQuery, rollup)
+}
+
+// rollup агрегирует значения за [from, until) и сдвигает отметку rollup_state
+// в одной транзакции, поэтому каждый интервал агрегируется ровно один раз.
+func (s *PostgreStorage) rollup(ctx context.Context, resolution, untilQuery, fromQuery, rollup string) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var from, until *time.Time
+	if err := tx.QueryRow(ctx, untilQuery).Scan(&until); err != nil {
+		return err
+	}
+	if err := tx.QueryRow(ctx, fromQuery).Scan(&from); err != nil {
+		return err
+	}
+
+	// нет данных для агрегации
+	if from == nil || until == nil || !from.Before(*until) {
+		return nil
+	}
+
+	if _, err := tx.Exec(ctx, rollup, *from, *until); err != nil {
+		return err
+	}
+
+	state := `INSERT INTO rollup_state (resolution, rolled_until) VALUES ($1, $2)
+		ON CONFLICT (resolution) DO UPDATE SET rolled_until = EXCLUDED.rolled_until;`
+	if _, err := tx.Exec(ctx, state, resolution, *until); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
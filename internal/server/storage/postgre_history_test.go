@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Xacor/go-metrics/internal/server/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostgreStorage_resolutionFor(t *testing.T) {
+	s := &PostgreStorage{history: HistoryConfig{RawRetention: 2, MinuteRetention: 14, HourRetention: 365}}
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		from time.Time
+		to   time.Time
+		name string
+		want string
+	}{
+		{
+			name: "recent_short",
+			from: now.Add(-time.Hour),
+			to:   now,
+			want: model.ResolutionRaw,
+		},
+		{
+			name: "recent_long",
+			from: now.Add(-24 * time.Hour),
+			to:   now,
+			want: model.ResolutionMinute,
+		},
+		{
+			name: "short_past_raw_retention",
+			from: now.Add(-5 * day),
+			to:   now.Add(-5*day + time.Hour),
+			want: model.ResolutionMinute,
+		},
+		{
+			name: "month",
+			from: now.Add(-30 * day),
+			to:   now,
+			want: model.ResolutionHour,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, s.resolutionFor(now, tt.from, tt.to))
+		})
+	}
+}
+
+func TestPostgreStorage_History_Disabled(t *testing.T) {
+	s := &PostgreStorage{history: HistoryConfig{MinuteRetention: 14, HourRetention: 365}}
+
+	_, err := s.History(context.Background(), "Alloc", time.Now().Add(-time.Hour), time.Now())
+	assert.ErrorIs(t, err, ErrHistoryUnsupported)
+}
@@ -0,0 +1,192 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Xacor/go-metrics/internal/server/model"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// Реплика PostgreSQL, используемая только для чтения.
+type replica struct {
+	pool    *pgxpool.Pool
+	host    string
+	healthy atomic.Bool
+}
+
+// Набор реплик для чтения. Запросы распределяются между доступными репликами
+// по очереди, а если доступных реплик нет, выполняются на основном сервере.
+type replicaSet struct {
+	l    *zap.Logger
+	stop context.CancelFunc
+	// время последней записи метрики в unix-наносекундах, по имени метрики
+	written  sync.Map
+	replicas []*replica
+	wg       sync.WaitGroup
+	// окно read-your-writes, нулевое значение отключает его
+	ryw  time.Duration
+	next atomic.Uint32
+}
+
+func newReplicaSet(ctx context.Context, dsns []string, ryw time.Duration, logger *zap.Logger) (*replicaSet, error) {
+	rs := &replicaSet{l: logger, ryw: ryw}
+
+	for _, dsn := range dsns {
+		pool, err := pgxpool.New(ctx, dsn)
+		if err != nil {
+			rs.close()
+			return nil, err
+		}
+
+		r := &replica{pool: pool, host: pool.Config().ConnConfig.Host}
+		// недоступная при старте реплика не мешает запуску сервера
+		if err := pool.Ping(ctx); err != nil {
+			logger.Warn("read replica is unavailable", zap.String("host", r.host), zap.Error(err))
+		} else {
+			r.healthy.Store(true)
+		}
+		rs.replicas = append(rs.replicas, r)
+	}
+
+	return rs, nil
+}
+
+// pick выбирает очередную доступную реплику или возвращает nil.
+func (rs *replicaSet) pick() *replica {
+	n := uint32(len(rs.replicas))
+	start := rs.next.Add(1)
+
+	for i := uint32(0); i < n; i++ {
+		r := rs.replicas[(start+i)%n]
+		if r.healthy.Load() {
+			return r
+		}
+	}
+
+	return nil
+}
+
+func (rs *replicaSet) setHealthy(r *replica, err error) {
+	if err == nil {
+		if !r.healthy.Swap(true) {
+			rs.l.Info("read replica is available again", zap.String("host", r.host))
+		}
+		return
+	}
+
+	if r.healthy.Swap(false) {
+		rs.l.Warn("read replica is unavailable", zap.String("host", r.host), zap.Error(err))
+	}
+}
+
+// markWritten запоминает время записи метрик для read-your-writes.
+func (rs *replicaSet) markWritten(metrics ...model.Metrics) {
+	if rs.ryw <= 0 {
+		return
+	}
+
+	now := time.Now().UnixNano()
+	for _, m := range metrics {
+		rs.written.Store(m.Name, now)
+	}
+}
+
+// recentlyWritten сообщает, записывалась ли метрика в пределах окна read-your-writes.
+func (rs *replicaSet) recentlyWritten(name string) bool {
+	if rs.ryw <= 0 {
+		return false
+	}
+
+	ts, ok := rs.written.Load(name)
+	return ok && time.Since(time.Unix(0, ts.(int64))) < rs.ryw
+}
+
+// start периодически проверяет доступность реплик и очищает
+// устаревшие отметки о записи.
+func (rs *replicaSet) start(interval time.Duration) {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rs.stop = cancel
+
+	rs.wg.Add(1)
+	go func() {
+		defer rs.wg.Done()
+
+		t := time.NewTicker(interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				rs.check(ctx)
+			}
+		}
+	}()
+}
+
+func (rs *replicaSet) check(ctx context.Context) {
+	for _, r := range rs.replicas {
+		pingCtx, cancel := context.WithTimeout(ctx, pingTimeout)
+		err := r.pool.Ping(pingCtx)
+		cancel()
+
+		if ctx.Err() != nil {
+			return
+		}
+		rs.setHealthy(r, err)
+	}
+
+	rs.written.Range(func(name, ts any) bool {
+		if time.Since(time.Unix(0, ts.(int64))) >= rs.ryw {
+			rs.written.Delete(name)
+		}
+		return true
+	})
+}
+
+func (rs *replicaSet) close() {
+	if rs.stop != nil {
+		rs.stop()
+		rs.wg.Wait()
+	}
+
+	for _, r := range rs.replicas {
+		r.pool.Close()
+	}
+}
+
+// read выполняет запрос на чтение метрики name на реплике, а при её недоступности
+// или отсутствии на ней метрики — на основном сервере. Пустое имя означает чтение
+// нескольких метрик, для него read-your-writes не применяется.
+func (s *PostgreStorage) read(ctx context.Context, name string, query func(db *pgxpool.Pool) error) error {
+	if s.replicas == nil || (name != "" && s.replicas.recentlyWritten(name)) {
+		return query(s.db)
+	}
+
+	r := s.replicas.pick()
+	if r == nil {
+		return query(s.db)
+	}
+
+	err := query(r.pool)
+	if err == nil || ctx.Err() != nil {
+		return err
+	}
+
+	// метрика могла ещё не дойти до реплики
+	if !errors.Is(err, ErrMetricNotFound) {
+		s.replicas.setHealthy(r, err)
+	}
+
+	return query(s.db)
+}
@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Xacor/go-metrics/internal/server/model"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestReplicaSet_Pick(t *testing.T) {
+	rs := &replicaSet{l: zap.NewNop()}
+	for _, host := range []string{"r1", "r2", "r3"} {
+		r := &replica{host: host}
+		r.healthy.Store(true)
+		rs.replicas = append(rs.replicas, r)
+	}
+
+	picked := make(map[string]int)
+	for i := 0; i < 6; i++ {
+		picked[rs.pick().host]++
+	}
+	assert.Equal(t, map[string]int{"r1": 2, "r2": 2, "r3": 2}, picked)
+
+	rs.setHealthy(rs.replicas[0], errors.New("connection refused"))
+	rs.setHealthy(rs.replicas[2], errors.New("connection refused"))
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, "r2", rs.pick().host)
+	}
+
+	rs.setHealthy(rs.replicas[1], errors.New("connection refused"))
+	assert.Nil(t, rs.pick())
+}
+
+func TestReplicaSet_ReadYourWrites(t *testing.T) {
+	tests := []struct {
+		name    string
+		ryw     time.Duration
+		written string
+		read    string
+		want    bool
+	}{
+		{
+			name:    "disabled",
+			written: "counter1",
+			read:    "counter1",
+		},
+		{
+			name:    "just_written",
+			ryw:     time.Minute,
+			written: "counter1",
+			read:    "counter1",
+			want:    true,
+		},
+		{
+			name:    "other_metric",
+			ryw:     time.Minute,
+			written: "counter1",
+			read:    "counter2",
+		},
+		{
+			name:    "window_passed",
+			ryw:     time.Nanosecond,
+			written: "counter1",
+			read:    "counter1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rs := &replicaSet{l: zap.NewNop(), ryw: tt.ryw}
+			rs.markWritten(model.Metrics{Name: tt.written})
+			time.Sleep(time.Millisecond)
+
+			assert.Equal(t, tt.want, rs.recentlyWritten(tt.read))
+		})
+	}
+}
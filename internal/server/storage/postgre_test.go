@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Xacor/go-metrics/internal/server/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// Тесты и бенчмарки PostgreStorage требуют доступной БД:
+//
+//	TEST_DATABASE_DSN="host=127.0.0.1 user=user dbname=test password=pass" go test -bench UpdateBatch ./internal/server/storage/
+func newTestPostgre(tb testing.TB) *PostgreStorage {
+	tb.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		tb.Skip("TEST_DATABASE_DSN is not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	s, err := NewPostgreStorage(ctx, &PostgreConfig{Logger: zap.NewNop(), DSN: dsn})
+	require.NoError(tb, err)
+	tb.Cleanup(func() {
+		s.db.Exec(context.Background(), "TRUNCATE metrics;")
+		s.Close()
+	})
+
+	_, err = s.db.Exec(ctx, "TRUNCATE metrics;")
+	require.NoError(tb, err)
+
+	return s
+}
+
+func mixedBatch(prefix string, n int) []model.Metrics {
+	batch := make([]model.Metrics, 0, n)
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("%s%d", prefix, i%(n/2+1))
+		if i%2 == 0 {
+			delta := int64(i)
+			batch = append(batch, model.Metrics{Name: "c_" + name, MType: model.TypeCounter, Delta: &delta})
+		} else {
+			value := float64(i)
+			batch = append(batch, model.Metrics{Name: "g_" + name, MType: model.TypeGauge, Value: &value})
+		}
+	}
+
+	return batch
+}
+
+func TestPostgreStorage_UpdateBatchStrategies(t *testing.T) {
+	ctx := context.Background()
+	s := newTestPostgre(t)
+
+	batch := mixedBatch("m", 200)
+
+	require.NoError(t, s.updateBatchQueue(ctx, batch))
+	require.NoError(t, s.updateBatchQueue(ctx, batch))
+	want, err := s.All(ctx)
+	require.NoError(t, err)
+
+	_, err = s.db.Exec(ctx, "TRUNCATE metrics;")
+	require.NoError(t, err)
+
+	require.NoError(t, s.updateBatchCopy(ctx, batch))
+	require.NoError(t, s.updateBatchCopy(ctx, batch))
+	got, err := s.All(ctx)
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, want, got)
+}
+
+func BenchmarkPostgreStorage_UpdateBatch(b *testing.B) {
+	ctx := context.Background()
+	s := newTestPostgre(b)
+
+	strategies := map[string]func(context.Context, []model.Metrics) error{
+		"batch": s.updateBatchQueue,
+		"copy":  s.updateBatchCopy,
+	}
+
+	for _, size := range []int{10, 100, 1000, 10000} {
+		batch := mixedBatch("bench", size)
+		for name, update := range strategies {
+			b.Run(fmt.Sprintf("%s/%d", name, size), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					if err := update(ctx, batch); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestPostgreStorage_GetBatch(t *testing.T) {
+	ctx := context.Background()
+	s := newTestPostgre(t)
+
+	batch := mixedBatch("m", 10)
+	require.NoError(t, s.UpdateBatch(ctx, batch))
+
+	got, err := s.GetBatch(ctx, []string{"c_m0", "g_m1", "missing"})
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	for _, m := range got {
+		stored, err := s.Get(ctx, m.Name)
+		require.NoError(t, err)
+		assert.Equal(t, stored, m)
+	}
+}
@@ -0,0 +1,231 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Xacor/go-metrics/internal/server/model"
+	"go.uber.org/zap"
+)
+
+// Время, за которое основное хранилище должно ответить на проверку доступности.
+const pingTimeout = time.Second
+
+// Реализует интерфейс Storage поверх основного хранилища, переходя в деградированный
+// режим при потере связи с ним. В деградированном режиме записи копятся в памяти
+// и переносятся в основное хранилище после восстановления связи. Чтение всегда
+// выполняется из основного хранилища.
+type ResilientStorage struct {
+	primary  Storage
+	buffer   *MemStorage
+	l        *zap.Logger
+	stop     context.CancelFunc
+	wg       sync.WaitGroup
+	mu       sync.RWMutex
+	interval time.Duration
+	degraded atomic.Bool
+}
+
+func NewResilientStorage(primary Storage, checkInterval time.Duration, logger *zap.Logger) *ResilientStorage {
+	if checkInterval <= 0 {
+		checkInterval = 5 * time.Second
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s := &ResilientStorage{
+		primary:  primary,
+		buffer:   newBuffer(logger),
+		l:        logger,
+		stop:     cancel,
+		interval: checkInterval,
+	}
+
+	s.wg.Add(1)
+	go s.watch(ctx)
+
+	return s
+}
+
+func newBuffer(logger *zap.Logger) *MemStorage {
+	return NewMemStorage(nil, -1, logger)
+}
+
+// Degraded() сообщает, работает ли хранилище в деградированном режиме.
+func (s *ResilientStorage) Degraded() bool {
+	return s.degraded.Load()
+}
+
+func (s *ResilientStorage) Ping(ctx context.Context) error {
+	return s.primary.Ping(ctx)
+}
+
+func (s *ResilientStorage) All(ctx context.Context) ([]model.Metrics, error) {
+	return s.primary.All(ctx)
+}
+
+func (s *ResilientStorage) AllWithPrefix(ctx context.Context, prefix string) ([]model.Metrics, error) {
+	return allWithPrefix(ctx, s.primary, prefix)
+}
+
+func (s *ResilientStorage) Get(ctx context.Context, name string) (model.Metrics, error) {
+	return s.primary.Get(ctx, name)
+}
+
+func (s *ResilientStorage) GetBatch(ctx context.Context, names []string) ([]model.Metrics, error) {
+	return getBatch(ctx, s.primary, names)
+}
+
+func (s *ResilientStorage) History(ctx context.Context, name string, from, to time.Time) (model.History, error) {
+	repo, ok := s.primary.(HistoryRepo)
+	if !ok {
+		return model.History{}, ErrHistoryUnsupported
+	}
+
+	return repo.History(ctx, name, from, to)
+}
+
+// Create() в деградированном режиме возвращает принятое значение метрики.
+func (s *ResilientStorage) Create(ctx context.Context, metric model.Metrics) (model.Metrics, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.degraded.Load() {
+		result, err := s.primary.Create(ctx, metric)
+		if err == nil || !s.lost(err) {
+			return result, err
+		}
+	}
+
+	if err := s.buffer.UpdateBatch(ctx, []model.Metrics{metric}); err != nil {
+		return model.Metrics{}, err
+	}
+
+	return metric, nil
+}
+
+// Update() в деградированном режиме возвращает принятое значение метрики:
+// для счётчика это приращение, а не накопленная сумма.
+func (s *ResilientStorage) Update(ctx context.Context, metric model.Metrics) (model.Metrics, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.degraded.Load() {
+		result, err := s.primary.Update(ctx, metric)
+		if err == nil || !s.lost(err) {
+			return result, err
+		}
+	}
+
+	if err := s.buffer.UpdateBatch(ctx, []model.Metrics{metric}); err != nil {
+		return model.Metrics{}, err
+	}
+
+	return metric, nil
+}
+
+func (s *ResilientStorage) UpdateBatch(ctx context.Context, metrics []model.Metrics) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.degraded.Load() {
+		err := s.primary.UpdateBatch(ctx, metrics)
+		if err == nil || !s.lost(err) {
+			return err
+		}
+	}
+
+	return s.buffer.UpdateBatch(ctx, metrics)
+}
+
+func (s *ResilientStorage) Close() error {
+	s.stop()
+	s.wg.Wait()
+
+	if s.degraded.Load() {
+		if err := s.flush(context.Background()); err != nil {
+			s.l.Error("unflushed metrics are lost", zap.Error(err))
+		}
+	}
+
+	return s.primary.Close()
+}
+
+// lost проверяет, вызвана ли ошибка записи потерей связи с основным хранилищем,
+// и при необходимости переводит хранилище в деградированный режим.
+func (s *ResilientStorage) lost(err error) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+	defer cancel()
+
+	if pingErr := s.primary.Ping(ctx); pingErr == nil {
+		return false
+	}
+
+	if !s.degraded.Swap(true) {
+		s.l.Warn("primary storage is unreachable, switching to degraded mode", zap.Error(err))
+	}
+
+	return true
+}
+
+// watch периодически проверяет основное хранилище и после восстановления связи
+// переносит в него накопленные записи.
+func (s *ResilientStorage) watch(ctx context.Context) {
+	defer s.wg.Done()
+
+	t := time.NewTicker(s.interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		pingCtx, cancel := context.WithTimeout(ctx, pingTimeout)
+		err := s.primary.Ping(pingCtx)
+		cancel()
+
+		if err != nil {
+			if !s.degraded.Swap(true) {
+				s.l.Warn("primary storage is unreachable, switching to degraded mode", zap.Error(err))
+			}
+			continue
+		}
+
+		if !s.degraded.Load() {
+			continue
+		}
+
+		if err := s.flush(ctx); err != nil {
+			s.l.Error("unable to flush buffered metrics", zap.Error(err))
+			continue
+		}
+		s.l.Info("primary storage recovered, leaving degraded mode")
+	}
+}
+
+// flush переносит накопленные записи в основное хранилище и выходит из деградированного режима.
+func (s *ResilientStorage) flush(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	buffered, err := s.buffer.All(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(buffered) > 0 {
+		if err := s.primary.UpdateBatch(ctx, buffered); err != nil {
+			return err
+		}
+	}
+
+	s.buffer = newBuffer(s.l)
+	s.degraded.Store(false)
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Xacor/go-metrics/internal/server/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+var errConnLost = errors.New("connection lost")
+
+// Основное хранилище, связь с которым можно разорвать.
+type flappingStorage struct {
+	*MemStorage
+	down atomic.Bool
+}
+
+func (s *flappingStorage) Ping(ctx context.Context) error {
+	if s.down.Load() {
+		return errConnLost
+	}
+	return nil
+}
+
+func (s *flappingStorage) Get(ctx context.Context, name string) (model.Metrics, error) {
+	if s.down.Load() {
+		return model.Metrics{}, errConnLost
+	}
+	return s.MemStorage.Get(ctx, name)
+}
+
+func (s *flappingStorage) Update(ctx context.Context, metric model.Metrics) (model.Metrics, error) {
+	if s.down.Load() {
+		return model.Metrics{}, errConnLost
+	}
+	return s.MemStorage.Update(ctx, metric)
+}
+
+func (s *flappingStorage) UpdateBatch(ctx context.Context, metrics []model.Metrics) error {
+	if s.down.Load() {
+		return errConnLost
+	}
+	return s.MemStorage.UpdateBatch(ctx, metrics)
+}
+
+func TestResilientStorage_BufferAndFlush(t *testing.T) {
+	ctx := context.Background()
+	primary := &flappingStorage{MemStorage: newTestMemStorage()}
+	s := NewResilientStorage(primary, 10*time.Millisecond, zap.NewNop())
+	defer s.Close()
+
+	var delta int64 = 2
+	counter := model.Metrics{Name: "counter1", MType: model.TypeCounter, Delta: &delta}
+	require.NoError(t, s.UpdateBatch(ctx, []model.Metrics{counter}))
+	assert.False(t, s.Degraded())
+
+	primary.down.Store(true)
+
+	got, err := s.Update(ctx, counter)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), *got.Delta)
+	assert.True(t, s.Degraded())
+
+	require.NoError(t, s.UpdateBatch(ctx, []model.Metrics{counter}))
+
+	primary.down.Store(false)
+	assert.Eventually(t, func() bool { return !s.Degraded() }, time.Second, 10*time.Millisecond)
+
+	stored, err := primary.Get(ctx, "counter1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), *stored.Delta)
+
+	// ошибки, не связанные с потерей связи, возвращаются как есть
+	_, err = s.Update(ctx, model.Metrics{Name: "unknown", MType: model.TypeGauge})
+	assert.True(t, errors.Is(err, ErrMetricNotFound))
+	assert.False(t, s.Degraded())
+}
@@ -0,0 +1,210 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Xacor/go-metrics/internal/server/model"
+	"github.com/Xacor/go-metrics/internal/server/storage/migrations"
+	"go.uber.org/zap"
+	_ "modernc.org/sqlite"
+)
+
+// Префикс DSN, по которому выбирается встроенное хранилище SQLite,
+// например sqlite:///var/lib/metrics.db.
+const SQLiteScheme = "sqlite://"
+
+// Реализует интерфейс Storage поверх встроенной БД SQLite.
+type SQLiteStorage struct {
+	db *sql.DB
+	l  *zap.Logger
+}
+
+func NewSQLiteStorage(ctx context.Context, dsn string, logger *zap.Logger) (*SQLiteStorage, error) {
+	db, err := OpenSQLite(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	s := SQLiteStorage{db: db, l: logger}
+	if err := s.Migrate(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// OpenSQLite() открывает БД SQLite по DSN с префиксом SQLiteScheme.
+func OpenSQLite(dsn string) (*sql.DB, error) {
+	path := strings.TrimPrefix(dsn, SQLiteScheme)
+	if path == "" {
+		return nil, ErrEmptyDSN
+	}
+
+	db, err := sql.Open("sqlite", fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", path))
+	if err != nil {
+		return nil, err
+	}
+	// SQLite допускает только одного писателя, поэтому все запросы
+	// сериализуются через единственное соединение.
+	db.SetMaxOpenConns(1)
+
+	return db, nil
+}
+
+// Migrate() применяет к БД все ещё не применённые миграции схемы.
+func (s *SQLiteStorage) Migrate(ctx context.Context) error {
+	migrator, err := migrations.NewSQLite(s.db, s.l)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrMigrationFailed, err)
+	}
+
+	if _, err := migrator.Up(ctx); err != nil {
+		return fmt.Errorf("%w: %v", ErrMigrationFailed, err)
+	}
+
+	return nil
+}
+
+func (s *SQLiteStorage) All(ctx context.Context) ([]model.Metrics, error) {
+	return s.all(ctx, "SELECT name, mtype, delta, value FROM metrics;")
+}
+
+func (s *SQLiteStorage) AllWithPrefix(ctx context.Context, prefix string) ([]model.Metrics, error) {
+	query := "SELECT name, mtype, delta, value FROM metrics WHERE substr(name, 1, ?) = ?;"
+	return s.all(ctx, query, len(prefix), prefix)
+}
+
+func (s *SQLiteStorage) all(ctx context.Context, query string, args ...any) ([]model.Metrics, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var metrics []model.Metrics
+
+	for rows.Next() {
+		var sql sqlResponse
+
+		if err := rows.Scan(&sql.name, &sql.mtype, &sql.delta, &sql.value); err != nil {
+			return metrics, err
+		}
+
+		m, err := sql.toModel()
+		if err != nil {
+			return metrics, err
+		}
+		metrics = append(metrics, m)
+	}
+
+	return metrics, rows.Err()
+}
+
+func (s *SQLiteStorage) Get(ctx context.Context, name string) (model.Metrics, error) {
+	query := "SELECT name, mtype, delta, value FROM metrics WHERE name = ?;"
+	var resp sqlResponse
+
+	err := s.db.QueryRowContext(ctx, query, name).Scan(&resp.name, &resp.mtype, &resp.delta, &resp.value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.Metrics{}, fmt.Errorf("%w: %s", ErrMetricNotFound, name)
+	}
+	if err != nil {
+		return model.Metrics{}, err
+	}
+
+	return resp.toModel()
+}
+
+// Наибольшее число параметров в одном запросе GetBatch.
+const sqliteBatchParams = 500
+
+func (s *SQLiteStorage) GetBatch(ctx context.Context, names []string) ([]model.Metrics, error) {
+	var metrics []model.Metrics
+	for len(names) > 0 {
+		chunk := names
+		if len(chunk) > sqliteBatchParams {
+			chunk = chunk[:sqliteBatchParams]
+		}
+		names = names[len(chunk):]
+
+		args := make([]any, len(chunk))
+		for i, name := range chunk {
+			args[i] = name
+		}
+		query := "SELECT name, mtype, delta, value FROM metrics WHERE name IN (?" + strings.Repeat(",?", len(chunk)-1) + ");"
+
+		m, err := s.all(ctx, query, args...)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, m...)
+	}
+
+	return metrics, nil
+}
+
+func (s *SQLiteStorage) Create(ctx context.Context, m model.Metrics) (model.Metrics, error) {
+	insert := "INSERT INTO metrics (name, mtype, delta, value) VALUES(?,?,?,?);"
+
+	if _, err := s.db.ExecContext(ctx, insert, m.Name, m.MType, m.Delta, m.Value); err != nil {
+		return model.Metrics{}, fmt.Errorf("%w: %v", ErrMetricNotCreated, err)
+	}
+
+	return s.Get(ctx, m.Name)
+}
+
+func (s *SQLiteStorage) Update(ctx context.Context, m model.Metrics) (model.Metrics, error) {
+	update := "UPDATE metrics SET delta = metrics.delta + ?, value = ? WHERE name = ?;"
+
+	if _, err := s.db.ExecContext(ctx, update, m.Delta, m.Value, m.Name); err != nil {
+		return model.Metrics{}, fmt.Errorf("%w: %v", ErrMetricNotUpdated, err)
+	}
+
+	return s.Get(ctx, m.Name)
+}
+
+func (s *SQLiteStorage) UpdateBatch(ctx context.Context, metrics []model.Metrics) error {
+	query := `INSERT INTO metrics (name, mtype, delta, value)
+		VALUES(?,?,?,?)
+		ON CONFLICT (name)
+		DO
+		UPDATE SET delta = metrics.delta + excluded.delta, value = excluded.value;`
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, m := range metrics {
+		if _, err := stmt.ExecContext(ctx, m.Name, m.MType, m.Delta, m.Value); err != nil {
+			return fmt.Errorf("%w: %v", ErrMetricNotUpdated, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *SQLiteStorage) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+func (s *SQLiteStorage) Close() error {
+	return s.db.Close()
+}
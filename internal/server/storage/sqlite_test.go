@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/Xacor/go-metrics/internal/server/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newTestSQLite(t *testing.T) *SQLiteStorage {
+	t.Helper()
+
+	dsn := SQLiteScheme + filepath.Join(t.TempDir(), "metrics.db")
+	s, err := NewSQLiteStorage(context.Background(), dsn, zap.NewNop())
+	require.NoError(t, err)
+	t.Cleanup(func() { s.Close() })
+
+	return s
+}
+
+func TestSQLiteStorage_CreateUpdate(t *testing.T) {
+	ctx := context.Background()
+	s := newTestSQLite(t)
+
+	_, err := s.Get(ctx, "counter1")
+	assert.True(t, errors.Is(err, ErrMetricNotFound))
+
+	var delta int64 = 2
+	got, err := s.Create(ctx, model.Metrics{Name: "counter1", MType: model.TypeCounter, Delta: &delta})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), *got.Delta)
+
+	_, err = s.Create(ctx, model.Metrics{Name: "counter1", MType: model.TypeCounter, Delta: &delta})
+	assert.Error(t, err)
+
+	got, err = s.Update(ctx, model.Metrics{Name: "counter1", MType: model.TypeCounter, Delta: &delta})
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), *got.Delta)
+
+	value := 1.5
+	_, err = s.Create(ctx, model.Metrics{Name: "gauge1", MType: model.TypeGauge, Value: &value})
+	require.NoError(t, err)
+
+	value = 3.5
+	got, err = s.Update(ctx, model.Metrics{Name: "gauge1", MType: model.TypeGauge, Value: &value})
+	require.NoError(t, err)
+	assert.Equal(t, 3.5, *got.Value)
+
+	all, err := s.All(ctx)
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+}
+
+func TestSQLiteStorage_UpdateBatch(t *testing.T) {
+	ctx := context.Background()
+	s := newTestSQLite(t)
+
+	var one int64 = 1
+	v1, v2 := 1.0, 2.0
+	batch := []model.Metrics{
+		{Name: "counter1", MType: model.TypeCounter, Delta: &one},
+		{Name: "counter1", MType: model.TypeCounter, Delta: &one},
+		{Name: "gauge1", MType: model.TypeGauge, Value: &v1},
+		{Name: "gauge1", MType: model.TypeGauge, Value: &v2},
+	}
+	require.NoError(t, s.UpdateBatch(ctx, batch))
+	require.NoError(t, s.UpdateBatch(ctx, batch))
+
+	counter, err := s.Get(ctx, "counter1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), *counter.Delta)
+
+	gauge, err := s.Get(ctx, "gauge1")
+	require.NoError(t, err)
+	assert.Equal(t, 2.0, *gauge.Value)
+
+	invalid := []model.Metrics{{Name: "gauge2", MType: model.TypeGauge}}
+	assert.Error(t, s.UpdateBatch(ctx, invalid))
+}
+
+func TestSQLiteStorage_GetBatch(t *testing.T) {
+	ctx := context.Background()
+	s := newTestSQLite(t)
+
+	// больше имён, чем параметров в одном запросе
+	batch := mixedBatch("m", 2*sqliteBatchParams)
+	require.NoError(t, s.UpdateBatch(ctx, batch))
+
+	names := []string{"missing"}
+	for _, m := range batch {
+		names = append(names, m.Name)
+	}
+
+	got, err := s.GetBatch(ctx, names)
+	require.NoError(t, err)
+	all, err := s.All(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, all, got)
+}
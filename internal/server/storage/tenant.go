@@ -0,0 +1,383 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Xacor/go-metrics/internal/server/model"
+	"github.com/Xacor/go-metrics/internal/server/tenant"
+	"golang.org/x/time/rate"
+)
+
+// Разделитель идентификатора арендатора и имени метрики в хранилище.
+const tenantSeparator = "/"
+
+// Состояние квот арендатора.
+type tenantState struct {
+	limiter *rate.Limiter
+	// имена метрик арендатора, загружаются при первой записи
+	series map[string]struct{}
+	mu     sync.Mutex
+}
+
+// Реализует интерфейс Storage поверх общего хранилища, разделяя метрики
+// арендаторов: имя метрики в хранилище предваряется идентификатором арендатора,
+// от имени которого выполняется запрос. При записи проверяются квоты арендатора
+// на количество метрик и скорость записи.
+type TenantStorage struct {
+	repo   Storage
+	states map[string]*tenantState
+	mu     sync.Mutex
+}
+
+func NewTenantStorage(repo Storage) *TenantStorage {
+	return &TenantStorage{
+		repo:   repo,
+		states: make(map[string]*tenantState),
+	}
+}
+
+func tenantPrefix(t *tenant.Tenant) string {
+	return t.ID + tenantSeparator
+}
+
+func fromContext(ctx context.Context) (*tenant.Tenant, error) {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, ErrNoTenant
+	}
+
+	return t, nil
+}
+
+func (s *TenantStorage) state(t *tenant.Tenant) *tenantState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, ok := s.states[t.ID]
+	if !ok {
+		st = &tenantState{}
+		if t.IngestRate > 0 {
+			st.limiter = rate.NewLimiter(rate.Limit(t.IngestRate), t.Burst())
+		}
+		s.states[t.ID] = st
+	}
+
+	return st
+}
+
+// admit проверяет квоты арендатора перед записью метрик, резервирует новые
+// имена и токены скорости записи. Токены резервируются только после проверки
+// квоты на число метрик, чтобы отклонённая пачка их не расходовала. Возвращает
+// функцию, отменяющую оба резервирования при неудачной записи.
+func (s *TenantStorage) admit(ctx context.Context, t *tenant.Tenant, metrics []model.Metrics) (func(), error) {
+	st := s.state(t)
+
+	release, err := s.admitSeries(ctx, t, st, metrics)
+	if err != nil {
+		return nil, err
+	}
+
+	if st.limiter == nil {
+		return release, nil
+	}
+
+	now := time.Now()
+	reserved, delay := reserve(st.limiter, len(metrics), now)
+	if delay > 0 {
+		release()
+		return nil, &RetryError{
+			Err:        fmt.Errorf("%w: tenant %s: ingest rate %v/s", ErrQuotaExceeded, t.ID, t.IngestRate),
+			RetryAfter: delay,
+		}
+	}
+
+	rollback := func() {
+		release()
+		// отмена на момент резервирования возвращает и уже доступные токены
+		for i := len(reserved) - 1; i >= 0; i-- {
+			reserved[i].CancelAt(now)
+		}
+	}
+
+	return rollback, nil
+}
+
+// admitSeries проверяет квоту арендатора на число метрик и резервирует новые имена.
+// Возвращает функцию, отменяющую резервирование.
+func (s *TenantStorage) admitSeries(ctx context.Context, t *tenant.Tenant, st *tenantState, metrics []model.Metrics) (func(), error) {
+	if t.MaxSeries <= 0 {
+		return func() {}, nil
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if st.series == nil {
+		stored, err := allWithPrefix(ctx, s.repo, tenantPrefix(t))
+		if err != nil {
+			return nil, err
+		}
+
+		st.series = make(map[string]struct{}, len(stored))
+		for _, m := range stored {
+			st.series[m.Name] = struct{}{}
+		}
+	}
+
+	var added []string
+	for _, m := range metrics {
+		if _, ok := st.series[m.Name]; !ok {
+			st.series[m.Name] = struct{}{}
+			added = append(added, m.Name)
+		}
+	}
+
+	if len(st.series) > t.MaxSeries {
+		for _, name := range added {
+			delete(st.series, name)
+		}
+		return nil, fmt.Errorf("%w: tenant %s: max series %d", ErrQuotaExceeded, t.ID, t.MaxSeries)
+	}
+
+	release := func() {
+		st.mu.Lock()
+		defer st.mu.Unlock()
+
+		for _, name := range added {
+			delete(st.series, name)
+		}
+	}
+
+	return release, nil
+}
+
+// reserve расходует n токенов limiter. Пачка больше всплеска резервируется
+// частями: она принимается, если доступна первая часть, а остальные токены
+// становятся долгом, который ждут следующие запросы. Возвращает резервирования,
+// чтобы их можно было отменить при неудачной записи. Иначе резервирование
+// отменяется и возвращается время, через которое пачку можно повторить.
+func reserve(limiter *rate.Limiter, n int, now time.Time) ([]*rate.Reservation, time.Duration) {
+	burst := limiter.Burst()
+
+	first := n
+	if first > burst {
+		first = burst
+	}
+	r := limiter.ReserveN(now, first)
+	if delay := r.DelayFrom(now); delay > 0 {
+		r.CancelAt(now)
+		return nil, delay
+	}
+
+	reserved := []*rate.Reservation{r}
+	for n -= first; n > 0; n -= burst {
+		chunk := n
+		if chunk > burst {
+			chunk = burst
+		}
+		reserved = append(reserved, limiter.ReserveN(now, chunk))
+	}
+
+	return reserved, 0
+}
+
+// scope переводит имена метрик в пространство имён арендатора.
+func scope(t *tenant.Tenant, metrics []model.Metrics) []model.Metrics {
+	scoped := make([]model.Metrics, len(metrics))
+	for i, m := range metrics {
+		m.Name = tenantPrefix(t) + m.Name
+		scoped[i] = m
+	}
+
+	return scoped
+}
+
+func unscope(t *tenant.Tenant, m model.Metrics) model.Metrics {
+	m.Name = strings.TrimPrefix(m.Name, tenantPrefix(t))
+	return m
+}
+
+func (s *TenantStorage) Ping(ctx context.Context) error {
+	return s.repo.Ping(ctx)
+}
+
+func (s *TenantStorage) All(ctx context.Context) ([]model.Metrics, error) {
+	t, err := fromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics, err := allWithPrefix(ctx, s.repo, tenantPrefix(t))
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range metrics {
+		metrics[i] = unscope(t, metrics[i])
+	}
+
+	return metrics, nil
+}
+
+func (s *TenantStorage) Get(ctx context.Context, name string) (model.Metrics, error) {
+	t, err := fromContext(ctx)
+	if err != nil {
+		return model.Metrics{}, err
+	}
+
+	m, err := s.repo.Get(ctx, tenantPrefix(t)+name)
+	if err != nil {
+		return model.Metrics{}, err
+	}
+
+	return unscope(t, m), nil
+}
+
+func (s *TenantStorage) GetBatch(ctx context.Context, names []string) ([]model.Metrics, error) {
+	t, err := fromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	scoped := make([]string, len(names))
+	for i, name := range names {
+		scoped[i] = tenantPrefix(t) + name
+	}
+
+	metrics, err := getBatch(ctx, s.repo, scoped)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range metrics {
+		metrics[i] = unscope(t, metrics[i])
+	}
+
+	return metrics, nil
+}
+
+func (s *TenantStorage) History(ctx context.Context, name string, from, to time.Time) (model.History, error) {
+	t, err := fromContext(ctx)
+	if err != nil {
+		return model.History{}, err
+	}
+
+	repo, ok := s.repo.(HistoryRepo)
+	if !ok {
+		return model.History{}, ErrHistoryUnsupported
+	}
+
+	history, err := repo.History(ctx, tenantPrefix(t)+name, from, to)
+	history.Name = name
+
+	return history, err
+}
+
+func (s *TenantStorage) Create(ctx context.Context, metric model.Metrics) (model.Metrics, error) {
+	return s.write(ctx, metric, s.repo.Create)
+}
+
+func (s *TenantStorage) Update(ctx context.Context, metric model.Metrics) (model.Metrics, error) {
+	return s.write(ctx, metric, s.repo.Update)
+}
+
+func (s *TenantStorage) write(ctx context.Context, metric model.Metrics,
+	fn func(context.Context, model.Metrics) (model.Metrics, error)) (model.Metrics, error) {
+	t, err := fromContext(ctx)
+	if err != nil {
+		return model.Metrics{}, err
+	}
+
+	scoped := scope(t, []model.Metrics{metric})
+	rollback, err := s.admit(ctx, t, scoped)
+	if err != nil {
+		return model.Metrics{}, err
+	}
+
+	result, err := fn(ctx, scoped[0])
+	if err != nil {
+		rollback()
+		return model.Metrics{}, err
+	}
+
+	return unscope(t, result), nil
+}
+
+func (s *TenantStorage) UpdateBatch(ctx context.Context, metrics []model.Metrics) error {
+	t, err := fromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	scoped := scope(t, metrics)
+	rollback, err := s.admit(ctx, t, scoped)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.UpdateBatch(ctx, scoped); err != nil {
+		rollback()
+		return err
+	}
+
+	return nil
+}
+
+func (s *TenantStorage) Close() error {
+	return s.repo.Close()
+}
+
+// Degraded() сообщает, работает ли общее хранилище в деградированном режиме.
+func (s *TenantStorage) Degraded() bool {
+	d, ok := s.repo.(Degrader)
+	return ok && d.Degraded()
+}
+
+// allWithPrefix выбирает метрики по префиксу имени средствами хранилища,
+// а если хранилище этого не умеет, фильтрует полный список.
+func allWithPrefix(ctx context.Context, repo MetricRepo, prefix string) ([]model.Metrics, error) {
+	if lister, ok := repo.(PrefixLister); ok {
+		return lister.AllWithPrefix(ctx, prefix)
+	}
+
+	all, err := repo.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]model.Metrics, 0, len(all))
+	for _, m := range all {
+		if strings.HasPrefix(m.Name, prefix) {
+			result = append(result, m)
+		}
+	}
+
+	return result, nil
+}
+
+// getBatch читает метрики с именами names одним запросом, если хранилище это
+// умеет, а иначе по одной. Отсутствующие метрики пропускаются.
+func getBatch(ctx context.Context, repo MetricRepo, names []string) ([]model.Metrics, error) {
+	if getter, ok := repo.(BatchGetter); ok {
+		return getter.GetBatch(ctx, names)
+	}
+
+	metrics := make([]model.Metrics, 0, len(names))
+	for _, name := range names {
+		m, err := repo.Get(ctx, name)
+		if errors.Is(err, ErrMetricNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, m)
+	}
+
+	return metrics, nil
+}
@@ -0,0 +1,169 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Xacor/go-metrics/internal/server/model"
+	"github.com/Xacor/go-metrics/internal/server/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func counter(name string, delta int64) model.Metrics {
+	return model.Metrics{Name: name, MType: model.TypeCounter, Delta: &delta}
+}
+
+func TestTenantStorage_Isolation(t *testing.T) {
+	mem := newTestMemStorage()
+	s := NewTenantStorage(mem)
+
+	ctxA := tenant.NewContext(context.Background(), &tenant.Tenant{ID: "team-a"})
+	ctxB := tenant.NewContext(context.Background(), &tenant.Tenant{ID: "team-b"})
+
+	require.NoError(t, s.UpdateBatch(ctxA, []model.Metrics{counter("requests", 1)}))
+	require.NoError(t, s.UpdateBatch(ctxB, []model.Metrics{counter("requests", 5)}))
+
+	got, err := s.Get(ctxA, "requests")
+	require.NoError(t, err)
+	assert.Equal(t, "requests", got.Name)
+	assert.Equal(t, int64(1), *got.Delta)
+
+	all, err := s.All(ctxB)
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+	assert.Equal(t, "requests", all[0].Name)
+	assert.Equal(t, int64(5), *all[0].Delta)
+
+	_, err = mem.Get(context.Background(), "team-a/requests")
+	assert.NoError(t, err)
+
+	_, err = s.All(context.Background())
+	assert.True(t, errors.Is(err, ErrNoTenant))
+}
+
+func TestTenantStorage_Quotas(t *testing.T) {
+	tests := []struct {
+		name    string
+		tenant  *tenant.Tenant
+		batches [][]model.Metrics
+		wantErr []bool
+	}{
+		{
+			name:   "max_series",
+			tenant: &tenant.Tenant{ID: "team-a", MaxSeries: 2},
+			batches: [][]model.Metrics{
+				{counter("c1", 1), counter("c2", 1)},
+				{counter("c1", 1), counter("c2", 1)},
+				{counter("c3", 1)},
+			},
+			wantErr: []bool{false, false, true},
+		},
+		{
+			name:   "ingest_rate",
+			tenant: &tenant.Tenant{ID: "team-a", IngestRate: 0.001, IngestBurst: 3},
+			batches: [][]model.Metrics{
+				{counter("c1", 1), counter("c2", 1)},
+				{counter("c1", 1)},
+				{counter("c1", 1)},
+			},
+			wantErr: []bool{false, false, true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewTenantStorage(newTestMemStorage())
+			ctx := tenant.NewContext(context.Background(), tt.tenant)
+
+			for i, batch := range tt.batches {
+				err := s.UpdateBatch(ctx, batch)
+				if tt.wantErr[i] {
+					assert.True(t, errors.Is(err, ErrQuotaExceeded), err)
+				} else {
+					assert.NoError(t, err)
+				}
+			}
+		})
+	}
+}
+
+func TestReserve(t *testing.T) {
+	now := time.Now()
+	limiter := rate.NewLimiter(10, 5)
+	reserve := func(limiter *rate.Limiter, n int, now time.Time) time.Duration {
+		_, delay := reserve(limiter, n, now)
+		return delay
+	}
+
+	// пачка больше всплеска принимается при полном запасе токенов,
+	// а следующие ждут, пока долг не будет погашен
+	assert.Zero(t, reserve(limiter, 12, now))
+	assert.Equal(t, 800*time.Millisecond, reserve(limiter, 1, now))
+	assert.Zero(t, reserve(limiter, 1, now.Add(800*time.Millisecond)))
+
+	// отказ не расходует токены
+	assert.Equal(t, 500*time.Millisecond, reserve(limiter, 5, now.Add(800*time.Millisecond)))
+	assert.Zero(t, reserve(limiter, 5, now.Add(1300*time.Millisecond)))
+}
+
+func TestTenantStorage_RetryAfter(t *testing.T) {
+	s := NewTenantStorage(newTestMemStorage())
+	ctx := tenant.NewContext(context.Background(), &tenant.Tenant{ID: "team-a", IngestRate: 1, IngestBurst: 2})
+
+	batch := []model.Metrics{counter("c1", 1), counter("c2", 1), counter("c3", 1)}
+	require.NoError(t, s.UpdateBatch(ctx, batch), "batch larger than burst is admitted")
+
+	err := s.UpdateBatch(ctx, batch)
+	assert.True(t, errors.Is(err, ErrQuotaExceeded), err)
+	delay, ok := RetryAfter(err)
+	assert.True(t, ok)
+	assert.Greater(t, delay, time.Second)
+}
+
+type failingStorage struct {
+	*MemStorage
+}
+
+func (s failingStorage) UpdateBatch(context.Context, []model.Metrics) error {
+	return errors.New("write failed")
+}
+
+func TestTenantStorage_ReleaseTokens(t *testing.T) {
+	tests := []struct {
+		name string
+		repo Storage
+		// пачка, которая не должна расходовать токены
+		batch []model.Metrics
+	}{
+		{
+			name:  "max_series",
+			repo:  newTestMemStorage(),
+			batch: []model.Metrics{counter("c1", 1), counter("c2", 1), counter("c3", 1)},
+		},
+		{
+			name:  "write_failed",
+			repo:  failingStorage{newTestMemStorage()},
+			batch: []model.Metrics{counter("c1", 1), counter("c2", 1)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewTenantStorage(tt.repo)
+			ctx := tenant.NewContext(context.Background(), &tenant.Tenant{ID: "team-a", MaxSeries: 2, IngestRate: 0.001, IngestBurst: 2})
+
+			err := s.UpdateBatch(ctx, tt.batch)
+			require.Error(t, err)
+			_, limited := RetryAfter(err)
+			assert.False(t, limited, err)
+
+			// отклонённая пачка не израсходовала ни токены, ни квоту метрик
+			s.repo = newTestMemStorage()
+			assert.NoError(t, s.UpdateBatch(ctx, []model.Metrics{counter("c4", 1), counter("c5", 1)}))
+		})
+	}
+}
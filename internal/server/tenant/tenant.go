@@ -0,0 +1,167 @@
+// Модуль tenant описывает арендаторов сервера: команды, которые используют
+// один сервер метрик и хранят метрики в изолированных пространствах имён.
+package tenant
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// Заголовки HTTP-запроса, в которых передаются идентификатор и токен арендатора.
+// В gRPC используются одноимённые ключи метаданных в нижнем регистре.
+const (
+	HeaderID    = "X-Tenant-ID"
+	HeaderToken = "X-Tenant-Token"
+)
+
+var (
+	ErrNoCredentials = errors.New("tenant credentials are not provided")
+	ErrUnknownTenant = errors.New("unknown tenant")
+	ErrInvalidToken  = errors.New("invalid tenant token")
+	ErrInvalidID     = errors.New("invalid tenant id")
+)
+
+// Идентификатор арендатора входит в имена метрик в хранилище,
+// поэтому допускается только ограниченный набор символов.
+var validID = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// Арендатор и его квоты.
+type Tenant struct {
+	ID string `json:"id"`
+	// Токены, которыми арендатор подтверждает свои запросы.
+	Tokens []string `json:"tokens"`
+	// Допустимое количество записываемых значений метрик в секунду, 0 — без ограничений.
+	IngestRate float64 `json:"ingest_rate"`
+	// Максимальное количество метрик арендатора, 0 — без ограничений.
+	MaxSeries int `json:"max_series"`
+	// Допустимый всплеск записи сверх IngestRate. Пачка больше всплеска
+	// принимается, только когда всплеск не израсходован, а следующие запросы
+	// ждут, пока скорость записи не вернётся к IngestRate.
+	IngestBurst int `json:"ingest_burst"`
+}
+
+// Burst() возвращает допустимый всплеск записи, но не меньше IngestRate и одного значения.
+func (t *Tenant) Burst() int {
+	burst := t.IngestBurst
+	if b := int(t.IngestRate + 0.5); b > burst {
+		burst = b
+	}
+	if burst < 1 {
+		return 1
+	}
+
+	return burst
+}
+
+// Реестр арендаторов.
+type Registry struct {
+	tenants map[string]*Tenant
+}
+
+func NewRegistry(tenants []Tenant) (*Registry, error) {
+	r := &Registry{tenants: make(map[string]*Tenant, len(tenants))}
+
+	for i := range tenants {
+		t := tenants[i]
+		if !validID.MatchString(t.ID) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidID, t.ID)
+		}
+		if _, ok := r.tenants[t.ID]; ok {
+			return nil, fmt.Errorf("%w: duplicate %q", ErrInvalidID, t.ID)
+		}
+		r.tenants[t.ID] = &t
+	}
+
+	return r, nil
+}
+
+// LoadRegistry() читает список арендаторов из JSON-файла.
+func LoadRegistry(path string) (*Registry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var tenants []Tenant
+	if err := json.Unmarshal(data, &tenants); err != nil {
+		return nil, fmt.Errorf("unable to parse tenants file: %w", err)
+	}
+
+	return NewRegistry(tenants)
+}
+
+// Get() возвращает арендатора по идентификатору.
+func (r *Registry) Get(id string) (*Tenant, bool) {
+	t, ok := r.tenants[id]
+	return t, ok
+}
+
+// Authenticate() определяет арендатора по токену. Если указан идентификатор,
+// токен должен принадлежать арендатору с этим идентификатором.
+func (r *Registry) Authenticate(id, token string) (*Tenant, error) {
+	if token == "" {
+		return nil, ErrNoCredentials
+	}
+
+	if id != "" {
+		t, ok := r.tenants[id]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownTenant, id)
+		}
+		if !t.hasToken(token) {
+			return nil, ErrInvalidToken
+		}
+		return t, nil
+	}
+
+	for _, t := range r.tenants {
+		if t.hasToken(token) {
+			return t, nil
+		}
+	}
+
+	return nil, ErrInvalidToken
+}
+
+// Resolve() определяет арендатора запроса. Если учётные данные клиента привязаны
+// к арендатору bound, используется он, а идентификатор и токен не проверяются.
+func (r *Registry) Resolve(bound, id, token string) (*Tenant, error) {
+	if bound == "" {
+		return r.Authenticate(id, token)
+	}
+
+	t, ok := r.tenants[bound]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTenant, bound)
+	}
+
+	return t, nil
+}
+
+func (t *Tenant) hasToken(token string) bool {
+	for _, known := range t.Tokens {
+		if subtle.ConstantTimeCompare([]byte(known), []byte(token)) == 1 {
+			return true
+		}
+	}
+
+	return false
+}
+
+type ctxKey struct{}
+
+// NewContext() возвращает контекст запроса арендатора t.
+func NewContext(ctx context.Context, t *Tenant) context.Context {
+	return context.WithValue(ctx, ctxKey{}, t)
+}
+
+// FromContext() возвращает арендатора, от имени которого выполняется запрос.
+func FromContext(ctx context.Context) (*Tenant, bool) {
+	t, ok := ctx.Value(ctxKey{}).(*Tenant)
+	return t, ok && t != nil
+}
@@ -0,0 +1,90 @@
+package tenant
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_Authenticate(t *testing.T) {
+	registry, err := NewRegistry([]Tenant{
+		{ID: "team-a", Tokens: []string{"token-a1", "token-a2"}},
+		{ID: "team-b", Tokens: []string{"token-b"}},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		id      string
+		token   string
+		want    string
+		wantErr error
+	}{
+		{
+			name:  "token_only",
+			token: "token-a2",
+			want:  "team-a",
+		},
+		{
+			name:  "id_and_token",
+			id:    "team-b",
+			token: "token-b",
+			want:  "team-b",
+		},
+		{
+			name:    "token_of_other_tenant",
+			id:      "team-b",
+			token:   "token-a1",
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name:    "unknown_tenant",
+			id:      "team-c",
+			token:   "token-a1",
+			wantErr: ErrUnknownTenant,
+		},
+		{
+			name:    "no_token",
+			id:      "team-a",
+			wantErr: ErrNoCredentials,
+		},
+		{
+			name:    "unknown_token",
+			token:   "token-c",
+			wantErr: ErrInvalidToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := registry.Authenticate(tt.id, tt.token)
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr), err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.ID)
+		})
+	}
+}
+
+func TestLoadRegistry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tenants.json")
+
+	require.NoError(t, os.WriteFile(path, []byte(`[{"id": "team/a", "tokens": ["x"]}]`), 0o600))
+	_, err := LoadRegistry(path)
+	assert.True(t, errors.Is(err, ErrInvalidID))
+
+	require.NoError(t, os.WriteFile(path, []byte(`[{"id": "team-a", "tokens": ["x"], "max_series": 10, "ingest_rate": 5}]`), 0o600))
+	registry, err := LoadRegistry(path)
+	require.NoError(t, err)
+
+	got, ok := registry.Get("team-a")
+	require.True(t, ok)
+	assert.Equal(t, 10, got.MaxSeries)
+	assert.Equal(t, 5, got.Burst())
+}
@@ -0,0 +1,215 @@
+package signing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Xacor/go-metrics/internal/logger"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrUnknownKey   = errors.New("unknown signing key")
+	ErrKeyRetired   = errors.New("signing key is retired")
+	ErrInvalidRing  = errors.New("invalid keyring")
+	ErrNoSigningKey = errors.New("keyring has no active signing key")
+)
+
+// Ключ подписи. Агент указывает идентификатор ключа в заголовке HeaderKeyID.
+type Key struct {
+	// Время, после которого подписи ключом отклоняются. Пустое значение — бессрочно.
+	RetireAt *time.Time `json:"retire_at,omitempty"`
+	ID       string     `json:"id"`
+	Secret   string     `json:"key"`
+}
+
+func (k *Key) active(now time.Time) bool {
+	return k.RetireAt == nil || now.Before(*k.RetireAt)
+}
+
+// Набор ключей подписи. Позволяет заменять ключи без одновременного обновления
+// всех агентов: новый ключ добавляется в набор, агенты переводятся на него,
+// а для старого назначается время вывода из обращения.
+type Keyring struct {
+	modTime time.Time
+	keys    []Key
+	path    string
+	mu      sync.RWMutex
+}
+
+// NewKeyring() создаёт набор из заданных ключей.
+func NewKeyring(keys []Key) (*Keyring, error) {
+	if err := validate(keys); err != nil {
+		return nil, err
+	}
+
+	return &Keyring{keys: keys}, nil
+}
+
+// LoadKeyring() читает набор ключей из JSON-файла. Набор можно перечитать
+// методом Reload() или отслеживать изменения файла методом Watch().
+func LoadKeyring(path string) (*Keyring, error) {
+	k := &Keyring{path: path}
+	if err := k.Reload(); err != nil {
+		return nil, err
+	}
+
+	return k, nil
+}
+
+func validate(keys []Key) error {
+	seen := make(map[string]struct{}, len(keys))
+	for _, k := range keys {
+		if k.Secret == "" {
+			return fmt.Errorf("%w: key %q is empty", ErrInvalidRing, k.ID)
+		}
+		if _, ok := seen[k.ID]; ok {
+			return fmt.Errorf("%w: duplicate key id %q", ErrInvalidRing, k.ID)
+		}
+		seen[k.ID] = struct{}{}
+	}
+
+	return nil
+}
+
+// Reload() перечитывает файл набора ключей. При ошибке прежний набор сохраняется.
+func (k *Keyring) Reload() error {
+	if k.path == "" {
+		return nil
+	}
+
+	info, err := os.Stat(k.path)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(k.path)
+	if err != nil {
+		return err
+	}
+
+	var keys []Key
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidRing, err)
+	}
+	for _, key := range keys {
+		if key.ID == "" {
+			return fmt.Errorf("%w: key id is required", ErrInvalidRing)
+		}
+	}
+	if err := validate(keys); err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	k.keys = keys
+	k.modTime = info.ModTime()
+	k.mu.Unlock()
+
+	return nil
+}
+
+// Watch() перечитывает файл набора ключей при изменении времени его модификации,
+// проверяя его каждые interval, пока не отменён ctx. После перечитывания
+// вызывается reloaded, если он задан.
+func (k *Keyring) Watch(ctx context.Context, interval time.Duration, reloaded func()) {
+	if k.path == "" || interval <= 0 {
+		return
+	}
+
+	l := logger.Get()
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			info, err := os.Stat(k.path)
+			if err != nil {
+				l.Error("unable to stat keyring", zap.Error(err))
+				continue
+			}
+
+			k.mu.RLock()
+			changed := !info.ModTime().Equal(k.modTime)
+			k.mu.RUnlock()
+			if !changed {
+				continue
+			}
+
+			if err := k.Reload(); err != nil {
+				l.Error("unable to reload keyring", zap.Error(err))
+				continue
+			}
+			l.Info("keyring reloaded", zap.String("path", k.path))
+			if reloaded != nil {
+				reloaded()
+			}
+		}
+	}
+}
+
+// Active() возвращает ключи, которыми можно проверить подпись: ключ с идентификатором id
+// или, если идентификатор не указан, все действующие ключи.
+func (k *Keyring) Active(id string, now time.Time) ([]Key, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	if id != "" {
+		for i := range k.keys {
+			if k.keys[i].ID != id {
+				continue
+			}
+			if !k.keys[i].active(now) {
+				return nil, fmt.Errorf("%w: %s", ErrKeyRetired, id)
+			}
+			return k.keys[i : i+1], nil
+		}
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, id)
+	}
+
+	active := make([]Key, 0, len(k.keys))
+	for i := range k.keys {
+		if k.keys[i].active(now) {
+			active = append(active, k.keys[i])
+		}
+	}
+
+	return active, nil
+}
+
+// SigningKey() возвращает ключ для подписи ответов сервера: из действующих ключей
+// тот, что остаётся действующим дольше всех, а при равенстве — последний в наборе.
+func (k *Keyring) SigningKey(now time.Time) (Key, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	var (
+		key   Key
+		found bool
+	)
+	for i := range k.keys {
+		if !k.keys[i].active(now) {
+			continue
+		}
+		if found && key.RetireAt == nil && k.keys[i].RetireAt != nil {
+			continue
+		}
+		if found && key.RetireAt != nil && k.keys[i].RetireAt != nil && k.keys[i].RetireAt.Before(*key.RetireAt) {
+			continue
+		}
+		key, found = k.keys[i], true
+	}
+	if !found {
+		return Key{}, ErrNoSigningKey
+	}
+
+	return key, nil
+}
@@ -0,0 +1,102 @@
+package signing
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyring_SigningKey(t *testing.T) {
+	now := time.Now()
+	soon, later := now.Add(time.Hour), now.Add(2*time.Hour)
+	past := now.Add(-time.Hour)
+
+	tests := []struct {
+		name    string
+		keys    []Key
+		wantID  string
+		wantErr error
+	}{
+		{name: "single", keys: []Key{{Secret: "s"}}, wantID: ""},
+		{name: "new_key_appended", keys: []Key{{ID: "old", Secret: "s", RetireAt: &soon}, {ID: "new", Secret: "s"}}, wantID: "new"},
+		{name: "retiring_later", keys: []Key{{ID: "b", Secret: "s", RetireAt: &later}, {ID: "a", Secret: "s", RetireAt: &soon}}, wantID: "b"},
+		{name: "last_of_equal", keys: []Key{{ID: "a", Secret: "s"}, {ID: "b", Secret: "s"}}, wantID: "b"},
+		{name: "all_retired", keys: []Key{{ID: "old", Secret: "s", RetireAt: &past}}, wantErr: ErrNoSigningKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyring, err := NewKeyring(tt.keys)
+			require.NoError(t, err)
+
+			key, err := keyring.SigningKey(now)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantID, key.ID)
+		})
+	}
+}
+
+func TestKeyring_Rotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keyring.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[
+		{"id": "old", "key": "old-secret", "retire_at": "2000-01-01T00:00:00Z"},
+		{"id": "current", "key": "current-secret"}
+	]`), 0o600))
+
+	keyring, err := LoadKeyring(path)
+	require.NoError(t, err)
+	v := NewVerifier(keyring, time.Minute, 100, V1)
+
+	body := []byte("body")
+	sign := func(secret, keyID string) Signature {
+		s, err := Sign([]byte(secret), body)
+		require.NoError(t, err)
+		s.KeyID = keyID
+		return s
+	}
+
+	tests := []struct {
+		name    string
+		sign    Signature
+		wantErr error
+	}{
+		{name: "key_id", sign: sign("current-secret", "current")},
+		{name: "any_active_key", sign: sign("current-secret", "")},
+		{name: "retired_key", sign: sign("old-secret", "old"), wantErr: ErrKeyRetired},
+		{name: "retired_key_without_id", sign: sign("old-secret", ""), wantErr: ErrInvalidSignature},
+		{name: "unknown_key", sign: sign("current-secret", "next"), wantErr: ErrUnknownKey},
+		{name: "wrong_key_id", sign: sign("old-secret", "current"), wantErr: ErrInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.Verify(tt.sign, body)
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr), err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+
+	// новый ключ принимается после перечитывания файла без перезапуска
+	require.NoError(t, os.WriteFile(path, []byte(`[
+		{"id": "current", "key": "current-secret"},
+		{"id": "next", "key": "next-secret"}
+	]`), 0o600))
+	require.NoError(t, keyring.Reload())
+	assert.NoError(t, v.Verify(sign("next-secret", "next"), body))
+
+	// некорректный файл не заменяет действующий набор
+	require.NoError(t, os.WriteFile(path, []byte(`[{"id": "next"}]`), 0o600))
+	assert.True(t, errors.Is(keyring.Reload(), ErrInvalidRing))
+	assert.NoError(t, v.Verify(sign("next-secret", "next"), body))
+}
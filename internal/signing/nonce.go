@@ -0,0 +1,47 @@
+package signing
+
+import "sync"
+
+// Размер кеша nonce по умолчанию.
+const DefaultNonceCacheSize = 100000
+
+// nonceCache хранит последние size значений nonce. При переполнении вытесняется
+// самое старое значение, поэтому размер кеша должен покрывать количество запросов,
+// которые сервер принимает за удвоенное допустимое расхождение часов: иначе
+// вытесненный nonce можно повторить, пока не истечёт время подписи.
+type nonceCache struct {
+	seen  map[string]struct{}
+	order []string
+	next  int
+	mu    sync.Mutex
+}
+
+func newNonceCache(size int) *nonceCache {
+	if size <= 0 {
+		size = DefaultNonceCacheSize
+	}
+
+	return &nonceCache{
+		seen:  make(map[string]struct{}, size),
+		order: make([]string, size),
+	}
+}
+
+// add запоминает nonce и сообщает, встретился ли он впервые.
+func (c *nonceCache) add(nonce string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.seen[nonce]; ok {
+		return false
+	}
+
+	if old := c.order[c.next]; old != "" {
+		delete(c.seen, old)
+	}
+	c.order[c.next] = nonce
+	c.next = (c.next + 1) % len(c.order)
+	c.seen[nonce] = struct{}{}
+
+	return true
+}
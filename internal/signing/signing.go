@@ -0,0 +1,239 @@
+// Модуль signing описывает подпись запросов агента HMAC-SHA256, общую для агента и сервера.
+//
+// Версия 1 подписывает только тело запроса и не защищает от повторной отправки.
+// Версия 2 подписывает также время отправки и одноразовое значение (nonce):
+// сервер отклоняет запросы, отправленные слишком давно, и запросы с уже
+// встречавшимся nonce. Версию подписи агент передаёт в заголовке HeaderVersion,
+// запросы без него проверяются как запросы версии 1. Идентификатор ключа
+// из набора Keyring агент передаёт в заголовке HeaderKeyID.
+package signing
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Заголовки HTTP-запроса с подписью. В gRPC используются одноимённые
+// ключи метаданных в нижнем регистре.
+const (
+	HeaderSignature = "HashSHA256"
+	HeaderVersion   = "X-Signature-Version"
+	HeaderTimestamp = "X-Signature-Timestamp"
+	HeaderNonce     = "X-Signature-Nonce"
+	HeaderKeyID     = "X-Signature-Key-ID"
+)
+
+// Версии схемы подписи.
+const (
+	V1 = 1
+	V2 = 2
+)
+
+const (
+	nonceSize     = 16
+	maxNonceBytes = 64
+)
+
+var (
+	ErrNoSignature        = errors.New("request is not signed")
+	ErrInvalidSignature   = errors.New("invalid signature")
+	ErrUnsupportedVersion = errors.New("unsupported signature version")
+	ErrExpired            = errors.New("signature timestamp is out of allowed window")
+	ErrReplayed           = errors.New("nonce has already been used")
+)
+
+// Подпись запроса и параметры, которые она покрывает.
+type Signature struct {
+	Value     string
+	Timestamp string
+	Nonce     string
+	KeyID     string
+	Version   int
+}
+
+// Sign() подписывает тело запроса по схеме версии 2 с текущим временем и случайным nonce.
+func Sign(key, body []byte) (Signature, error) {
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return Signature{}, err
+	}
+
+	s := Signature{
+		Version:   V2,
+		Timestamp: strconv.FormatInt(time.Now().Unix(), 10),
+		Nonce:     hex.EncodeToString(nonce),
+	}
+	s.Value = hex.EncodeToString(s.mac(key, body))
+
+	return s, nil
+}
+
+// SignV1() подписывает только тело запроса по схеме версии 1.
+func SignV1(key, body []byte) Signature {
+	s := Signature{Version: V1}
+	s.Value = hex.EncodeToString(s.mac(key, body))
+
+	return s
+}
+
+// mac вычисляет HMAC-SHA256 от данных, которые покрывает подпись.
+func (s Signature) mac(key, body []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	if s.Version != V1 {
+		fmt.Fprintf(h, "%d\n%s\n%s\n", s.Version, s.Timestamp, s.Nonce)
+	}
+	h.Write(body)
+
+	return h.Sum(nil)
+}
+
+// SetHeader() записывает подпись в заголовки HTTP-запроса.
+func (s Signature) SetHeader(h http.Header) {
+	h.Set(HeaderSignature, s.Value)
+	if s.KeyID != "" {
+		h.Set(HeaderKeyID, s.KeyID)
+	}
+	if s.Version == V1 {
+		return
+	}
+
+	h.Set(HeaderVersion, strconv.Itoa(s.Version))
+	h.Set(HeaderTimestamp, s.Timestamp)
+	h.Set(HeaderNonce, s.Nonce)
+}
+
+// Pairs() возвращает подпись в виде пар ключ-значение для метаданных gRPC.
+func (s Signature) Pairs() []string {
+	pairs := []string{HeaderSignature, s.Value}
+	if s.KeyID != "" {
+		pairs = append(pairs, HeaderKeyID, s.KeyID)
+	}
+	if s.Version == V1 {
+		return pairs
+	}
+
+	return append(pairs,
+		HeaderVersion, strconv.Itoa(s.Version),
+		HeaderTimestamp, s.Timestamp,
+		HeaderNonce, s.Nonce,
+	)
+}
+
+// Parse() собирает подпись из значений заголовков, которые возвращает get.
+// Пустая версия означает версию 1.
+func Parse(get func(key string) string) (Signature, error) {
+	value := get(HeaderSignature)
+	if value == "" {
+		return Signature{}, ErrNoSignature
+	}
+
+	s := Signature{
+		Value:     value,
+		Timestamp: get(HeaderTimestamp),
+		Nonce:     get(HeaderNonce),
+		KeyID:     get(HeaderKeyID),
+		Version:   V1,
+	}
+	if version := get(HeaderVersion); version != "" {
+		v, err := strconv.Atoi(version)
+		if err != nil || v < V1 || v > V2 {
+			return Signature{}, fmt.Errorf("%w: %q", ErrUnsupportedVersion, version)
+		}
+		s.Version = v
+	}
+
+	return s, nil
+}
+
+// FromHeader() извлекает подпись из заголовков HTTP-запроса.
+func FromHeader(h http.Header) (Signature, error) {
+	return Parse(h.Get)
+}
+
+// Проверка подписей запросов.
+type Verifier struct {
+	nonces     *nonceCache
+	keyring    *Keyring
+	now        func() time.Time
+	skew       time.Duration
+	minVersion int
+}
+
+// NewVerifier() создаёт проверку подписей ключами из keyring. Подписи версии 2 принимаются,
+// если время отправки отличается от времени сервера не более чем на skew, а nonce
+// не встречался среди последних cacheSize запросов. Подписи версий ниже minVersion
+// отклоняются.
+func NewVerifier(keyring *Keyring, skew time.Duration, cacheSize, minVersion int) *Verifier {
+	return &Verifier{
+		nonces:     newNonceCache(cacheSize),
+		keyring:    keyring,
+		now:        time.Now,
+		skew:       skew,
+		minVersion: minVersion,
+	}
+}
+
+// Verify() проверяет подпись тела запроса.
+func (v *Verifier) Verify(s Signature, body []byte) error {
+	if s.Version < v.minVersion {
+		return fmt.Errorf("%w: %d, minimal is %d", ErrUnsupportedVersion, s.Version, v.minVersion)
+	}
+
+	if s.Version != V1 && (s.Nonce == "" || len(s.Nonce) > maxNonceBytes) {
+		return fmt.Errorf("%w: invalid nonce", ErrInvalidSignature)
+	}
+
+	keys, err := v.keyring.Active(s.KeyID, v.now())
+	if err != nil {
+		return err
+	}
+	if !equal(s, keys, body) {
+		return ErrInvalidSignature
+	}
+
+	if s.Version == V1 {
+		return nil
+	}
+
+	ts, err := strconv.ParseInt(s.Timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: invalid timestamp", ErrInvalidSignature)
+	}
+	if d := v.now().Sub(time.Unix(ts, 0)); d > v.skew || d < -v.skew {
+		return fmt.Errorf("%w: %s", ErrExpired, d.Truncate(time.Second))
+	}
+
+	if !v.nonces.add(s.Nonce) {
+		return ErrReplayed
+	}
+
+	return nil
+}
+
+// equal сообщает, совпадает ли подпись с подписью одним из ключей. Подписи
+// версии 1 прежние агенты передавали без кодирования, такие значения тоже принимаются.
+func equal(s Signature, keys []Key, body []byte) bool {
+	got, err := hex.DecodeString(s.Value)
+	if err != nil {
+		got = nil
+	}
+
+	for i := range keys {
+		expected := s.mac([]byte(keys[i].Secret), body)
+		if got != nil && hmac.Equal(got, expected) {
+			return true
+		}
+		if s.Version == V1 && hmac.Equal([]byte(s.Value), expected) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,155 @@
+package signing
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifier_Verify(t *testing.T) {
+	key := []byte("secret")
+	body := []byte(`[{"id":"PollCount","type":"counter","delta":1}]`)
+
+	signed := func(t *testing.T) Signature {
+		s, err := Sign(key, body)
+		require.NoError(t, err)
+		return s
+	}
+	legacyRaw := func() Signature {
+		h := hmac.New(sha256.New, key)
+		h.Write(body)
+		return Signature{Version: V1, Value: string(h.Sum(nil))}
+	}
+
+	tests := []struct {
+		name       string
+		sign       func(t *testing.T) Signature
+		body       []byte
+		minVersion int
+		wantErr    error
+	}{
+		{
+			name: "v2",
+			sign: signed,
+		},
+		{
+			name: "v1_hex",
+			sign: func(*testing.T) Signature { return SignV1(key, body) },
+		},
+		{
+			name: "v1_raw",
+			sign: func(*testing.T) Signature { return legacyRaw() },
+		},
+		{
+			name:       "v1_rejected",
+			sign:       func(*testing.T) Signature { return SignV1(key, body) },
+			minVersion: V2,
+			wantErr:    ErrUnsupportedVersion,
+		},
+		{
+			name:    "tampered_body",
+			sign:    signed,
+			body:    []byte(`[{"id":"PollCount","type":"counter","delta":100}]`),
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name: "tampered_timestamp",
+			sign: func(t *testing.T) Signature {
+				s := signed(t)
+				s.Timestamp = strconv.FormatInt(time.Now().Unix()+1, 10)
+				return s
+			},
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name: "downgraded_version",
+			sign: func(t *testing.T) Signature {
+				s := signed(t)
+				s.Version = V1
+				return s
+			},
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name: "expired",
+			sign: func(t *testing.T) Signature {
+				s := Signature{Version: V2, Timestamp: strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10), Nonce: "nonce"}
+				s.Value = hex.EncodeToString(s.mac(key, body))
+				return s
+			},
+			wantErr: ErrExpired,
+		},
+	}
+
+	keyring, err := NewKeyring([]Key{{Secret: string(key)}})
+	require.NoError(t, err)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewVerifier(keyring, time.Minute, 10, tt.minVersion)
+			data := body
+			if tt.body != nil {
+				data = tt.body
+			}
+
+			err := v.Verify(tt.sign(t), data)
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr), err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestVerifier_Replay(t *testing.T) {
+	key := []byte("secret")
+	body := []byte("body")
+	keyring, err := NewKeyring([]Key{{Secret: string(key)}})
+	require.NoError(t, err)
+	v := NewVerifier(keyring, time.Minute, 2, V2)
+
+	first, err := Sign(key, body)
+	require.NoError(t, err)
+	require.NoError(t, v.Verify(first, body))
+	assert.True(t, errors.Is(v.Verify(first, body), ErrReplayed))
+
+	// подпись, переданная через заголовки, проверяется так же
+	h := http.Header{}
+	first.SetHeader(h)
+	parsed, err := FromHeader(h)
+	require.NoError(t, err)
+	assert.True(t, errors.Is(v.Verify(parsed, body), ErrReplayed))
+
+	// при переполнении кеша вытесняется самый старый nonce
+	for i := 0; i < 2; i++ {
+		s, err := Sign(key, body)
+		require.NoError(t, err)
+		require.NoError(t, v.Verify(s, body))
+	}
+	assert.NoError(t, v.Verify(first, body))
+}
+
+func TestParse(t *testing.T) {
+	header := func(values map[string]string) func(string) string {
+		return func(key string) string { return values[key] }
+	}
+
+	_, err := Parse(header(nil))
+	assert.True(t, errors.Is(err, ErrNoSignature))
+
+	_, err = Parse(header(map[string]string{HeaderSignature: "abc", HeaderVersion: "3"}))
+	assert.True(t, errors.Is(err, ErrUnsupportedVersion))
+
+	s, err := Parse(header(map[string]string{HeaderSignature: "abc"}))
+	require.NoError(t, err)
+	assert.Equal(t, V1, s.Version)
+}
@@ -0,0 +1,154 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// issue выпускает сертификат, подписанный parent, или самоподписанный CA, если parent nil.
+func issue(t *testing.T, cn string, parent *testCert, usage x509.ExtKeyUsage) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	signer, signerKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{usage}
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCert{cert: cert, key: key}
+}
+
+// write сохраняет сертификат и ключ в PEM и возвращает пути к файлам.
+func (c *testCert) write(t *testing.T, dir, name string) (string, string) {
+	t.Helper()
+
+	certFile := filepath.Join(dir, name+".crt")
+	keyFile := filepath.Join(dir, name+".key")
+
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw}), 0o600))
+
+	der, err := x509.MarshalECPrivateKey(c.key)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0o600))
+
+	return certFile, keyFile
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+
+	ca := issue(t, "test-ca", nil, 0)
+	caFile, _ := ca.write(t, dir, "ca")
+	serverCert, serverKey := issue(t, "server", ca, x509.ExtKeyUsageServerAuth).write(t, dir, "server")
+	clientCert, clientKey := issue(t, "agent-1", ca, x509.ExtKeyUsageClientAuth).write(t, dir, "client")
+
+	otherCA := issue(t, "other-ca", nil, 0)
+	strangerCert, strangerKey := issue(t, "stranger", otherCA, x509.ExtKeyUsageClientAuth).write(t, dir, "stranger")
+
+	tests := []struct {
+		name       string
+		require    bool
+		clientCert string
+		clientKey  string
+		wantCN     string
+		wantErr    bool
+	}{
+		{
+			name:       "client_certificate",
+			clientCert: clientCert,
+			clientKey:  clientKey,
+			wantCN:     "agent-1",
+		},
+		{
+			name: "optional_without_certificate",
+		},
+		{
+			name:    "required_without_certificate",
+			require: true,
+			wantErr: true,
+		},
+		{
+			// клиент не предъявляет сертификат, не подписанный CA из запроса сервера
+			name:       "untrusted_certificate",
+			require:    true,
+			clientCert: strangerCert,
+			clientKey:  strangerKey,
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serverTLS, err := Server(serverCert, serverKey, caFile, tt.require)
+			require.NoError(t, err)
+
+			srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if len(r.TLS.VerifiedChains) > 0 {
+					w.Write([]byte(r.TLS.VerifiedChains[0][0].Subject.CommonName))
+				}
+			}))
+			srv.TLS = serverTLS
+			srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+			srv.StartTLS()
+			defer srv.Close()
+
+			clientTLS, err := Client(caFile, tt.clientCert, tt.clientKey)
+			require.NoError(t, err)
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+
+			resp, err := client.Get(srv.URL)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			body := make([]byte, 64)
+			n, _ := resp.Body.Read(body)
+			assert.Equal(t, tt.wantCN, string(body[:n]))
+		})
+	}
+}